@@ -10,11 +10,13 @@ import (
 	"syscall"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/klog/v2"
 
 	"github.com/openshift/csi-driver-projected-resource/pkg/client"
 	"github.com/openshift/csi-driver-projected-resource/pkg/controller"
 	"github.com/openshift/csi-driver-projected-resource/pkg/hostpath"
+	"github.com/openshift/csi-driver-projected-resource/pkg/webhook"
 )
 
 var (
@@ -23,8 +25,14 @@ var (
 	driverName          string
 	nodeID              string
 	maxVolumesPerNode   int64
+	maxMemoryPerNode    string
+	healthAddress       string
 	version             string
 	shareRelistInterval string
+	namespaceConfig     string
+	webhookAddress      string
+	webhookCertFile     string
+	webhookKeyFile      string
 
 	shutdownSignals      = []os.Signal{os.Interrupt, syscall.SIGTERM}
 	onlyOneSignalHandler = make(chan struct{})
@@ -36,13 +44,49 @@ var rootCmd = &cobra.Command{
 	Short:   "",
 	Long:    ``,
 	Run: func(cmd *cobra.Command, args []string) {
-		driver, err := hostpath.NewHostPathDriver(hostpath.DataRoot, hostpath.VolumeMapRoot, driverName, nodeID, endPoint, maxVolumesPerNode, version)
+		maxBytesPerNode := int64(0)
+		if len(maxMemoryPerNode) > 0 {
+			quantity, err := resource.ParseQuantity(maxMemoryPerNode)
+			if err != nil {
+				fmt.Printf("Invalid max-memory-per-node %s: %s", maxMemoryPerNode, err.Error())
+				os.Exit(1)
+			}
+			maxBytesPerNode = quantity.Value()
+		}
+		driver, err := hostpath.NewHostPathDriver(hostpath.DataRoot, hostpath.StagingRoot, hostpath.VolumeMapRoot, driverName, nodeID, endPoint, maxVolumesPerNode, maxBytesPerNode, version)
 		if err != nil {
 			fmt.Printf("Failed to initialize driver: %s", err.Error())
 			os.Exit(1)
 		}
 		go runOperator()
-		driver.Run()
+		driver.Run(healthAddress)
+	},
+}
+
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "Run the admission webhook validating Share and NamespacedShare objects",
+	Run: func(cmd *cobra.Command, args []string) {
+		namespaces, err := controller.LoadNamespaceConfig(namespaceConfig)
+		if err != nil {
+			fmt.Printf("Failed to load the namespace configuration: %s", err.Error())
+			os.Exit(1)
+		}
+		if err := controller.SetNamespaceConfig(namespaces); err != nil {
+			fmt.Printf("Failed to load the namespace configuration: %s", err.Error())
+			os.Exit(1)
+		}
+		stopCh := setupSignalHandler()
+		go controller.WatchNamespaceConfig(namespaceConfig, stopCh, nil)
+		server, err := webhook.NewServer(webhookAddress, webhookCertFile, webhookKeyFile)
+		if err != nil {
+			fmt.Printf("Failed to set up webhook: %s", err.Error())
+			os.Exit(1)
+		}
+		if err := server.Run(stopCh); err != nil {
+			fmt.Printf("Webhook exited: %s", err.Error())
+			os.Exit(1)
+		}
 	},
 }
 
@@ -65,8 +109,22 @@ func init() {
 	rootCmd.Flags().StringVar(&driverName, "drivername", client.DriverName, "name of the driver")
 	rootCmd.Flags().StringVar(&nodeID, "nodeid", "", "node id")
 	rootCmd.Flags().Int64Var(&maxVolumesPerNode, "maxvolumespernode", 0, "limit of volumes per node")
+	rootCmd.Flags().StringVar(&maxMemoryPerNode, "max-memory-per-node", "",
+		"limit of the memory, as a quantity like 512Mi, the tmpfs of the volumes of the node, twice their size limits, add up to; unlimited if not set")
+	rootCmd.Flags().StringVar(&healthAddress, "health-address", ":9898",
+		"address the /healthz and /readyz health checks are served on; not served if empty")
 	rootCmd.Flags().StringVar(&shareRelistInterval, "share-relist-interval", "",
 		"the time between controller relist on the share resource expressed with golang time.Duration syntax(default=10m")
+	rootCmd.Flags().StringVar(&namespaceConfig, "namespace-config", "",
+		"file, in YAML or JSON, listing the namespaces to include and exclude and a namespace label selector; reloaded when it changes, the built in list of excluded namespaces is used if not set")
+
+	webhookCmd.Flags().AddGoFlagSet(flag.CommandLine)
+	webhookCmd.Flags().StringVar(&webhookAddress, "listen-address", ":8443", "address the webhook listens on")
+	webhookCmd.Flags().StringVar(&webhookCertFile, "tls-cert-file", "/etc/webhook/certs/tls.crt", "TLS certificate served by the webhook")
+	webhookCmd.Flags().StringVar(&namespaceConfig, "namespace-config", "",
+		"namespace configuration file of the controller; the backing resources of shares in the namespaces it excludes are rejected")
+	webhookCmd.Flags().StringVar(&webhookKeyFile, "tls-private-key-file", "/etc/webhook/certs/tls.key", "private key of the TLS certificate served by the webhook")
+	rootCmd.AddCommand(webhookCmd)
 }
 
 func runOperator() {
@@ -80,7 +138,7 @@ func runOperator() {
 			shareRelist = controller.DefaultResyncDuration
 		}
 	}
-	c, err := controller.NewController(shareRelist)
+	c, err := controller.NewController(shareRelist, namespaceConfig, nodeID)
 	if err != nil {
 		fmt.Printf("Failed to set up controller: %s", err.Error())
 		os.Exit(1)
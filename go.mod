@@ -4,10 +4,13 @@ go 1.14
 
 require (
 	github.com/container-storage-interface/spec v1.3.0
+	github.com/golang/protobuf v1.4.3
 	github.com/kubernetes-csi/csi-lib-utils v0.7.0
+	github.com/prometheus/client_golang v1.7.1
 	github.com/spf13/cobra v1.1.1
 	github.com/spf13/pflag v1.0.5
 	golang.org/x/net v0.0.0-20201110031124-69a78807bb2b
+	golang.org/x/sys v0.0.0-20201112073958-5cba982894dd
 	google.golang.org/grpc v1.31.0
 	k8s.io/api v0.20.1
 	k8s.io/apimachinery v0.20.1
@@ -10,5 +10,5 @@ bash vendor/k8s.io/code-generator/generate-groups.sh \
 deepcopy,client,lister,informer \
 github.com/openshift/csi-driver-projected-resource/pkg/generated \
 github.com/openshift/csi-driver-projected-resource/pkg/api \
-projectedresource:v1alpha1 \
+projectedresource:v1alpha1,v1beta1 \
 --go-header-file "./hack/boilerplate.go.txt"
\ No newline at end of file
@@ -0,0 +1,40 @@
+/*
+Copyright The OpenShift authors.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NamespacedShare is the Schema for the namespacedshares API. Unlike a Share, it can be created by
+// the admin of a namespace, and only pods in its namespace can consume it. Its backing resources need
+// to be in the same namespace, or in a namespace whose AllowedShareNamespacesAnnotation lists it.
+type NamespacedShare struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ShareSpec   `json:"spec,omitempty"`
+	Status ShareStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NamespacedShareList contains a list of NamespacedShare
+type NamespacedShareList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NamespacedShare `json:"items"`
+}
+
+const (
+	// AllowedShareNamespacesAnnotation is set on a namespace to allow the NamespacedShares of other
+	// namespaces to use the ConfigMaps and Secrets in it as backing resources. Its value is a comma
+	// separated list of namespaces, or "*" to allow any namespace.
+	AllowedShareNamespacesAnnotation = "projectedresource.storage.openshift.io/allowed-share-namespaces"
+)
@@ -6,7 +6,9 @@ SPDX-License-Identifier: Apache-2.0
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // +genclient
@@ -34,15 +36,57 @@ type ShareList struct {
 // ShareSpec defines the desired state of Share
 type ShareSpec struct {
 	// BackingResource captures the ConfigMap or Secret that is shared.
-	// +required
+	// Either it or BackingResources needs to be set.
+	// +optional
 	BackingResource `json:"backingResource"`
 
+	// BackingResources captures additional ConfigMaps and Secrets that are shared along with
+	// BackingResource, each landing in its own directory of the consuming volume.
+	// +optional
+	BackingResources []BackingResource `json:"backingResources,omitempty"`
+
 	// Description is a user readable explanation of what the backing resource
 	// provides.
 	// +optional
 	Description string `json:"description,omitempty"`
+
+	// UpdatePolicy determines whether changes to the backing resources reach the volumes of pods
+	// that are already running. Immediate, the default, writes them as soon as the controller sees
+	// them; OnPodRestart keeps the content a volume was mounted with until its pod is recreated.
+	// A pod can override it with the updatePolicy volume attribute.
+	// +optional
+	// +kubebuilder:validation:Enum=Immediate;OnPodRestart
+	UpdatePolicy UpdatePolicy `json:"updatePolicy,omitempty"`
+
+	// DefaultMode is the mode of the files projected from the backing resources, unless an item sets
+	// its own mode or SecretDefaultMode or ConfigMapDefaultMode applies. Defaults to 0644.
+	// A pod can override it with the defaultMode volume attribute.
+	// +optional
+	DefaultMode *int32 `json:"defaultMode,omitempty"`
+
+	// SecretDefaultMode is the mode of the files projected from Secrets, overriding DefaultMode.
+	// A pod can override it with the secretDefaultMode volume attribute.
+	// +optional
+	SecretDefaultMode *int32 `json:"secretDefaultMode,omitempty"`
+
+	// ConfigMapDefaultMode is the mode of the files projected from ConfigMaps, overriding DefaultMode.
+	// A pod can override it with the configMapDefaultMode volume attribute.
+	// +optional
+	ConfigMapDefaultMode *int32 `json:"configMapDefaultMode,omitempty"`
 }
 
+// UpdatePolicy determines when changes to the backing resources of a share reach the volumes consuming it
+type UpdatePolicy string
+
+const (
+	// UpdatePolicyImmediate writes changes to the backing resources into volumes as soon as they are seen.
+	UpdatePolicyImmediate UpdatePolicy = "Immediate"
+
+	// UpdatePolicyOnPodRestart fixes the content of a volume when it is mounted; changes to the backing
+	// resources are only seen by the pods created after them.
+	UpdatePolicyOnPodRestart UpdatePolicy = "OnPodRestart"
+)
+
 // ShareStatus defines the observed state of Share
 type ShareStatus struct {
 	// Conditions are the set of k8s Condition instances provided by the associated controller for Shares.
@@ -50,21 +94,114 @@ type ShareStatus struct {
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+const (
+	// ShareConditionValid is true when the kind, name and namespace of the backing resource are set
+	// and the kind is one the driver supports.
+	ShareConditionValid = "Valid"
+
+	// ShareConditionBackingResourceNamespaceWatched is false when the namespace of the backing resource
+	// is one the controller excludes from its watches, in which case its data is never projected.
+	ShareConditionBackingResourceNamespaceWatched = "BackingResourceNamespaceWatched"
+
+	// ShareConditionBackingResourceFound is true when the backing resource exists in the controller's cache.
+	ShareConditionBackingResourceFound = "BackingResourceFound"
+)
+
 type BackingResource struct {
 	// Kind is a string value representing the REST resource this object represents.
-	// Currently only Secret and ConfigMap are accepted.
+	// The data of a Secret or ConfigMap is projected; for any other kind, the results of Fields are.
 	// +required
 	Kind string `json:"kind"`
 
 	// APIVersion defines the versioned schema of this representation of an object.
+	// It needs to be set for kinds other than Secret and ConfigMap.
 	// +optional
 	APIVersion string `json:"apiVersion,omitempty"`
 
-	// Name is the name of the object serving as the backing resource
-	// +required
-	Name string `json:"name"`
+	// Name is the name of the object serving as the backing resource.
+	// Either it or Selector needs to be set.
+	// +optional
+	Name string `json:"name,omitempty"`
 
 	// Namespace is the namespace of the object serving as the backing resource
 	// +required
 	Namespace string `json:"namespace"`
+
+	// Items, when set, selects the keys of the backing resource that are projected, along with
+	// the path each one is written to and an optional file mode, the same as the items of a
+	// configMap or secret volume. Paths are relative to the root of the volume and may contain
+	// directories, but may not contain '..'. Keys that are not listed are not projected.
+	// When not set, every key is written to a file of the same name under the
+	// configmaps/<namespace>:<name> or secrets/<namespace>:<name> directory of the volume.
+	// +optional
+	Items []corev1.KeyToPath `json:"items,omitempty"`
+
+	// Selector, when set instead of Name, selects every object of the given Kind in Namespace whose
+	// labels match it. Each selected object is written under the configmaps/<namespace>:<name> or
+	// secrets/<namespace>:<name> directory of the volume, which is added and removed as objects start
+	// or stop matching. Items cannot be set along with it.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Fields selects what is projected for a backing resource of a kind other than Secret or ConfigMap,
+	// and needs to be set for those kinds. Each JSONPath expression is evaluated against the object,
+	// and the result written to the file at the associated path, relative to the root of the volume.
+	// +optional
+	Fields []FieldToPath `json:"fields,omitempty"`
+}
+
+// FieldToPath maps the result of a JSONPath expression evaluated against a backing resource to a file
+type FieldToPath struct {
+	// JSONPath is the expression evaluated against the backing resource, for example {.spec.endpoint}.
+	// The enclosing braces are optional. A string result is written as is, anything else as JSON.
+	// +required
+	JSONPath string `json:"jsonPath"`
+
+	// Path is the relative path of the file to write the result to. May not be an absolute path.
+	// May not contain the path element '..'.
+	// +required
+	Path string `json:"path"`
+
+	// Mode bits used to set permissions on the file, between 0 and 0777. Defaults to 0644.
+	// +optional
+	Mode *int32 `json:"mode,omitempty"`
+}
+
+// IsSet returns true if any of the fields identifying the backing resource have been specified.
+func (br BackingResource) IsSet() bool {
+	return len(br.Kind) > 0 || len(br.APIVersion) > 0 || len(br.Name) > 0 || len(br.Namespace) > 0 || br.Selector != nil
+}
+
+// ShareObject is implemented by Share and NamespacedShare, which differ only in their scope
+// +k8s:deepcopy-gen=false
+type ShareObject interface {
+	metav1.Object
+	runtime.Object
+	GetSpec() ShareSpec
+	GetStatus() ShareStatus
+}
+
+func (s *Share) GetSpec() ShareSpec {
+	return s.Spec
+}
+
+func (s *Share) GetStatus() ShareStatus {
+	return s.Status
+}
+
+func (s *NamespacedShare) GetSpec() ShareSpec {
+	return s.Spec
+}
+
+func (s *NamespacedShare) GetStatus() ShareStatus {
+	return s.Status
+}
+
+// GetBackingResources returns BackingResource, if set, followed by the entries of BackingResources.
+func (s ShareSpec) GetBackingResources() []BackingResource {
+	brs := []BackingResource{}
+	if s.BackingResource.IsSet() {
+		brs = append(brs, s.BackingResource)
+	}
+	return append(brs, s.BackingResources...)
 }
@@ -0,0 +1,121 @@
+package v1beta1
+
+import (
+	"strings"
+
+	"github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+)
+
+// ConvertShareFromV1alpha1 converts a v1alpha1 Share to v1beta1
+func ConvertShareFromV1alpha1(in *v1alpha1.Share) *Share {
+	out := &Share{
+		ObjectMeta: in.ObjectMeta,
+		Spec:       convertShareSpecFromV1alpha1(in.Spec),
+		Status:     ShareStatus(in.Status),
+	}
+	out.SetGroupVersionKind(SchemeGroupVersion.WithKind("Share"))
+	return out
+}
+
+// ConvertShareToV1alpha1 converts a v1beta1 Share to v1alpha1
+func ConvertShareToV1alpha1(in *Share) *v1alpha1.Share {
+	out := &v1alpha1.Share{
+		ObjectMeta: in.ObjectMeta,
+		Spec:       convertShareSpecToV1alpha1(in.Spec),
+		Status:     v1alpha1.ShareStatus(in.Status),
+	}
+	out.SetGroupVersionKind(v1alpha1.SchemeGroupVersion.WithKind("Share"))
+	return out
+}
+
+// ConvertNamespacedShareFromV1alpha1 converts a v1alpha1 NamespacedShare to v1beta1
+func ConvertNamespacedShareFromV1alpha1(in *v1alpha1.NamespacedShare) *NamespacedShare {
+	out := &NamespacedShare{
+		ObjectMeta: in.ObjectMeta,
+		Spec:       convertShareSpecFromV1alpha1(in.Spec),
+		Status:     ShareStatus(in.Status),
+	}
+	out.SetGroupVersionKind(SchemeGroupVersion.WithKind("NamespacedShare"))
+	return out
+}
+
+// ConvertNamespacedShareToV1alpha1 converts a v1beta1 NamespacedShare to v1alpha1
+func ConvertNamespacedShareToV1alpha1(in *NamespacedShare) *v1alpha1.NamespacedShare {
+	out := &v1alpha1.NamespacedShare{
+		ObjectMeta: in.ObjectMeta,
+		Spec:       convertShareSpecToV1alpha1(in.Spec),
+		Status:     v1alpha1.ShareStatus(in.Status),
+	}
+	out.SetGroupVersionKind(v1alpha1.SchemeGroupVersion.WithKind("NamespacedShare"))
+	return out
+}
+
+func convertShareSpecFromV1alpha1(in v1alpha1.ShareSpec) ShareSpec {
+	out := ShareSpec{
+		Description:          in.Description,
+		UpdatePolicy:         UpdatePolicy(in.UpdatePolicy),
+		DefaultMode:          in.DefaultMode,
+		SecretDefaultMode:    in.SecretDefaultMode,
+		ConfigMapDefaultMode: in.ConfigMapDefaultMode,
+	}
+	if in.BackingResource.IsSet() {
+		br := convertBackingResourceFromV1alpha1(in.BackingResource)
+		out.BackingResource = &br
+	}
+	for _, br := range in.BackingResources {
+		out.BackingResources = append(out.BackingResources, convertBackingResourceFromV1alpha1(br))
+	}
+	return out
+}
+
+func convertShareSpecToV1alpha1(in ShareSpec) v1alpha1.ShareSpec {
+	out := v1alpha1.ShareSpec{
+		Description:          in.Description,
+		UpdatePolicy:         v1alpha1.UpdatePolicy(in.UpdatePolicy),
+		DefaultMode:          in.DefaultMode,
+		SecretDefaultMode:    in.SecretDefaultMode,
+		ConfigMapDefaultMode: in.ConfigMapDefaultMode,
+	}
+	if in.BackingResource != nil {
+		out.BackingResource = convertBackingResourceToV1alpha1(*in.BackingResource)
+	}
+	for _, br := range in.BackingResources {
+		out.BackingResources = append(out.BackingResources, convertBackingResourceToV1alpha1(br))
+	}
+	return out
+}
+
+// convertBackingResourceFromV1alpha1 also sets the apiVersion v1alpha1 leaves optional for ConfigMaps and Secrets
+func convertBackingResourceFromV1alpha1(in v1alpha1.BackingResource) BackingResource {
+	out := BackingResource{
+		Kind:       in.Kind,
+		APIVersion: in.APIVersion,
+		Name:       in.Name,
+		Namespace:  in.Namespace,
+		Items:      in.Items,
+		Selector:   in.Selector,
+	}
+	kind := strings.TrimSpace(in.Kind)
+	if len(strings.TrimSpace(out.APIVersion)) == 0 && (kind == "ConfigMap" || kind == "Secret") {
+		out.APIVersion = "v1"
+	}
+	for _, field := range in.Fields {
+		out.Fields = append(out.Fields, FieldToPath(field))
+	}
+	return out
+}
+
+func convertBackingResourceToV1alpha1(in BackingResource) v1alpha1.BackingResource {
+	out := v1alpha1.BackingResource{
+		Kind:       in.Kind,
+		APIVersion: in.APIVersion,
+		Name:       in.Name,
+		Namespace:  in.Namespace,
+		Items:      in.Items,
+		Selector:   in.Selector,
+	}
+	for _, field := range in.Fields {
+		out.Fields = append(out.Fields, v1alpha1.FieldToPath(field))
+	}
+	return out
+}
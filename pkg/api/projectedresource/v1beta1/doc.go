@@ -0,0 +1,7 @@
+// +k8s:deepcopy-gen=package,register
+// +k8s:defaulter-gen=TypeMeta
+// +k8s:openapi-gen=true
+
+// +groupName=projectedresource.storage.openshift.io
+// Package v1beta1 is the v1beta1 version of the API.
+package v1beta1
@@ -0,0 +1,154 @@
+/*
+Copyright The OpenShift authors.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:storageversion
+
+// Share is the Schema for the shares API
+type Share struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ShareSpec   `json:"spec,omitempty"`
+	Status ShareStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ShareList contains a list of Share
+type ShareList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Share `json:"items"`
+}
+
+// ShareSpec defines the desired state of Share
+type ShareSpec struct {
+	// BackingResource captures the object that is shared.
+	// Either it or BackingResources needs to be set.
+	// +optional
+	BackingResource *BackingResource `json:"backingResource,omitempty"`
+
+	// BackingResources captures additional objects that are shared along with BackingResource.
+	// +optional
+	BackingResources []BackingResource `json:"backingResources,omitempty"`
+
+	// Description is a user readable explanation of what the backing resource
+	// provides.
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// UpdatePolicy determines whether changes to the backing resources reach the volumes of pods
+	// that are already running. Immediate, the default, writes them as soon as the controller sees
+	// them; OnPodRestart keeps the content a volume was mounted with until its pod is recreated.
+	// A pod can override it with the updatePolicy volume attribute.
+	// +optional
+	// +kubebuilder:validation:Enum=Immediate;OnPodRestart
+	UpdatePolicy UpdatePolicy `json:"updatePolicy,omitempty"`
+
+	// DefaultMode is the mode of the files projected from the backing resources, unless an item sets
+	// its own mode or SecretDefaultMode or ConfigMapDefaultMode applies. Defaults to 0644.
+	// A pod can override it with the defaultMode volume attribute.
+	// +optional
+	DefaultMode *int32 `json:"defaultMode,omitempty"`
+
+	// SecretDefaultMode is the mode of the files projected from Secrets, overriding DefaultMode.
+	// A pod can override it with the secretDefaultMode volume attribute.
+	// +optional
+	SecretDefaultMode *int32 `json:"secretDefaultMode,omitempty"`
+
+	// ConfigMapDefaultMode is the mode of the files projected from ConfigMaps, overriding DefaultMode.
+	// A pod can override it with the configMapDefaultMode volume attribute.
+	// +optional
+	ConfigMapDefaultMode *int32 `json:"configMapDefaultMode,omitempty"`
+}
+
+// UpdatePolicy determines when changes to the backing resources of a share reach the volumes consuming it
+type UpdatePolicy string
+
+const (
+	// UpdatePolicyImmediate writes changes to the backing resources into volumes as soon as they are seen.
+	UpdatePolicyImmediate UpdatePolicy = "Immediate"
+
+	// UpdatePolicyOnPodRestart fixes the content of a volume when it is mounted; changes to the backing
+	// resources are only seen by the pods created after them.
+	UpdatePolicyOnPodRestart UpdatePolicy = "OnPodRestart"
+)
+
+// ShareStatus defines the observed state of Share
+type ShareStatus struct {
+	// Conditions are the set of k8s Condition instances provided by the associated controller for Shares.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+type BackingResource struct {
+	// Kind is a string value representing the REST resource this object represents.
+	// The data of a Secret or ConfigMap is projected; for any other kind, the results of Fields are.
+	// +required
+	Kind string `json:"kind"`
+
+	// APIVersion defines the versioned schema of this representation of an object, v1 for
+	// Secrets and ConfigMaps.
+	// +required
+	APIVersion string `json:"apiVersion"`
+
+	// Name is the name of the object serving as the backing resource.
+	// Either it or Selector needs to be set.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Namespace is the namespace of the object serving as the backing resource
+	// +required
+	Namespace string `json:"namespace"`
+
+	// Items, when set, selects the keys of the backing resource that are projected, along with
+	// the path each one is written to and an optional file mode, the same as the items of a
+	// configMap or secret volume. Paths are relative to the root of the volume and may contain
+	// directories, but may not contain '..'. Keys that are not listed are not projected.
+	// When not set, every key is written to a file of the same name under the
+	// configmaps/<namespace>:<name> or secrets/<namespace>:<name> directory of the volume.
+	// +optional
+	Items []corev1.KeyToPath `json:"items,omitempty"`
+
+	// Selector, when set instead of Name, selects every object of the given Kind in Namespace whose
+	// labels match it. Each selected object is written under the configmaps/<namespace>:<name> or
+	// secrets/<namespace>:<name> directory of the volume, which is added and removed as objects start
+	// or stop matching. Items cannot be set along with it.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Fields selects what is projected for a backing resource of a kind other than Secret or ConfigMap,
+	// and needs to be set for those kinds. Each JSONPath expression is evaluated against the object,
+	// and the result written to the file at the associated path, relative to the root of the volume.
+	// +optional
+	Fields []FieldToPath `json:"fields,omitempty"`
+}
+
+// FieldToPath maps the result of a JSONPath expression evaluated against a backing resource to a file
+type FieldToPath struct {
+	// JSONPath is the expression evaluated against the backing resource, for example {.spec.endpoint}.
+	// The enclosing braces are optional. A string result is written as is, anything else as JSON.
+	// +required
+	JSONPath string `json:"jsonPath"`
+
+	// Path is the relative path of the file to write the result to. May not be an absolute path.
+	// May not contain the path element '..'.
+	// +required
+	Path string `json:"path"`
+
+	// Mode bits used to set permissions on the file, between 0 and 0777. Defaults to 0644.
+	// +optional
+	Mode *int32 `json:"mode,omitempty"`
+}
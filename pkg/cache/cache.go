@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	sharev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+)
+
+// Callback is called by the cache with the key and value of an object or share a volume uses, in the form of a
+// sync.Map ranger; its return value is ignored
+type Callback func(key, value interface{}) bool
+
+// VolumeObjects are the objects of one kind a volume projects: those its backing resources name by key, and
+// those of the namespaces its backing resources select objects in
+type VolumeObjects struct {
+	Keys       []string
+	Namespaces []string
+}
+
+// Cache holds the backing resources of shares, and the shares, along with the callbacks of the volumes that
+// use them. The callbacks are indexed by the keys and namespaces of the objects, and by the shares, the volumes
+// use, so that an event only reaches the volumes it affects
+type Cache struct {
+	lock sync.RWMutex
+
+	// the objects of each kind of backing resource, keyed by KindKey
+	kinds map[string]*kindCache
+
+	shares               map[string]sharev1alpha1.ShareObject
+	shareUpdateCallbacks map[string]Callback
+	shareDeleteCallbacks map[string]Callback
+	// the share key of each volume with share callbacks, and the volumes of each share key
+	volumeShares   map[string]string
+	volumesByShare map[string]sets.String
+}
+
+var (
+	defaultCache = NewCache()
+)
+
+func NewCache() *Cache {
+	return &Cache{
+		kinds:                map[string]*kindCache{},
+		shares:               map[string]sharev1alpha1.ShareObject{},
+		shareUpdateCallbacks: map[string]Callback{},
+		shareDeleteCallbacks: map[string]Callback{},
+		volumeShares:         map[string]string{},
+		volumesByShare:       map[string]sets.String{},
+	}
+}
+
+// addToIndex adds a volume to the set of an index entry
+func addToIndex(index map[string]sets.String, key, volID string) {
+	volIDs, ok := index[key]
+	if !ok {
+		volIDs = sets.NewString()
+		index[key] = volIDs
+	}
+	volIDs.Insert(volID)
+}
+
+// removeFromIndex removes a volume from the set of an index entry, dropping the entry once it is empty
+func removeFromIndex(index map[string]sets.String, key, volID string) {
+	volIDs, ok := index[key]
+	if !ok {
+		return
+	}
+	volIDs.Delete(volID)
+	if volIDs.Len() == 0 {
+		delete(index, key)
+	}
+}
+
+// callbacksOf returns the callbacks of the given volumes; they are called once the lock of the cache is released,
+// since they use the cache in turn
+func callbacksOf(callbacks map[string]Callback, volIDs sets.String) []Callback {
+	ret := []Callback{}
+	for volID := range volIDs {
+		if f, ok := callbacks[volID]; ok {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
+
+func call(callbacks []Callback, key, value interface{}) {
+	for _, f := range callbacks {
+		f(key, value)
+	}
+}
@@ -0,0 +1,237 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sharev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+)
+
+// recorder records the keys the callbacks of each volume were called with
+type recorder struct {
+	calls map[string][]string
+}
+
+func (r *recorder) callback(volID string) Callback {
+	return func(key, value interface{}) bool {
+		r.calls[volID] = append(r.calls[volID], key.(string))
+		return true
+	}
+}
+
+func (r *recorder) expect(t *testing.T, step string, expected map[string][]string) {
+	if len(r.calls) != len(expected) {
+		t.Fatalf("%s: expected calls %v got %v", step, expected, r.calls)
+	}
+	for volID, keys := range expected {
+		if fmt.Sprint(r.calls[volID]) != fmt.Sprint(keys) {
+			t.Fatalf("%s: expected calls %v got %v", step, expected, r.calls)
+		}
+	}
+	r.calls = map[string][]string{}
+}
+
+func configMap(namespace, name string, labels map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels}}
+}
+
+func TestObjectCallbacks(t *testing.T) {
+	c := NewCache()
+	upserts := &recorder{calls: map[string][]string{}}
+	deletes := &recorder{calls: map[string][]string{}}
+	c.UpsertObject("ConfigMap", configMap("namespace1", "cm1", nil))
+
+	named := VolumeObjects{Keys: []string{"namespace1:cm1"}}
+	selected := VolumeObjects{Namespaces: []string{"namespace2"}}
+	c.RegisterObjectUpsertCallback("ConfigMap", "vol1", named, upserts.callback("vol1"))
+	c.RegisterObjectDeleteCallback("ConfigMap", "vol1", named, deletes.callback("vol1"))
+	c.RegisterObjectUpsertCallback("ConfigMap", "vol2", selected, upserts.callback("vol2"))
+	c.RegisterObjectDeleteCallback("ConfigMap", "vol2", selected, deletes.callback("vol2"))
+	upserts.expect(t, "register", map[string][]string{})
+
+	c.UpsertObject("ConfigMap", configMap("namespace1", "cm1", nil))
+	upserts.expect(t, "upsert named", map[string][]string{"vol1": {"namespace1:cm1"}})
+	c.UpsertObject("ConfigMap", configMap("namespace2", "cm2", map[string]string{"app": "a"}))
+	upserts.expect(t, "upsert selected", map[string][]string{"vol2": {"namespace2:cm2"}})
+	c.UpsertObject("ConfigMap", configMap("namespace3", "cm3", nil))
+	upserts.expect(t, "upsert unused", map[string][]string{})
+
+	if objs := c.ListObjects("ConfigMap", "namespace2", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}}); len(objs) != 1 {
+		t.Fatalf("expected one object listed got %v", objs)
+	}
+
+	c.DelObject("ConfigMap", configMap("namespace2", "cm2", nil))
+	deletes.expect(t, "delete selected", map[string][]string{"vol2": {"namespace2:cm2"}})
+	if c.GetObject("ConfigMap", "namespace2:cm2") != nil {
+		t.Fatalf("expected the deleted object to be gone")
+	}
+
+	c.UnregisterObjectCallbacks("vol1")
+	c.UpsertObject("ConfigMap", configMap("namespace1", "cm1", nil))
+	c.DelObject("ConfigMap", configMap("namespace1", "cm1", nil))
+	upserts.expect(t, "unregistered upsert", map[string][]string{})
+	deletes.expect(t, "unregistered delete", map[string][]string{})
+}
+
+func TestShareCallbacks(t *testing.T) {
+	c := NewCache()
+	updates := &recorder{calls: map[string][]string{}}
+	deletes := &recorder{calls: map[string][]string{}}
+	c.UpsertObject("ConfigMap", configMap("namespace1", "cm1", nil))
+	share := func(name string) *sharev1alpha1.Share {
+		return &sharev1alpha1.Share{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: sharev1alpha1.ShareSpec{
+				BackingResource: sharev1alpha1.BackingResource{Kind: "ConfigMap", APIVersion: "v1", Namespace: "namespace1", Name: "cm1"},
+			},
+		}
+	}
+	for volID, shareKey := range map[string]string{"vol1": "share1", "vol2": "share1", "vol3": "share2"} {
+		c.RegisterShareUpdateCallback(volID, shareKey, updates.callback(volID))
+		c.RegisterShareDeleteCallback(volID, shareKey, deletes.callback(volID))
+	}
+
+	c.AddShare(share("share1"))
+	updates.expect(t, "add", map[string][]string{"vol1": {"share1"}, "vol2": {"share1"}})
+	if c.GetObject("ConfigMap", "namespace1:cm1") == nil {
+		t.Fatalf("expected the object of the share")
+	}
+
+	// a change of the spec alone, here of the description, still reaches the volumes of the share
+	updated := share("share1")
+	updated.Spec.Description = "updated"
+	c.UpdateShare(updated)
+	updates.expect(t, "update spec", map[string][]string{"vol1": {"share1"}, "vol2": {"share1"}})
+
+	// as does a switch to a backing object that does not exist yet
+	missing := share("share2")
+	missing.Spec.BackingResource.Name = "missing"
+	c.UpdateShare(missing)
+	updates.expect(t, "update missing object", map[string][]string{"vol3": {"share2"}})
+
+	c.DelShare(share("share2"))
+	deletes.expect(t, "delete", map[string][]string{"vol3": {"share2"}})
+
+	c.UnregisterShareUpdateCallback("vol1")
+	c.UnregisterShareDeleteCallback("vol1")
+	c.DelShare(share("share1"))
+	deletes.expect(t, "unregistered delete", map[string][]string{"vol2": {"share1"}})
+}
+
+func TestOverlappingShares(t *testing.T) {
+	c := NewCache()
+	c.UpsertObject("ConfigMap", configMap("namespace1", "cm1", nil))
+	c.UpsertObject("ConfigMap", configMap("namespace1", "cm2", nil))
+	share := func(name, cmName string) *sharev1alpha1.Share {
+		return &sharev1alpha1.Share{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: sharev1alpha1.ShareSpec{
+				BackingResource: sharev1alpha1.BackingResource{Kind: "ConfigMap", APIVersion: "v1", Namespace: "namespace1", Name: cmName},
+			},
+		}
+	}
+	expectObjects := func(step string, expected map[string]bool) {
+		for key, found := range expected {
+			if (c.GetObject("ConfigMap", key) != nil) != found {
+				t.Fatalf("%s: expected object %s found %v", step, key, found)
+			}
+		}
+	}
+
+	c.AddShare(share("share1", "cm1"))
+	c.AddShare(share("share2", "cm1"))
+	c.DelShare(share("share1", "cm1"))
+	expectObjects("delete one of two shares", map[string]bool{"namespace1:cm1": true})
+
+	c.AddShare(share("share1", "cm1"))
+	c.UpdateShare(share("share2", "cm2"))
+	expectObjects("update one of two shares", map[string]bool{"namespace1:cm1": true, "namespace1:cm2": true})
+
+	c.UpdateShare(share("share1", "cm2"))
+	expectObjects("update the other share", map[string]bool{"namespace1:cm1": false, "namespace1:cm2": true})
+
+	c.DelShare(share("share1", "cm2"))
+	expectObjects("delete one of two shares again", map[string]bool{"namespace1:cm2": true})
+	c.DelShare(share("share2", "cm2"))
+	expectObjects("delete the last share", map[string]bool{"namespace1:cm2": false})
+
+	// a resync of the informers does not bring back an object no share references
+	c.UpsertObject("ConfigMap", configMap("namespace1", "cm2", nil))
+	expectObjects("upsert after the last share", map[string]bool{"namespace1:cm2": false})
+}
+
+func TestSelectorShareReferences(t *testing.T) {
+	c := NewCache()
+	c.AddShare(&sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{Name: "share1"},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{Kind: "ConfigMap", APIVersion: "v1", Namespace: "namespace1",
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}}},
+		},
+	})
+	for _, test := range []struct {
+		name     string
+		cm       *corev1.ConfigMap
+		expected bool
+	}{
+		{name: "matching object", cm: configMap("namespace1", "cm1", map[string]string{"app": "a"}), expected: true},
+		{name: "object no longer matching", cm: configMap("namespace1", "cm1", nil), expected: false},
+		{name: "object of another namespace", cm: configMap("namespace2", "cm1", map[string]string{"app": "a"}), expected: false},
+	} {
+		c.UpsertObject("ConfigMap", test.cm)
+		if (c.GetObject("ConfigMap", GetKey(test.cm)) != nil) != test.expected {
+			t.Fatalf("test %s expected object found %v", test.name, test.expected)
+		}
+	}
+}
+
+// unindexedUpsert fans an upsert out the way the cache did before it was indexed: to the callback of every
+// volume, each filtering on the keys it projects
+func unindexedUpsert(callbacks *sync.Map, key string, obj interface{}) {
+	callbacks.Range(func(volID, f interface{}) bool {
+		f.(Callback)(key, obj)
+		return true
+	})
+}
+
+// BenchmarkUpsertObject measures the upsert of an object projected by one volume among many; with the index,
+// its cost does not grow with the number of volumes
+func BenchmarkUpsertObject(b *testing.B) {
+	for _, volumes := range []int{100, 1000, 10000} {
+		cms := []*corev1.ConfigMap{}
+		for i := 0; i < volumes; i++ {
+			cms = append(cms, configMap("namespace", fmt.Sprintf("cm%d", i), nil))
+		}
+
+		b.Run(fmt.Sprintf("indexed-%d", volumes), func(b *testing.B) {
+			c := NewCache()
+			for i, cm := range cms {
+				c.RegisterObjectUpsertCallback("ConfigMap", fmt.Sprintf("vol%d", i), VolumeObjects{Keys: []string{GetKey(cm)}},
+					func(key, value interface{}) bool { return true })
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.UpsertObject("ConfigMap", cms[i%volumes])
+			}
+		})
+
+		b.Run(fmt.Sprintf("unindexed-%d", volumes), func(b *testing.B) {
+			callbacks := &sync.Map{}
+			for i, cm := range cms {
+				key := GetKey(cm)
+				callbacks.Store(fmt.Sprintf("vol%d", i), Callback(func(k, value interface{}) bool {
+					return k.(string) != key
+				}))
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cm := cms[i%volumes]
+				unindexedUpsert(callbacks, GetKey(cm), cm)
+			}
+		})
+	}
+}
@@ -2,9 +2,9 @@ package cache
 
 import (
 	"fmt"
-	"sync"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 func GetKey(o interface{}) string {
@@ -19,16 +19,16 @@ func BuildKey(namespace, name string) string {
 	return namespace + ":" + name
 }
 
-func buildCallbackMap(key, value interface{}) *sync.Map {
-	c := &sync.Map{}
-	c.Store(key, value)
-	return c
-}
-
-func buildRanger(m *sync.Map) func(key, value interface{}) bool {
-	return func(key, value interface{}) bool {
-		f, _ := value.(func(key, value interface{}) bool)
-		m.Range(f)
-		return true
+// MatchesSelector returns true if the object is in the given namespace and its labels match the
+// selector of a backing resource
+func MatchesSelector(namespace string, selector *metav1.LabelSelector, o interface{}) bool {
+	obj, ok := o.(metav1.Object)
+	if !ok || selector == nil || obj.GetNamespace() != namespace {
+		return false
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
 	}
+	return s.Matches(labels.Set(obj.GetLabels()))
 }
@@ -0,0 +1,249 @@
+package cache
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// kindCache holds the objects of one kind of backing resource, along with the callbacks of the
+// volumes projecting them
+type kindCache struct {
+	// the objects of the kind, by namespace and then by key
+	objects map[string]map[string]runtime.Object
+	// the objects shares reference, by key; objects a share names that do not exist yet are stored as their key
+	objectsWithShares map[string]interface{}
+	// the shares referencing each key, and the keys each share references, so that an object stays referenced
+	// until the last share using it goes away
+	sharesByKey map[string]sets.String
+	keysByShare map[string]sets.String
+	// the shares with backing resources selecting objects of the kind, by namespace
+	selectorShares  map[string]sets.String
+	upsertCallbacks map[string]Callback
+	deleteCallbacks map[string]Callback
+	// the objects each volume with callbacks projects, and the volumes projecting each key and namespace
+	volumeObjects      map[string]VolumeObjects
+	volumesByKey       map[string]sets.String
+	volumesByNamespace map[string]sets.String
+}
+
+// KindKey identifies a kind of backing resource; ConfigMaps and Secrets by their kind alone,
+// any other kind by its group, version and kind
+func KindKey(apiVersion, kind string) string {
+	kind = strings.TrimSpace(kind)
+	if kind == "ConfigMap" || kind == "Secret" {
+		return kind
+	}
+	return schema.FromAPIVersionAndKind(strings.TrimSpace(apiVersion), kind).String()
+}
+
+// kind returns the cache of a kind, creating it if needed; the lock of the cache has to be held for writing
+func (c *Cache) kind(kindKey string) *kindCache {
+	kc, ok := c.kinds[kindKey]
+	if !ok {
+		kc = &kindCache{
+			objects:            map[string]map[string]runtime.Object{},
+			objectsWithShares:  map[string]interface{}{},
+			sharesByKey:        map[string]sets.String{},
+			keysByShare:        map[string]sets.String{},
+			selectorShares:     map[string]sets.String{},
+			upsertCallbacks:    map[string]Callback{},
+			deleteCallbacks:    map[string]Callback{},
+			volumeObjects:      map[string]VolumeObjects{},
+			volumesByKey:       map[string]sets.String{},
+			volumesByNamespace: map[string]sets.String{},
+		}
+		c.kinds[kindKey] = kc
+	}
+	return kc
+}
+
+func namespaceOf(obj interface{}) string {
+	if o, ok := obj.(metav1.Object); ok {
+		return o.GetNamespace()
+	}
+	return ""
+}
+
+// index records the objects a volume projects, replacing those it was recorded with before
+func (kc *kindCache) index(volID string, objects VolumeObjects) {
+	kc.unindex(volID)
+	kc.volumeObjects[volID] = objects
+	for _, key := range objects.Keys {
+		addToIndex(kc.volumesByKey, key, volID)
+	}
+	for _, namespace := range objects.Namespaces {
+		addToIndex(kc.volumesByNamespace, namespace, volID)
+	}
+}
+
+func (kc *kindCache) unindex(volID string) {
+	objects, ok := kc.volumeObjects[volID]
+	if !ok {
+		return
+	}
+	for _, key := range objects.Keys {
+		removeFromIndex(kc.volumesByKey, key, volID)
+	}
+	for _, namespace := range objects.Namespaces {
+		removeFromIndex(kc.volumesByNamespace, namespace, volID)
+	}
+	delete(kc.volumeObjects, volID)
+}
+
+// volumes returns the volumes projecting the object of the given key and namespace
+func (kc *kindCache) volumes(key, namespace string) sets.String {
+	return kc.volumesByKey[key].Union(kc.volumesByNamespace[namespace])
+}
+
+// list returns the objects of the kind in the namespace whose labels match the selector
+func (kc *kindCache) list(namespace string, selector *metav1.LabelSelector) []runtime.Object {
+	matches := []runtime.Object{}
+	for _, obj := range kc.objects[namespace] {
+		if MatchesSelector(namespace, selector, obj) {
+			matches = append(matches, obj)
+		}
+	}
+	return matches
+}
+
+// referenceSelected records the references to an object of the shares selecting objects in its namespace, as
+// its labels start or stop matching their selectors; the lock of the cache has to be held for writing
+func (c *Cache) referenceSelected(kindKey string, kc *kindCache, key string, obj runtime.Object) {
+	for shareKey := range kc.selectorShares[namespaceOf(obj)] {
+		share, ok := c.shares[shareKey]
+		if !ok {
+			continue
+		}
+		if referencesObject(share, kindKey, key, obj) {
+			kc.addReference(shareKey, key)
+		} else {
+			kc.removeReference(shareKey, key)
+		}
+	}
+}
+
+// GetObject returns the object of the given kind and key if a share references it
+func (c *Cache) GetObject(kindKey string, key interface{}) runtime.Object {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	kc, ok := c.kinds[kindKey]
+	if !ok {
+		return nil
+	}
+	k, _ := key.(string)
+	o, _ := kc.objectsWithShares[k].(runtime.Object)
+	return o
+}
+
+// ListObjects returns the objects of the given kind in the namespace whose labels match the selector
+func (c *Cache) ListObjects(kindKey, namespace string, selector *metav1.LabelSelector) []runtime.Object {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	kc, ok := c.kinds[kindKey]
+	if !ok {
+		return []runtime.Object{}
+	}
+	return kc.list(namespace, selector)
+}
+
+func (c *Cache) UpsertObject(kindKey string, obj runtime.Object) {
+	key, namespace := GetKey(obj), namespaceOf(obj)
+	c.lock.Lock()
+	kc := c.kind(kindKey)
+	if _, ok := kc.objects[namespace]; !ok {
+		kc.objects[namespace] = map[string]runtime.Object{}
+	}
+	kc.objects[namespace][key] = obj
+	c.referenceSelected(kindKey, kc, key, obj)
+	// only the objects a share references are kept, so that they are dropped once the last of those goes away
+	if _, ok := kc.sharesByKey[key]; ok {
+		kc.objectsWithShares[key] = obj
+	} else {
+		delete(kc.objectsWithShares, key)
+	}
+	callbacks := callbacksOf(kc.upsertCallbacks, kc.volumes(key, namespace))
+	c.lock.Unlock()
+	call(callbacks, key, obj)
+}
+
+func (c *Cache) DelObject(kindKey string, obj runtime.Object) {
+	key, namespace := GetKey(obj), namespaceOf(obj)
+	c.lock.Lock()
+	kc := c.kind(kindKey)
+	delete(kc.objects[namespace], key)
+	if len(kc.objects[namespace]) == 0 {
+		delete(kc.objects, namespace)
+	}
+	// removed before the callbacks run, so that volumes written again by them no longer include the object
+	delete(kc.objectsWithShares, key)
+	callbacks := callbacksOf(kc.deleteCallbacks, kc.volumes(key, namespace))
+	c.lock.Unlock()
+	call(callbacks, key, obj)
+}
+
+// RegisterObjectUpsertCallback registers the callback of a volume for the upserts of the objects of a kind it
+// projects; the objects already known are not replayed, the volume is written with them by its caller once its
+// callbacks are registered
+func (c *Cache) RegisterObjectUpsertCallback(kindKey, volID string, objects VolumeObjects, f Callback) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	kc := c.kind(kindKey)
+	kc.index(volID, objects)
+	kc.upsertCallbacks[volID] = f
+}
+
+// RegisterObjectDeleteCallback registers the callback of a volume for the deletes of the objects of a kind it
+// projects
+func (c *Cache) RegisterObjectDeleteCallback(kindKey, volID string, objects VolumeObjects, f Callback) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	kc := c.kind(kindKey)
+	kc.index(volID, objects)
+	kc.deleteCallbacks[volID] = f
+}
+
+// UnregisterObjectCallbacks removes the upsert and delete callbacks of a volume for every kind
+func (c *Cache) UnregisterObjectCallbacks(volID string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, kc := range c.kinds {
+		delete(kc.upsertCallbacks, volID)
+		delete(kc.deleteCallbacks, volID)
+		kc.unindex(volID)
+	}
+}
+
+// GetObject returns the object of the given kind and key if a share references it
+func GetObject(kindKey string, key interface{}) runtime.Object {
+	return defaultCache.GetObject(kindKey, key)
+}
+
+// ListObjects returns the objects of the given kind in the namespace whose labels match the selector
+func ListObjects(kindKey, namespace string, selector *metav1.LabelSelector) []runtime.Object {
+	return defaultCache.ListObjects(kindKey, namespace, selector)
+}
+
+func UpsertObject(kindKey string, obj runtime.Object) {
+	defaultCache.UpsertObject(kindKey, obj)
+}
+
+func DelObject(kindKey string, obj runtime.Object) {
+	defaultCache.DelObject(kindKey, obj)
+}
+
+func RegisterObjectUpsertCallback(kindKey, volID string, objects VolumeObjects, f Callback) {
+	defaultCache.RegisterObjectUpsertCallback(kindKey, volID, objects, f)
+}
+
+func RegisterObjectDeleteCallback(kindKey, volID string, objects VolumeObjects, f Callback) {
+	defaultCache.RegisterObjectDeleteCallback(kindKey, volID, objects, f)
+}
+
+// UnregisterObjectCallbacks removes the upsert and delete callbacks of a volume for every kind
+func UnregisterObjectCallbacks(volID string) {
+	defaultCache.UnregisterObjectCallbacks(volID)
+}
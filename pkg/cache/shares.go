@@ -1,96 +1,244 @@
 package cache
 
 import (
-	corev1 "k8s.io/api/core/v1"
-	"sync"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
 
 	sharev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
 )
 
-var (
-	shares               = sync.Map{}
-	shareUpdateCallbacks = sync.Map{}
-	shareDeleteCallbacks = sync.Map{}
-)
+// GetShareKey returns the key Shares and NamespacedShares are stored and passed to the share callbacks
+// with; the name of a Share, and the namespace and name of a NamespacedShare
+func GetShareKey(share sharev1alpha1.ShareObject) string {
+	if len(share.GetNamespace()) == 0 {
+		return share.GetName()
+	}
+	return BuildKey(share.GetNamespace(), share.GetName())
+}
 
-func AddShare(share *sharev1alpha1.Share) {
-	br := share.Spec.BackingResource
-	key := BuildKey(br.Namespace, br.Name)
-	switch br.Kind {
-	case "ConfigMap":
-		obj, ok := configmaps.Load(key)
-		if obj != nil && ok {
-			cm := obj.(*corev1.ConfigMap)
-			configmapsWithShares.Store(key, cm)
-			shareUpdateCallbacks.Range(buildRanger(buildCallbackMap(share.Name, share)))
-		} else {
-			configmapsWithShares.Store(key, key)
+// addShare records the objects the backing resources of a share reference, in place of those it referenced
+// before, and returns the update callbacks of the volumes of the share if any of those exist; the lock of the
+// cache has to be held for writing
+func (c *Cache) addShare(share sharev1alpha1.ShareObject) []Callback {
+	shareKey := GetShareKey(share)
+	c.dropReferences(shareKey)
+	c.shares[shareKey] = copyShare(share)
+	found := false
+	for _, br := range share.GetSpec().GetBackingResources() {
+		kc := c.kind(KindKey(br.APIVersion, br.Kind))
+		if br.Selector != nil {
+			// objects that start matching later on are picked up as they are upserted
+			addToIndex(kc.selectorShares, br.Namespace, shareKey)
+			for _, obj := range kc.list(br.Namespace, br.Selector) {
+				key := GetKey(obj)
+				kc.addReference(shareKey, key)
+				kc.objectsWithShares[key] = obj
+				found = true
+			}
+			continue
 		}
-	case "Secret":
-		obj, ok := secrets.Load(key)
-		if obj != nil && ok {
-			s := obj.(*corev1.Secret)
-			secretsWithShare.Store(key, s)
-			shareUpdateCallbacks.Range(buildRanger(buildCallbackMap(share.Name, share)))
+		key := BuildKey(br.Namespace, br.Name)
+		kc.addReference(shareKey, key)
+		if obj, ok := kc.objects[br.Namespace][key]; ok && obj != nil {
+			kc.objectsWithShares[key] = obj
+			found = true
 		} else {
-			secretsWithShare.Store(key, key)
+			kc.objectsWithShares[key] = key
 		}
 	}
+	if !found {
+		return nil
+	}
+	return callbacksOf(c.shareUpdateCallbacks, c.volumesByShare[shareKey])
 }
 
-func UpdateShare(share *sharev1alpha1.Share) {
-	old, ok := shares.Load(share.Name)
-	if !ok || old == nil {
-		AddShare(share)
-		return
+// dropReferences forgets the objects a share referenced, dropping those no other share references from the
+// objects with shares; the lock of the cache has to be held for writing
+func (c *Cache) dropReferences(shareKey string) {
+	for _, kc := range c.kinds {
+		for key := range kc.keysByShare[shareKey] {
+			removeFromIndex(kc.sharesByKey, key, shareKey)
+			if _, ok := kc.sharesByKey[key]; !ok {
+				delete(kc.objectsWithShares, key)
+			}
+		}
+		delete(kc.keysByShare, shareKey)
+		for namespace := range kc.selectorShares {
+			removeFromIndex(kc.selectorShares, namespace, shareKey)
+		}
 	}
-	oldShare := old.(*sharev1alpha1.Share)
-	diffInstance := false
-	oldBr := oldShare.Spec.BackingResource
-	newBr := share.Spec.BackingResource
-	switch {
-	case oldBr.Kind != newBr.Kind:
-		diffInstance = true
-	case oldBr.Namespace != newBr.Namespace:
-		diffInstance = true
-	case oldBr.Name != newBr.Name:
-		diffInstance = true
+}
+
+func (kc *kindCache) addReference(shareKey, key string) {
+	addToIndex(kc.sharesByKey, key, shareKey)
+	addToIndex(kc.keysByShare, shareKey, key)
+}
+
+func (kc *kindCache) removeReference(shareKey, key string) {
+	removeFromIndex(kc.sharesByKey, key, shareKey)
+	removeFromIndex(kc.keysByShare, shareKey, key)
+}
+
+// referencesObject returns true if a backing resource of a share names the object, or selects it
+func referencesObject(share sharev1alpha1.ShareObject, kindKey, key string, obj runtime.Object) bool {
+	for _, br := range share.GetSpec().GetBackingResources() {
+		if KindKey(br.APIVersion, br.Kind) != kindKey {
+			continue
+		}
+		if br.Selector == nil && BuildKey(br.Namespace, br.Name) == key {
+			return true
+		}
+		if br.Selector != nil && MatchesSelector(br.Namespace, br.Selector, obj) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyShare returns a copy of a share to record, so that the next update is compared with the share as it was
+// rather than with an object its caller may have modified since
+func copyShare(share sharev1alpha1.ShareObject) sharev1alpha1.ShareObject {
+	return share.DeepCopyObject().(sharev1alpha1.ShareObject)
+}
+
+// backingResourcesDiffer returns true if the two lists do not reference the same objects in the same order
+func backingResourcesDiffer(oldBrs, newBrs []sharev1alpha1.BackingResource) bool {
+	if len(oldBrs) != len(newBrs) {
+		return true
+	}
+	for i := range oldBrs {
+		switch {
+		case KindKey(oldBrs[i].APIVersion, oldBrs[i].Kind) != KindKey(newBrs[i].APIVersion, newBrs[i].Kind):
+			return true
+		case oldBrs[i].Namespace != newBrs[i].Namespace:
+			return true
+		case oldBrs[i].Name != newBrs[i].Name:
+			return true
+		case !equality.Semantic.DeepEqual(oldBrs[i].Selector, newBrs[i].Selector):
+			return true
+		}
 	}
-	if !diffInstance {
-		shareUpdateCallbacks.Range(buildRanger(buildCallbackMap(share.Name, share)))
+	return false
+}
+
+func (c *Cache) AddShare(share sharev1alpha1.ShareObject) {
+	c.lock.Lock()
+	callbacks := c.addShare(share)
+	c.lock.Unlock()
+	call(callbacks, GetShareKey(share), share)
+}
+
+// UpdateShare records the objects the backing resources of a share now reference, and calls the update
+// callbacks of the volumes of the share, whatever changed in its spec
+func (c *Cache) UpdateShare(share sharev1alpha1.ShareObject) {
+	shareKey := GetShareKey(share)
+	c.lock.Lock()
+	old, ok := c.shares[shareKey]
+	if !ok || old == nil || backingResourcesDiffer(old.GetSpec().GetBackingResources(), share.GetSpec().GetBackingResources()) {
+		c.addShare(share)
+	}
+	c.shares[shareKey] = copyShare(share)
+	callbacks := callbacksOf(c.shareUpdateCallbacks, c.volumesByShare[shareKey])
+	c.lock.Unlock()
+	call(callbacks, shareKey, share)
+}
+
+func (c *Cache) DelShare(share sharev1alpha1.ShareObject) {
+	shareKey := GetShareKey(share)
+	c.lock.Lock()
+	c.dropReferences(shareKey)
+	delete(c.shares, shareKey)
+	callbacks := callbacksOf(c.shareDeleteCallbacks, c.volumesByShare[shareKey])
+	c.lock.Unlock()
+	call(callbacks, shareKey, share)
+}
+
+// RegisterShareUpdateCallback registers the callback of a volume for the updates of the share it uses
+func (c *Cache) RegisterShareUpdateCallback(volID, shareKey string, f Callback) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.indexShare(volID, shareKey)
+	c.shareUpdateCallbacks[volID] = f
+}
+
+func (c *Cache) UnregisterShareUpdateCallback(volID string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.shareUpdateCallbacks, volID)
+	c.unindexShare(volID)
+}
+
+// RegisterShareDeleteCallback registers the callback of a volume for the delete of the share it uses
+func (c *Cache) RegisterShareDeleteCallback(volID, shareKey string, f Callback) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.indexShare(volID, shareKey)
+	c.shareDeleteCallbacks[volID] = f
+}
+
+func (c *Cache) UnregisterShareDeleteCallback(volID string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.shareDeleteCallbacks, volID)
+	c.unindexShare(volID)
+}
+
+func (c *Cache) indexShare(volID, shareKey string) {
+	if old, ok := c.volumeShares[volID]; ok {
+		removeFromIndex(c.volumesByShare, old, volID)
+	}
+	c.volumeShares[volID] = shareKey
+	addToIndex(c.volumesByShare, shareKey, volID)
+}
+
+// unindexShare drops a volume from the index of the shares once it has no share callback left
+func (c *Cache) unindexShare(volID string) {
+	_, hasUpdate := c.shareUpdateCallbacks[volID]
+	_, hasDelete := c.shareDeleteCallbacks[volID]
+	shareKey, ok := c.volumeShares[volID]
+	if hasUpdate || hasDelete || !ok {
 		return
 	}
+	removeFromIndex(c.volumesByShare, shareKey, volID)
+	delete(c.volumeShares, volID)
+}
+
+func AddShare(share *sharev1alpha1.Share) {
+	defaultCache.AddShare(share)
+}
 
-	shares.Store(share.Name, share)
-	br := share.Spec.BackingResource
-	key := BuildKey(br.Namespace, br.Name)
-	configmapsWithShares.Delete(key)
-	secretsWithShare.Delete(key)
-	AddShare(share)
+func AddNamespacedShare(share *sharev1alpha1.NamespacedShare) {
+	defaultCache.AddShare(share)
+}
+
+func UpdateShare(share *sharev1alpha1.Share) {
+	defaultCache.UpdateShare(share)
+}
+
+func UpdateNamespacedShare(share *sharev1alpha1.NamespacedShare) {
+	defaultCache.UpdateShare(share)
 }
 
 func DelShare(share *sharev1alpha1.Share) {
-	br := share.Spec.BackingResource
-	key := BuildKey(br.Namespace, br.Name)
-	configmapsWithShares.Delete(key)
-	secretsWithShare.Delete(key)
-	shares.Delete(share.Name)
-	shareDeleteCallbacks.Range(buildRanger(buildCallbackMap(share.Name, share)))
+	defaultCache.DelShare(share)
+}
+
+func DelNamespacedShare(share *sharev1alpha1.NamespacedShare) {
+	defaultCache.DelShare(share)
 }
 
-func RegisterShareUpdateCallback(volID string, f func(key, value interface{}) bool) {
-	shareUpdateCallbacks.Store(volID, f)
-	shares.Range(f)
+func RegisterShareUpdateCallback(volID, shareKey string, f Callback) {
+	defaultCache.RegisterShareUpdateCallback(volID, shareKey, f)
 }
 
 func UnregisterShareUpdateCallback(volID string) {
-	secretUpsertCallbacks.Delete(volID)
+	defaultCache.UnregisterShareUpdateCallback(volID)
 }
 
-func RegisterShareDeleteCallback(volID string, f func(key, value interface{}) bool) {
-	shareDeleteCallbacks.Store(volID, f)
+func RegisterShareDeleteCallback(volID, shareKey string, f Callback) {
+	defaultCache.RegisterShareDeleteCallback(volID, shareKey, f)
 }
 
 func UnregisterShareDeleteCallback(volID string) {
-	shareDeleteCallbacks.Delete(volID)
+	defaultCache.UnregisterShareDeleteCallback(volID)
 }
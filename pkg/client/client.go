@@ -14,12 +14,14 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
 	ktypedclient "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/record"
+
+	"github.com/openshift/csi-driver-projected-resource/pkg/metrics"
 )
 
 const (
@@ -37,6 +39,11 @@ func SetClient(client kubernetes.Interface) {
 	kubeClient = client
 }
 
+// SetRecorder sets the event recorder. Useful for testing.
+func SetRecorder(r record.EventRecorder) {
+	recorder = r
+}
+
 func GetRecorder() record.EventRecorder {
 	return recorder
 }
@@ -83,24 +90,43 @@ func initClient() error {
 		}
 
 	}
-	eventBroadcaster := record.NewBroadcaster()
-	eventBroadcaster.StartRecordingToSink(&ktypedclient.EventSinkImpl{Interface: kubeClient.CoreV1().Events(DefaultNamespace)})
-	recorder = eventBroadcaster.NewRecorder(runtime.NewScheme(), corev1.EventSource{Component: DefaultNamespace})
+	if recorder == nil {
+		eventBroadcaster := record.NewBroadcaster()
+		eventBroadcaster.StartRecordingToSink(&ktypedclient.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+		recorder = eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: DefaultNamespace})
+	}
 	return nil
 }
 
 func ExecuteSAR(shareName, podNamespace, podName, podSA string) (bool, error) {
-	err := initClient()
-	if err != nil {
-		return false, err
-	}
-	sarClient := kubeClient.AuthorizationV1().SubjectAccessReviews()
 	resourceAttributes := &authorizationv1.ResourceAttributes{
 		Verb:     "get",
 		Group:    "projectedresource.storage.openshift.io",
 		Resource: "shares",
 		Name:     shareName,
 	}
+	return executeSAR(resourceAttributes, "share", shareName, podNamespace, podName, podSA)
+}
+
+// ExecuteNamespacedShareSAR checks whether the service account of a pod can get the named NamespacedShare
+// in the namespace of the pod
+func ExecuteNamespacedShareSAR(shareName, podNamespace, podName, podSA string) (bool, error) {
+	resourceAttributes := &authorizationv1.ResourceAttributes{
+		Verb:      "get",
+		Group:     "projectedresource.storage.openshift.io",
+		Resource:  "namespacedshares",
+		Namespace: podNamespace,
+		Name:      shareName,
+	}
+	return executeSAR(resourceAttributes, "namespacedshare", shareName, podNamespace, podName, podSA)
+}
+
+func executeSAR(resourceAttributes *authorizationv1.ResourceAttributes, resource, shareName, podNamespace, podName, podSA string) (bool, error) {
+	err := initClient()
+	if err != nil {
+		return false, err
+	}
+	sarClient := kubeClient.AuthorizationV1().SubjectAccessReviews()
 	sar := &authorizationv1.SubjectAccessReview{
 		Spec: authorizationv1.SubjectAccessReviewSpec{
 			ResourceAttributes: resourceAttributes,
@@ -110,25 +136,37 @@ func ExecuteSAR(shareName, podNamespace, podName, podSA string) (bool, error) {
 	resp, err := sarClient.Create(context.TODO(), sar, metav1.CreateOptions{})
 	if err == nil && resp != nil {
 		if resp.Status.Allowed {
+			metrics.RecordSAR(resource, metrics.SARAllowed)
 			return true, nil
 		}
+		metrics.RecordSAR(resource, metrics.SARDenied)
 		return false, status.Errorf(codes.PermissionDenied,
-			"subjectaccessreviews share %s podNamespace %s podName %s podSA %s returned forbidden",
-			shareName, podNamespace, podName, podSA)
+			"subjectaccessreviews %s %s podNamespace %s podName %s podSA %s returned forbidden",
+			resource, shareName, podNamespace, podName, podSA)
 	}
 
+	metrics.RecordSAR(resource, metrics.SARError)
 	if kerrors.IsForbidden(err) {
 		return false, status.Errorf(codes.PermissionDenied,
-			"subjectaccessreviews share %s podNamespace %s podName %s podSA %s returned forbidden: %s",
-			shareName, podNamespace, podName, podSA, err.Error())
+			"subjectaccessreviews %s %s podNamespace %s podName %s podSA %s returned forbidden: %s",
+			resource, shareName, podNamespace, podName, podSA, err.Error())
 	}
 
 	return false, status.Errorf(codes.Internal,
-		"subjectaccessreviews share %s podNamespace %s podName %s podSA %s returned error: %s",
-		shareName, podNamespace, podName, podSA, err.Error())
+		"subjectaccessreviews %s %s podNamespace %s podName %s podSA %s returned error: %s",
+		resource, shareName, podNamespace, podName, podSA, err.Error())
 }
 
 func GetPod(namespace, name string) (*corev1.Pod, error) {
-	initClient()
+	if err := initClient(); err != nil {
+		return nil, err
+	}
 	return kubeClient.CoreV1().Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
 }
+
+func GetPersistentVolumeClaim(namespace, name string) (*corev1.PersistentVolumeClaim, error) {
+	if err := initClient(); err != nil {
+		return nil, err
+	}
+	return kubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
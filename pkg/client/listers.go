@@ -1,17 +1,29 @@
 package client
 
 import (
+	"sync"
+	"sync/atomic"
+
 	sharev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/generated/listers/projectedresource/v1alpha1"
 	corev1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
 type Listers struct {
-	Secrets    corev1.SecretLister
-	ConfigMaps corev1.ConfigMapLister
-	Shares     sharev1alpha1.ShareLister
+	Secrets          corev1.SecretLister
+	ConfigMaps       corev1.ConfigMapLister
+	Namespaces       corev1.NamespaceLister
+	Shares           sharev1alpha1.ShareLister
+	NamespacedShares sharev1alpha1.NamespacedShareLister
+	// Objects holds the listers of the other kinds backing resources are of, keyed by cache.KindKey
+	Objects sync.Map
 }
 
-var singleton Listers
+var (
+	singleton Listers
+	// synced is set once the informer caches backing the listers have synced
+	synced int32
+)
 
 func init() {
 	singleton = Listers{}
@@ -29,6 +41,39 @@ func SetSharesLister(s sharev1alpha1.ShareLister) {
 	singleton.Shares = s
 }
 
+func SetNamespacesLister(n corev1.NamespaceLister) {
+	singleton.Namespaces = n
+}
+
+func SetNamespacedSharesLister(s sharev1alpha1.NamespacedShareLister) {
+	singleton.NamespacedShares = s
+}
+
+func SetObjectLister(kindKey string, l cache.GenericLister) {
+	singleton.Objects.Store(kindKey, l)
+}
+
+// GetObjectLister returns the lister of a kind other than ConfigMap or Secret, or nil if that kind is not watched
+func (l *Listers) GetObjectLister(kindKey string) cache.GenericLister {
+	lister, _ := l.Objects.Load(kindKey)
+	gl, _ := lister.(cache.GenericLister)
+	return gl
+}
+
+// SetListersSynced records whether the informer caches backing the listers have synced
+func SetListersSynced(s bool) {
+	value := int32(0)
+	if s {
+		value = 1
+	}
+	atomic.StoreInt32(&synced, value)
+}
+
+// ListersSynced returns whether the informer caches backing the listers have synced
+func ListersSynced() bool {
+	return atomic.LoadInt32(&synced) == 1
+}
+
 func GetListers() *Listers {
 	return &singleton
 }
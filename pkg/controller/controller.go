@@ -2,13 +2,17 @@ package controller
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
-	"k8s.io/client-go/informers/internalinterfaces"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
@@ -20,10 +24,14 @@ import (
 	"github.com/openshift/csi-driver-projected-resource/pkg/client"
 	shareclientv1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/generated/clientset/versioned"
 	shareinformer "github.com/openshift/csi-driver-projected-resource/pkg/generated/informers/externalversions"
+	"github.com/openshift/csi-driver-projected-resource/pkg/metrics"
 )
 
 const (
 	DefaultResyncDuration = 10 * time.Minute
+	// InitialSyncTimeout is how long the controller waits, when it starts, for the informers of the backing
+	// resources of the shares that exist to sync before processing events anyway
+	InitialSyncTimeout = time.Minute
 )
 
 type Controller struct {
@@ -32,18 +40,56 @@ type Controller struct {
 	cfgMapWorkqueue workqueue.RateLimitingInterface
 	secretWorkqueue workqueue.RateLimitingInterface
 	shareWorkqueue  workqueue.RateLimitingInterface
-
-	cfgMapInformer cache.SharedIndexInformer
-	secInformer    cache.SharedIndexInformer
-	shareInformer  cache.SharedIndexInformer
-
-	shareInformerFactory shareinformer.SharedInformerFactory
-	informerFactory      informers.SharedInformerFactory
+	objectWorkqueue workqueue.RateLimitingInterface
+	// queuedAt is when each event waiting in a workqueue was first added, for the update latency metric; it is
+	// kept off the events, as the workqueues only collapse the same event added again while it waits if it is equal
+	queuedAtLock sync.Mutex
+	queuedAt     map[client.Event]time.Time
+
+	// backing resources are only watched in the namespaces shares reference them in, with informers started and
+	// stopped as shares come and go; those of ConfigMaps and Secrets keyed by namespace, and those of the other
+	// kinds, watched via the dynamic client, by kind and namespace. Each informer runs while at least one share
+	// references it, counted from the informers each share references by share key
+	informersLock       sync.Mutex
+	namespaceInformers  map[string]*namespaceInformers
+	objectInformers     map[objectInformerKey]*objectInformer
+	shareReferences     map[string]informerReferences
+	namespaceReferences map[string]int
+	objectReferences    map[objectInformerKey]int
+
+	namespaceInformer       cache.SharedIndexInformer
+	shareInformer           cache.SharedIndexInformer
+	namespacedShareInformer cache.SharedIndexInformer
+
+	shareInformerFactory     shareinformer.SharedInformerFactory
+	namespaceInformerFactory informers.SharedInformerFactory
+
+	kubeClient  kubernetes.Interface
+	shareClient shareclientv1alpha1.Interface
+
+	dynamicClient   dynamic.Interface
+	discoveryClient discovery.DiscoveryInterface
+	stopCh          <-chan struct{}
+
+	namespaceConfigPath string
+
+	// the driver runs on every node; the instance holding the status lease, identified by its node, is the only
+	// one writing the status of shares, and statusWriter is set while it does
+	nodeID       string
+	statusWriter int32
 
 	listers *client.Listers
 }
 
-func NewController(shareRelist time.Duration) (*Controller, error) {
+func NewController(shareRelist time.Duration, namespaceConfigPath, nodeID string) (*Controller, error) {
+	namespaceConfig, err := LoadNamespaceConfig(namespaceConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := SetNamespaceConfig(namespaceConfig); err != nil {
+		return nil, err
+	}
+
 	kubeRestConfig, err := client.GetConfig()
 	if err != nil {
 		return nil, err
@@ -59,53 +105,13 @@ func NewController(shareRelist time.Duration) (*Controller, error) {
 		return nil, err
 	}
 
-	// NOTE, not specifying a namespace defaults to metav1.NamespaceAll in
-	// informers.NewSharedInformerFactoryWithOptions, but we restrict OpenShift
-	// "system" namespaces with chatty configmaps like the leaderelection related ones
-	// that are updated every few seconds
-	//TODO make this list externally configurable
-	tweakListOptions := internalinterfaces.TweakListOptionsFunc(func(options *metav1.ListOptions) {
-		fsString := ""
-		namespaceFieldSelector := "metadata.namespace!=%s"
-		namespaces := []string{"kube-system",
-			"openshift-machine-api",
-			"openshift-kube-apiserver",
-			"openshift-kube-apiserver-operator",
-			"openshift-kube-scheduler",
-			"openshift-kube-controller-manager",
-			"openshift-kube-controller-manager-operator",
-			"openshift-kube-scheduler-operator",
-			"openshift-console-operator",
-			"openshift-controller-manager",
-			"openshift-controller-manager-operator",
-			"openshift-cloud-credential-operator",
-			"openshift-authentication-operator",
-			"openshift-service-ca",
-			"openshift-kube-storage-version-migrator-operator",
-			"openshift-config-operator",
-			"openshift-etcd-operator",
-			"openshift-apiserver-operator",
-			"openshift-cluster-csi-drivers",
-			"openshift-cluster-storage-operator",
-			"openshift-cluster-version",
-			"openshift-image-registry",
-			"openshift-machine-config-operator",
-			"openshift-sdn",
-			"openshift-service-ca-operator",
-		}
-		for _, ns := range namespaces {
-			nsfs := fmt.Sprintf(namespaceFieldSelector, ns)
-			if len(fsString) == 0 {
-				fsString = nsfs
-			} else {
-				fsString = fsString + "," + nsfs
-			}
-		}
-		options.FieldSelector = fsString
+	dynamicClient, err := dynamic.NewForConfig(kubeRestConfig)
+	if err != nil {
+		return nil, err
+	}
 
-	})
-	informerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient,
-		DefaultResyncDuration, informers.WithTweakListOptions(tweakListOptions))
+	// namespaces are cluster scoped, so the field selector of the namespace configuration does not apply to them
+	namespaceInformerFactory := informers.NewSharedInformerFactory(kubeClient, DefaultResyncDuration)
 
 	klog.V(5).Infof("configured share relist %v", shareRelist)
 	shareInformerFactory := shareinformer.NewSharedInformerFactoryWithOptions(shareClient,
@@ -119,21 +125,34 @@ func NewController(shareRelist time.Duration) (*Controller, error) {
 			"projected-resource-secret-changes"),
 		shareWorkqueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(),
 			"projected-resource-share-changes"),
-		informerFactory:      informerFactory,
-		shareInformerFactory: shareInformerFactory,
-		cfgMapInformer:       informerFactory.Core().V1().ConfigMaps().Informer(),
-		secInformer:          informerFactory.Core().V1().Secrets().Informer(),
-		shareInformer:        shareInformerFactory.Projectedresource().V1alpha1().Shares().Informer(),
-		listers:              client.GetListers(),
+		objectWorkqueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(),
+			"projected-resource-object-changes"),
+		namespaceInformerFactory: namespaceInformerFactory,
+		shareInformerFactory:     shareInformerFactory,
+		namespaceInformer:        namespaceInformerFactory.Core().V1().Namespaces().Informer(),
+		shareInformer:            shareInformerFactory.Projectedresource().V1alpha1().Shares().Informer(),
+		namespacedShareInformer:  shareInformerFactory.Projectedresource().V1alpha1().NamespacedShares().Informer(),
+		kubeClient:               kubeClient,
+		shareClient:              shareClient,
+		dynamicClient:            dynamicClient,
+		discoveryClient:          kubeClient.Discovery(),
+		namespaceInformers:       map[string]*namespaceInformers{},
+		objectInformers:          map[objectInformerKey]*objectInformer{},
+		namespaceConfigPath:      namespaceConfigPath,
+		nodeID:                   nodeID,
+		listers:                  client.GetListers(),
 	}
 
-	client.SetConfigMapsLister(c.informerFactory.Core().V1().ConfigMaps().Lister())
-	client.SetSecretsLister(c.informerFactory.Core().V1().Secrets().Lister())
+	client.SetConfigMapsLister(configMapLister{c})
+	client.SetSecretsLister(secretLister{c})
+
+	client.SetNamespacesLister(c.namespaceInformerFactory.Core().V1().Namespaces().Lister())
 	client.SetSharesLister(c.shareInformerFactory.Projectedresource().V1alpha1().Shares().Lister())
+	client.SetNamespacedSharesLister(c.shareInformerFactory.Projectedresource().V1alpha1().NamespacedShares().Lister())
 
-	c.cfgMapInformer.AddEventHandler(c.configMapEventHandler())
-	c.secInformer.AddEventHandler(c.secretEventHandler())
+	c.namespaceInformer.AddEventHandler(c.namespaceEventHandler())
 	c.shareInformer.AddEventHandler(c.shareEventHandler())
+	c.namespacedShareInformer.AddEventHandler(c.shareEventHandler())
 
 	return c, nil
 }
@@ -142,29 +161,123 @@ func (c *Controller) Run(stopCh <-chan struct{}) error {
 	defer c.cfgMapWorkqueue.ShutDown()
 	defer c.secretWorkqueue.ShutDown()
 	defer c.shareWorkqueue.ShutDown()
+	defer c.objectWorkqueue.ShutDown()
 
-	c.informerFactory.Start(stopCh)
+	c.stopCh = stopCh
+	c.namespaceInformerFactory.Start(stopCh)
 	c.shareInformerFactory.Start(stopCh)
+	go func() {
+		<-stopCh
+		c.stopInformers()
+	}()
 
-	if !cache.WaitForCacheSync(stopCh, c.cfgMapInformer.HasSynced, c.secInformer.HasSynced, c.shareInformer.HasSynced) {
+	if !cache.WaitForCacheSync(stopCh, c.namespaceInformer.HasSynced, c.shareInformer.HasSynced,
+		c.namespacedShareInformer.HasSynced) {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
+	if err := c.syncBackingResources(stopCh, InitialSyncTimeout); err != nil {
+		return err
+	}
 
 	go wait.Until(c.configMapEventProcessor, time.Second, stopCh)
 	go wait.Until(c.secretEventProcessor, time.Second, stopCh)
 	go wait.Until(c.shareEventProcessor, time.Second, stopCh)
+	go wait.Until(c.objectEventProcessor, time.Second, stopCh)
+
+	go WatchNamespaceConfig(c.namespaceConfigPath, stopCh, c.applyNamespaceConfig)
+	go c.runStatusLeaderElection(stopCh)
 
 	<-stopCh
 
 	return nil
 }
 
+// syncBackingResources starts the informers of the backing resources of the shares that exist and waits up to
+// timeout for them to sync. Those that do not sync in time, say for lack of permissions in their namespace, are left
+// to the shares referencing them, which are requeued until they do; the listers are only reported synced once every
+// informer has, so the volumes are not restored from the objects of some of them only
+func (c *Controller) syncBackingResources(stopCh <-chan struct{}, timeout time.Duration) error {
+	c.syncNamespaceInformers()
+	err := wait.PollImmediate(time.Second, timeout, func() (bool, error) {
+		select {
+		case <-stopCh:
+			return false, fmt.Errorf("stopped")
+		default:
+		}
+		return c.loadInformers(nil), nil
+	})
+	switch {
+	case err == nil:
+		client.SetListersSynced(true)
+	case err == wait.ErrWaitTimeout:
+		klog.Warningf("the informers of some backing resources have not synced after %v, the listers are reported synced once they have", timeout)
+		go func() {
+			if err := wait.PollImmediateUntil(time.Second, func() (bool, error) {
+				return c.loadInformers(nil), nil
+			}, stopCh); err != nil {
+				return
+			}
+			klog.V(0).Infof("the informers of the backing resources have synced")
+			client.SetListersSynced(true)
+			// the namespaces added meanwhile were not checked against the namespace configuration
+			c.syncNamespaceInformers()
+		}()
+	default:
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+	return nil
+}
+
+// queueEvent adds an event to a workqueue, recording when it was added unless it is already waiting
+func (c *Controller) queueEvent(queue workqueue.RateLimitingInterface, event client.Event) {
+	c.queuedAtLock.Lock()
+	if c.queuedAt == nil {
+		c.queuedAt = map[client.Event]time.Time{}
+	}
+	if _, ok := c.queuedAt[event]; !ok {
+		c.queuedAt[event] = time.Now()
+	}
+	c.queuedAtLock.Unlock()
+	queue.Add(event)
+}
+
+// dequeuedEvent returns when an event taken off its workqueue was first added, and forgets it so that the
+// event added again while it is processed is timed from then
+func (c *Controller) dequeuedEvent(event client.Event) time.Time {
+	c.queuedAtLock.Lock()
+	defer c.queuedAtLock.Unlock()
+	queued, ok := c.queuedAt[event]
+	if !ok {
+		return time.Now()
+	}
+	delete(c.queuedAt, event)
+	return queued
+}
+
+// requeueEvent adds an event that failed to be processed back to its workqueue, rate limited, still timed from
+// when it was first added
+func (c *Controller) requeueEvent(queue workqueue.RateLimitingInterface, event client.Event, queued time.Time) {
+	c.queuedAtLock.Lock()
+	if c.queuedAt == nil {
+		c.queuedAt = map[client.Event]time.Time{}
+	}
+	if added, ok := c.queuedAt[event]; !ok || queued.Before(added) {
+		c.queuedAt[event] = queued
+	}
+	c.queuedAtLock.Unlock()
+	queue.AddRateLimited(event)
+}
+
 func (c *Controller) addConfigMapToQueue(cm *corev1.ConfigMap, verb client.ObjectAction) {
+	// deletes are let through, so the objects of a namespace no longer watched are dropped
+	if verb != client.DeleteObjectAction && IsNamespaceExcluded(cm.Namespace) {
+		return
+	}
 	event := client.Event{
 		Object: cm,
 		Verb:   verb,
 	}
-	c.cfgMapWorkqueue.Add(event)
+	c.queueEvent(c.cfgMapWorkqueue, event)
 }
 
 // as the actions we have to take *MAY* vary to a significant enough degree between add, update, and delete,
@@ -222,9 +335,12 @@ func (c *Controller) configMapEventProcessor() {
 				return
 			}
 
+			queued := c.dequeuedEvent(event)
 			if err := c.syncConfigMap(event); err != nil {
-				c.cfgMapWorkqueue.AddRateLimited(obj)
+				c.requeueEvent(c.cfgMapWorkqueue, event, queued)
 			} else {
+				// the data of the object has been written to the volumes it is projected into
+				metrics.ObserveUpdate("ConfigMap", queued)
 				c.cfgMapWorkqueue.Forget(obj)
 			}
 		}()
@@ -243,26 +359,30 @@ func (c *Controller) syncConfigMap(event client.Event) error {
 	switch event.Verb {
 	case client.DeleteObjectAction:
 		objcache.DelConfigMap(cm)
+		return c.syncStatusOfSharesFor("ConfigMap", cm.Namespace, cm.Name)
 	case client.AddObjectAction:
 		// again, add vs. update distinctions upheld for now, even though the path is common, in case
 		// host filesystem interactions changes such that different methods for add vs. update are needed
 		objcache.UpsertConfigMap(cm)
+		return c.syncStatusOfSharesFor("ConfigMap", cm.Namespace, cm.Name)
 	case client.UpdateObjectAction:
-		// again, add vs. update distinctions upheld for now, even though the path is common, in case
-		// host filesystem interactions changes such that different methods for add vs. update are needed
-		objcache.UpsertConfigMap(cm)
+		// unlike an add, an update only changes the status of the shares referencing the object when it
+		// changes its labels or whether shares reference it
+		return c.upsertUpdatedObject("ConfigMap", cm)
 	default:
 		return fmt.Errorf("unexpected configmap event action: %s", event.Verb)
 	}
-	return nil
 }
 
 func (c *Controller) addSecretToQueue(s *corev1.Secret, verb client.ObjectAction) {
+	if verb != client.DeleteObjectAction && IsNamespaceExcluded(s.Namespace) {
+		return
+	}
 	event := client.Event{
 		Object: s,
 		Verb:   verb,
 	}
-	c.secretWorkqueue.Add(event)
+	c.queueEvent(c.secretWorkqueue, event)
 }
 
 // as the actions we have to take vary to a significant enough degree between add, update, and delete
@@ -320,9 +440,12 @@ func (c *Controller) secretEventProcessor() {
 				return
 			}
 
+			queued := c.dequeuedEvent(event)
 			if err := c.syncSecret(event); err != nil {
-				c.secretWorkqueue.AddRateLimited(obj)
+				c.requeueEvent(c.secretWorkqueue, event, queued)
 			} else {
+				// the data of the object has been written to the volumes it is projected into
+				metrics.ObserveUpdate("Secret", queued)
 				c.secretWorkqueue.Forget(obj)
 			}
 		}()
@@ -339,33 +462,47 @@ func (c *Controller) syncSecret(event client.Event) error {
 	switch event.Verb {
 	case client.DeleteObjectAction:
 		objcache.DelSecret(secret)
+		return c.syncStatusOfSharesFor("Secret", secret.Namespace, secret.Name)
 	case client.AddObjectAction:
 		// again, add vs. update distinctions upheld for now, even though the path is common, in case
 		// host filesystem interactions changes such that different methods for add vs. update are needed
 		objcache.UpsertSecret(secret)
+		return c.syncStatusOfSharesFor("Secret", secret.Namespace, secret.Name)
 	case client.UpdateObjectAction:
-		// again, add vs. update distinctions upheld for now, even though the path is common, in case
-		// host filesystem interactions changes such that different methods for add vs. update are needed
-		objcache.UpsertSecret(secret)
+		// unlike an add, an update only changes the status of the shares referencing the object when it
+		// changes its labels or whether shares reference it
+		return c.upsertUpdatedObject("Secret", secret)
 	default:
 		return fmt.Errorf("unexpected secret event action: %s", event.Verb)
 	}
-	return nil
 }
 
-func (c *Controller) addShareToQueue(s *sharev1alpha1.Share, verb client.ObjectAction) {
+// queueShares queues every share and namespaced share to be synced again
+func (c *Controller) queueShares() {
+	shares, _ := c.listers.Shares.List(labels.Everything())
+	for _, share := range shares {
+		c.addShareToQueue(share, client.UpdateObjectAction)
+	}
+	namespacedShares, _ := c.listers.NamespacedShares.List(labels.Everything())
+	for _, share := range namespacedShares {
+		c.addShareToQueue(share, client.UpdateObjectAction)
+	}
+}
+
+func (c *Controller) addShareToQueue(s sharev1alpha1.ShareObject, verb client.ObjectAction) {
 	event := client.Event{
 		Object: s,
 		Verb:   verb,
 	}
-	c.shareWorkqueue.Add(event)
+	c.queueEvent(c.shareWorkqueue, event)
 }
 
+// shareEventHandler handles both Shares and NamespacedShares, which are processed by the same workqueue
 func (c *Controller) shareEventHandler() cache.ResourceEventHandlerFuncs {
 	return cache.ResourceEventHandlerFuncs{
 		AddFunc: func(o interface{}) {
 			switch v := o.(type) {
-			case *sharev1alpha1.Share:
+			case sharev1alpha1.ShareObject:
 				c.addShareToQueue(v, client.AddObjectAction)
 			default:
 				//log unrecognized type
@@ -373,7 +510,12 @@ func (c *Controller) shareEventHandler() cache.ResourceEventHandlerFuncs {
 		},
 		UpdateFunc: func(o, n interface{}) {
 			switch v := n.(type) {
-			case *sharev1alpha1.Share:
+			case sharev1alpha1.ShareObject:
+				// changes to the status subresource do not bump the generation; those are the result of
+				// syncShareStatus on this or another node, so there is nothing to reconcile
+				if old, ok := o.(sharev1alpha1.ShareObject); ok && isStatusOnlyChange(old, v) {
+					return
+				}
 				c.addShareToQueue(v, client.UpdateObjectAction)
 			default:
 				//log unrecognized type
@@ -383,13 +525,13 @@ func (c *Controller) shareEventHandler() cache.ResourceEventHandlerFuncs {
 			switch v := o.(type) {
 			case cache.DeletedFinalStateUnknown:
 				switch vv := v.Obj.(type) {
-				case *sharev1alpha1.Share:
+				case sharev1alpha1.ShareObject:
 					// log recovered deleted obj from tombstone via vv.GetName()
 					c.addShareToQueue(vv, client.DeleteObjectAction)
 				default:
 					// log  error decoding obj tombstone
 				}
-			case *sharev1alpha1.Share:
+			case sharev1alpha1.ShareObject:
 				c.addShareToQueue(v, client.DeleteObjectAction)
 			default:
 				//log unrecognized type
@@ -414,33 +556,174 @@ func (c *Controller) shareEventProcessor() {
 				return
 			}
 
+			queued := c.dequeuedEvent(event)
 			if err := c.syncShare(event); err != nil {
-				c.shareWorkqueue.AddRateLimited(obj)
+				c.requeueEvent(c.shareWorkqueue, event, queued)
 			} else {
+				// the data of the share has been written to the volumes using it
+				metrics.ObserveUpdate("Share", queued)
 				c.shareWorkqueue.Forget(obj)
 			}
 		}()
 	}
 }
 
+func isStatusOnlyChange(old, new sharev1alpha1.ShareObject) bool {
+	return old.GetGeneration() == new.GetGeneration() &&
+		old.GetResourceVersion() != new.GetResourceVersion() &&
+		!equality.Semantic.DeepEqual(old.GetStatus(), new.GetStatus())
+}
+
 func (c *Controller) syncShare(event client.Event) error {
-	// copy in case we start updating conditions
+	// copy since the objcache retains what we pass it
 	obj := event.Object.DeepCopyObject()
-	share, ok := obj.(*sharev1alpha1.Share)
-	if share == nil || !ok {
-		return fmt.Errorf("unexpected object vs. share: %v", event.Object.GetObjectKind().GroupVersionKind())
+	switch share := obj.(type) {
+	case *sharev1alpha1.Share:
+		klog.V(5).Infof("verb %s share name %s", event.Verb, share.Name)
+		switch event.Verb {
+		case client.DeleteObjectAction:
+			objcache.DelShare(share)
+			c.unwatchShare(share)
+			return nil
+		case client.AddObjectAction:
+			if err := c.watchBackingResources(share); err != nil {
+				klog.V(4).Infof("requeueing share: %s", err.Error())
+				return err
+			}
+			objcache.AddShare(share)
+		case client.UpdateObjectAction:
+			if err := c.watchBackingResources(share); err != nil {
+				klog.V(4).Infof("requeueing share: %s", err.Error())
+				return err
+			}
+			objcache.UpdateShare(share)
+		default:
+			return fmt.Errorf("unexpected share event action: %s", event.Verb)
+		}
+		return c.syncShareStatus(share.Name)
+	case *sharev1alpha1.NamespacedShare:
+		klog.V(5).Infof("verb %s namespaced share namespace %s name %s", event.Verb, share.Namespace, share.Name)
+		switch event.Verb {
+		case client.DeleteObjectAction:
+			objcache.DelNamespacedShare(share)
+			c.unwatchShare(share)
+			return nil
+		case client.AddObjectAction:
+			if err := c.watchBackingResources(share); err != nil {
+				klog.V(4).Infof("requeueing share: %s", err.Error())
+				return err
+			}
+			objcache.AddNamespacedShare(share)
+		case client.UpdateObjectAction:
+			if err := c.watchBackingResources(share); err != nil {
+				klog.V(4).Infof("requeueing share: %s", err.Error())
+				return err
+			}
+			objcache.UpdateNamespacedShare(share)
+		default:
+			return fmt.Errorf("unexpected namespaced share event action: %s", event.Verb)
+		}
+		return c.syncNamespacedShareStatus(share.Namespace, share.Name)
+	}
+	return fmt.Errorf("unexpected object vs. share: %v", event.Object.GetObjectKind().GroupVersionKind())
+}
+
+func (c *Controller) addObjectToQueue(o *unstructured.Unstructured, verb client.ObjectAction) {
+	if verb != client.DeleteObjectAction && IsNamespaceExcluded(o.GetNamespace()) {
+		return
+	}
+	event := client.Event{
+		Object: o,
+		Verb:   verb,
 	}
-	klog.V(5).Infof("verb %s share name %s", event.Verb, share.Name)
+	c.queueEvent(c.objectWorkqueue, event)
+}
+
+// objectEventHandler handles the objects of every kind watched via the dynamic client, which are processed
+// by the same workqueue
+func (c *Controller) objectEventHandler() cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(o interface{}) {
+			switch v := o.(type) {
+			case *unstructured.Unstructured:
+				c.addObjectToQueue(v, client.AddObjectAction)
+			default:
+				//log unrecognized type
+			}
+		},
+		UpdateFunc: func(o, n interface{}) {
+			switch v := n.(type) {
+			case *unstructured.Unstructured:
+				c.addObjectToQueue(v, client.UpdateObjectAction)
+			default:
+				//log unrecognized type
+			}
+		},
+		DeleteFunc: func(o interface{}) {
+			switch v := o.(type) {
+			case cache.DeletedFinalStateUnknown:
+				switch vv := v.Obj.(type) {
+				case *unstructured.Unstructured:
+					// log recovered deleted obj from tombstone via vv.GetName()
+					c.addObjectToQueue(vv, client.DeleteObjectAction)
+				default:
+					// log  error decoding obj tombstone
+				}
+			case *unstructured.Unstructured:
+				c.addObjectToQueue(v, client.DeleteObjectAction)
+			default:
+				//log unrecognized type
+			}
+		},
+	}
+}
+
+func (c *Controller) objectEventProcessor() {
+	for {
+		obj, shutdown := c.objectWorkqueue.Get()
+		if shutdown {
+			return
+		}
+
+		func() {
+			defer c.objectWorkqueue.Done(obj)
+
+			event, ok := obj.(client.Event)
+			if !ok {
+				c.objectWorkqueue.Forget(obj)
+				return
+			}
+
+			queued := c.dequeuedEvent(event)
+			if err := c.syncObject(event); err != nil {
+				c.requeueEvent(c.objectWorkqueue, event, queued)
+			} else {
+				// the data of the object has been written to the volumes it is projected into
+				metrics.ObserveUpdate(event.Object.GetObjectKind().GroupVersionKind().Kind, queued)
+				c.objectWorkqueue.Forget(obj)
+			}
+		}()
+	}
+}
+
+func (c *Controller) syncObject(event client.Event) error {
+	obj, ok := event.Object.(*unstructured.Unstructured)
+	if obj == nil || !ok {
+		return fmt.Errorf("unexpected object vs. unstructured: %v", event.Object.GetObjectKind().GroupVersionKind())
+	}
+	kindKey := objcache.KindKey(obj.GetAPIVersion(), obj.GetKind())
+	// since we don't mutate we do not copy
+	klog.V(5).Infof("verb %s obj namespace %s %s name %s", event.Verb, obj.GetNamespace(), kindKey, obj.GetName())
 	switch event.Verb {
 	case client.DeleteObjectAction:
-		objcache.DelShare(share)
+		objcache.DelObject(kindKey, obj)
+		return c.syncStatusOfSharesFor(kindKey, obj.GetNamespace(), obj.GetName())
 	case client.AddObjectAction:
-		objcache.AddShare(share)
+		objcache.UpsertObject(kindKey, obj)
+		return c.syncStatusOfSharesFor(kindKey, obj.GetNamespace(), obj.GetName())
 	case client.UpdateObjectAction:
-		objcache.UpdateShare(share)
+		return c.upsertUpdatedObject(kindKey, obj)
 	default:
-		return fmt.Errorf("unexpected share event action: %s", event.Verb)
+		return fmt.Errorf("unexpected %s event action: %s", kindKey, event.Verb)
 	}
-
-	return nil
 }
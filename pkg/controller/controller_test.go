@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+	fakekubetesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/openshift/csi-driver-projected-resource/pkg/client"
+)
+
+func TestQueueEvent(t *testing.T) {
+	c := &Controller{cfgMapWorkqueue: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())}
+	defer c.cfgMapWorkqueue.ShutDown()
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "namespace1", Name: "configmap1"}}
+
+	// the same event added again while it waits is collapsed, and timed from when it was first added
+	c.addConfigMapToQueue(cm, client.UpdateObjectAction)
+	first := c.queuedAt[client.Event{Object: cm, Verb: client.UpdateObjectAction}]
+	time.Sleep(time.Millisecond)
+	c.addConfigMapToQueue(cm, client.UpdateObjectAction)
+	if c.cfgMapWorkqueue.Len() != 1 {
+		t.Fatalf("expected the event to be queued once got %d", c.cfgMapWorkqueue.Len())
+	}
+	obj, _ := c.cfgMapWorkqueue.Get()
+	event := obj.(client.Event)
+	if queued := c.dequeuedEvent(event); !queued.Equal(first) {
+		t.Fatalf("expected the event timed from %v got %v", first, queued)
+	}
+
+	// an event failing to be processed keeps the time it was first added
+	c.requeueEvent(c.cfgMapWorkqueue, event, first)
+	c.cfgMapWorkqueue.Done(obj)
+	if queued := c.queuedAt[event]; !queued.Equal(first) {
+		t.Fatalf("expected the requeued event timed from %v got %v", first, queued)
+	}
+}
+
+func TestSyncBackingResources(t *testing.T) {
+	share := testShare("ConfigMap", "namespace1", "cm1")
+	for _, test := range []struct {
+		name        string
+		timeout     time.Duration
+		listFailing bool
+	}{
+		{name: "informers synced in time", timeout: wait.ForeverTestTimeout},
+		{name: "informers not synced in time", timeout: 100 * time.Millisecond, listFailing: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			defer client.SetListersSynced(false)
+			client.SetListersSynced(false)
+			listFailing := int32(0)
+			if test.listFailing {
+				listFailing = 1
+			}
+			kubeClient := fakekubeclientset.NewSimpleClientset()
+			kubeClient.PrependReactor("list", "configmaps", func(action fakekubetesting.Action) (bool, runtime.Object, error) {
+				if atomic.LoadInt32(&listFailing) == 1 {
+					return true, nil, fmt.Errorf("forbidden")
+				}
+				return false, nil, nil
+			})
+			c := &Controller{
+				kubeClient:         kubeClient,
+				namespaceInformers: map[string]*namespaceInformers{},
+				objectInformers:    map[objectInformerKey]*objectInformer{},
+				listers:            testListers(share),
+				stopCh:             stopCh,
+			}
+
+			if err := c.syncBackingResources(stopCh, test.timeout); err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			if client.ListersSynced() == test.listFailing {
+				t.Fatalf("expected the listers synced %v", !test.listFailing)
+			}
+			if !test.listFailing {
+				return
+			}
+
+			// the listers are reported synced once the informers sync after all
+			atomic.StoreInt32(&listFailing, 0)
+			if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+				return client.ListersSynced(), nil
+			}); err != nil {
+				t.Fatalf("expected the listers to be reported synced once the informers are")
+			}
+			if !c.backingResourcesLoaded(share) {
+				t.Fatalf("expected the backing resources of the share to be loaded")
+			}
+		})
+	}
+}
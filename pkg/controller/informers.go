@@ -0,0 +1,588 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	sharev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+	objcache "github.com/openshift/csi-driver-projected-resource/pkg/cache"
+	"github.com/openshift/csi-driver-projected-resource/pkg/client"
+	"github.com/openshift/csi-driver-projected-resource/pkg/validation"
+)
+
+// namespaceInformers watch the ConfigMaps and Secrets of one namespace
+type namespaceInformers struct {
+	factory        informers.SharedInformerFactory
+	cfgMapInformer cache.SharedIndexInformer
+	secInformer    cache.SharedIndexInformer
+	stopCh         chan struct{}
+	// loaded is set once the objects listed have been added to the objcache
+	loaded bool
+}
+
+func (c *Controller) newNamespaceInformers(namespace string) *namespaceInformers {
+	factory := informers.NewSharedInformerFactoryWithOptions(c.kubeClient, DefaultResyncDuration,
+		informers.WithNamespace(namespace))
+	ni := &namespaceInformers{
+		factory:        factory,
+		cfgMapInformer: factory.Core().V1().ConfigMaps().Informer(),
+		secInformer:    factory.Core().V1().Secrets().Informer(),
+		stopCh:         make(chan struct{}),
+	}
+	ni.cfgMapInformer.AddEventHandler(c.configMapEventHandler())
+	ni.secInformer.AddEventHandler(c.secretEventHandler())
+	return ni
+}
+
+// objectInformerKey identifies the informer of the objects of a kind other than ConfigMap and Secret in a namespace
+type objectInformerKey struct {
+	kindKey   string
+	namespace string
+}
+
+// objectInformer watches the objects of a kind other than ConfigMap and Secret in one namespace, via the dynamic
+// client
+type objectInformer struct {
+	informer cache.SharedIndexInformer
+	stopCh   chan struct{}
+	// loaded is set once the objects listed have been added to the objcache
+	loaded bool
+}
+
+func (c *Controller) newObjectInformer(mapping *meta.RESTMapping, namespace string) *objectInformer {
+	resource := c.dynamicClient.Resource(mapping.Resource).Namespace(namespace)
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return resource.List(context.TODO(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return resource.Watch(context.TODO(), options)
+		},
+	}
+	oi := &objectInformer{
+		informer: cache.NewSharedIndexInformer(lw, &unstructured.Unstructured{}, DefaultResyncDuration,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}),
+		stopCh: make(chan struct{}),
+	}
+	oi.informer.AddEventHandler(c.objectEventHandler())
+	return oi
+}
+
+// informerReferences are the namespaces a share references ConfigMaps or Secrets in, and the backing resources of
+// the other kinds it references, one for each kind and namespace
+type informerReferences struct {
+	namespaces sets.String
+	objects    map[objectInformerKey]sharev1alpha1.BackingResource
+}
+
+// shareInformerReferences returns the informers the backing resources of a share need: those of the backing
+// resources that can be projected, being valid, in the namespaces watched, and for NamespacedShares, that they may
+// use
+func (c *Controller) shareInformerReferences(share sharev1alpha1.ShareObject) informerReferences {
+	refs := informerReferences{namespaces: sets.NewString(), objects: map[objectInformerKey]sharev1alpha1.BackingResource{}}
+	for _, br := range share.GetSpec().GetBackingResources() {
+		kind, namespace := strings.TrimSpace(br.Kind), strings.TrimSpace(br.Namespace)
+		if validation.ValidateBackingResourceKind(br) != nil || len(namespace) == 0 || IsNamespaceExcluded(namespace) {
+			continue
+		}
+		// the backing resources a namespaced share may not use are never projected
+		if len(share.GetNamespace()) > 0 &&
+			validation.ValidateNamespacedBackingResource(share.GetNamespace(), br, c.listers.Namespaces) != nil {
+			continue
+		}
+		if kind == "ConfigMap" || kind == "Secret" {
+			refs.namespaces.Insert(namespace)
+			continue
+		}
+		refs.objects[objectInformerKey{kindKey: objcache.KindKey(br.APIVersion, kind), namespace: namespace}] = br
+	}
+	return refs
+}
+
+// referencedShares returns the informers the backing resources of each share listed need, by share key
+func (c *Controller) referencedShares() map[string]informerReferences {
+	shares := []sharev1alpha1.ShareObject{}
+	clusterShares, _ := c.listers.Shares.List(labels.Everything())
+	for _, share := range clusterShares {
+		shares = append(shares, share)
+	}
+	namespacedShares, _ := c.listers.NamespacedShares.List(labels.Everything())
+	for _, share := range namespacedShares {
+		shares = append(shares, share)
+	}
+	referenced := map[string]informerReferences{}
+	for _, share := range shares {
+		referenced[objcache.GetShareKey(share)] = c.shareInformerReferences(share)
+	}
+	return referenced
+}
+
+// setShareReferences replaces the informers a share references, counting the shares that reference each, and
+// returns those whose count changed; the lock of the informers has to be held
+func (c *Controller) setShareReferences(shareKey string, refs informerReferences) informerReferences {
+	if c.shareReferences == nil {
+		c.shareReferences = map[string]informerReferences{}
+		c.namespaceReferences = map[string]int{}
+		c.objectReferences = map[objectInformerKey]int{}
+	}
+	touched := informerReferences{namespaces: sets.NewString(), objects: map[objectInformerKey]sharev1alpha1.BackingResource{}}
+	old, ok := c.shareReferences[shareKey]
+	if ok {
+		for namespace := range old.namespaces.Difference(refs.namespaces) {
+			c.namespaceReferences[namespace]--
+			if c.namespaceReferences[namespace] <= 0 {
+				delete(c.namespaceReferences, namespace)
+			}
+			touched.namespaces.Insert(namespace)
+		}
+		for key, br := range old.objects {
+			if _, ok := refs.objects[key]; ok {
+				continue
+			}
+			c.objectReferences[key]--
+			if c.objectReferences[key] <= 0 {
+				delete(c.objectReferences, key)
+			}
+			touched.objects[key] = br
+		}
+	}
+	for namespace := range refs.namespaces {
+		if ok && old.namespaces.Has(namespace) {
+			continue
+		}
+		c.namespaceReferences[namespace]++
+		touched.namespaces.Insert(namespace)
+	}
+	for key, br := range refs.objects {
+		if _, found := old.objects[key]; ok && found {
+			continue
+		}
+		c.objectReferences[key]++
+		touched.objects[key] = br
+	}
+	if len(refs.namespaces) == 0 && len(refs.objects) == 0 {
+		delete(c.shareReferences, shareKey)
+	} else {
+		c.shareReferences[shareKey] = refs
+	}
+	return touched
+}
+
+// reconcileInformers starts the informers given that shares reference and that are not running, and stops those
+// that no share references any more, returning the ones stopped; the lock of the informers has to be held
+func (c *Controller) reconcileInformers(refs informerReferences) ([]*namespaceInformers, []*objectInformer) {
+	stopped := []*namespaceInformers{}
+	stoppedObjects := []*objectInformer{}
+	for _, namespace := range refs.namespaces.List() {
+		ni, running := c.namespaceInformers[namespace]
+		switch {
+		case c.namespaceReferences[namespace] > 0 && !running:
+			ni = c.newNamespaceInformers(namespace)
+			c.namespaceInformers[namespace] = ni
+			ni.factory.Start(ni.stopCh)
+			klog.V(2).Infof("watching the ConfigMaps and Secrets of namespace %s", namespace)
+		case c.namespaceReferences[namespace] == 0 && running:
+			close(ni.stopCh)
+			delete(c.namespaceInformers, namespace)
+			stopped = append(stopped, ni)
+			klog.V(2).Infof("no longer watching the ConfigMaps and Secrets of namespace %s", namespace)
+		}
+	}
+	var mapper meta.RESTMapper
+	for key, br := range refs.objects {
+		oi, running := c.objectInformers[key]
+		switch {
+		case c.objectReferences[key] > 0 && !running:
+			mapping, err := c.restMapping(&mapper, br)
+			if err != nil {
+				// surfaces through the status of the shares as the kind not being watched
+				klog.Warningf("unable to watch backing resource kind %s in namespace %s: %s", key.kindKey, key.namespace, err.Error())
+				continue
+			}
+			oi = c.newObjectInformer(mapping, key.namespace)
+			c.objectInformers[key] = oi
+			client.SetObjectLister(key.kindKey, objectLister{c: c, kindKey: key.kindKey, resource: mapping.Resource.GroupResource()})
+			go oi.informer.Run(oi.stopCh)
+			klog.V(2).Infof("watching backing resource kind %s in namespace %s", key.kindKey, key.namespace)
+		case c.objectReferences[key] == 0 && running:
+			close(oi.stopCh)
+			delete(c.objectInformers, key)
+			stoppedObjects = append(stoppedObjects, oi)
+			klog.V(2).Infof("no longer watching backing resource kind %s in namespace %s", key.kindKey, key.namespace)
+		}
+	}
+	return stopped, stoppedObjects
+}
+
+// dropStoppedInformers queues the objects of the informers stopped for deletion, so they are dropped from the
+// objcache and the volumes they are projected into
+func (c *Controller) dropStoppedInformers(stopped []*namespaceInformers, stoppedObjects []*objectInformer) {
+	for _, ni := range stopped {
+		for _, obj := range append(ni.cfgMapInformer.GetStore().List(), ni.secInformer.GetStore().List()...) {
+			c.queueObject(obj, client.DeleteObjectAction)
+		}
+	}
+	for _, oi := range stoppedObjects {
+		for _, obj := range oi.informer.GetStore().List() {
+			c.queueObject(obj, client.DeleteObjectAction)
+		}
+	}
+}
+
+// loadInformers adds the objects of the informers given, or of every informer if refs is nil, that have synced
+// since they were started to the objcache, so that the shares referencing them find them, and returns true once
+// every one of them has
+func (c *Controller) loadInformers(refs *informerReferences) bool {
+	synced := true
+	loading := []*namespaceInformers{}
+	loadingObjects := map[objectInformerKey]*objectInformer{}
+	c.informersLock.Lock()
+	load := func(ni *namespaceInformers) {
+		switch {
+		case ni.loaded:
+		case ni.cfgMapInformer.HasSynced() && ni.secInformer.HasSynced():
+			loading = append(loading, ni)
+		default:
+			synced = false
+		}
+	}
+	loadObject := func(key objectInformerKey, oi *objectInformer) {
+		switch {
+		case oi.loaded:
+		case oi.informer.HasSynced():
+			loadingObjects[key] = oi
+		default:
+			synced = false
+		}
+	}
+	if refs == nil {
+		for _, ni := range c.namespaceInformers {
+			load(ni)
+		}
+		for key, oi := range c.objectInformers {
+			loadObject(key, oi)
+		}
+	} else {
+		for namespace := range refs.namespaces {
+			if ni, ok := c.namespaceInformers[namespace]; ok {
+				load(ni)
+			}
+		}
+		for key := range refs.objects {
+			if oi, ok := c.objectInformers[key]; ok {
+				loadObject(key, oi)
+			}
+		}
+	}
+	c.informersLock.Unlock()
+
+	// the add events of the objects listed are still processed, which then finds them unchanged
+	for _, ni := range loading {
+		for _, obj := range ni.cfgMapInformer.GetStore().List() {
+			objcache.UpsertConfigMap(obj.(*corev1.ConfigMap))
+		}
+		for _, obj := range ni.secInformer.GetStore().List() {
+			objcache.UpsertSecret(obj.(*corev1.Secret))
+		}
+	}
+	for key, oi := range loadingObjects {
+		for _, obj := range oi.informer.GetStore().List() {
+			objcache.UpsertObject(key.kindKey, obj.(*unstructured.Unstructured))
+		}
+	}
+	if len(loading) == 0 && len(loadingObjects) == 0 {
+		return synced
+	}
+	c.informersLock.Lock()
+	defer c.informersLock.Unlock()
+	for _, ni := range loading {
+		ni.loaded = true
+	}
+	for _, oi := range loadingObjects {
+		oi.loaded = true
+	}
+	return synced
+}
+
+// syncNamespaceInformers works out again the informers every share listed references, starting those now
+// referenced and stopping those no longer referenced, dropping their objects: the ConfigMaps and Secrets of each
+// namespace, and the objects of the other kinds by kind and namespace. It is called when the namespaces watched
+// change, which can change the informers of any share; a share added, updated or deleted only syncs its own. It
+// does not wait for the informers it starts to sync, and returns true once every informer has
+func (c *Controller) syncNamespaceInformers() bool {
+	referenced := c.referencedShares()
+
+	c.informersLock.Lock()
+	select {
+	case <-c.stopCh:
+		c.informersLock.Unlock()
+		return false
+	default:
+	}
+	touched := informerReferences{namespaces: sets.NewString(), objects: map[objectInformerKey]sharev1alpha1.BackingResource{}}
+	for namespace := range c.namespaceInformers {
+		touched.namespaces.Insert(namespace)
+	}
+	for key := range c.objectInformers {
+		// stopping an informer needs no backing resource
+		touched.objects[key] = sharev1alpha1.BackingResource{}
+	}
+	c.shareReferences = map[string]informerReferences{}
+	c.namespaceReferences = map[string]int{}
+	c.objectReferences = map[objectInformerKey]int{}
+	for shareKey, refs := range referenced {
+		for namespace := range refs.namespaces {
+			touched.namespaces.Insert(namespace)
+		}
+		for key, br := range refs.objects {
+			touched.objects[key] = br
+		}
+		c.setShareReferences(shareKey, refs)
+	}
+	stopped, stoppedObjects := c.reconcileInformers(touched)
+	c.informersLock.Unlock()
+
+	c.dropStoppedInformers(stopped, stoppedObjects)
+	return c.loadInformers(nil)
+}
+
+// watchShare starts the informers the backing resources of a share now reference, and stops those it referenced
+// that no other share does, leaving the informers of the other shares alone
+func (c *Controller) watchShare(share sharev1alpha1.ShareObject) {
+	refs := c.shareInformerReferences(share)
+	c.syncShareReferences(objcache.GetShareKey(share), refs)
+	c.loadInformers(&refs)
+}
+
+// unwatchShare stops the informers the backing resources of a share deleted referenced that no other share does
+func (c *Controller) unwatchShare(share sharev1alpha1.ShareObject) {
+	c.syncShareReferences(objcache.GetShareKey(share), informerReferences{namespaces: sets.NewString()})
+}
+
+// syncShareReferences replaces the informers a share references, and starts or stops those whose count changed
+func (c *Controller) syncShareReferences(shareKey string, refs informerReferences) {
+	c.informersLock.Lock()
+	select {
+	case <-c.stopCh:
+		c.informersLock.Unlock()
+		return
+	default:
+	}
+	stopped, stoppedObjects := c.reconcileInformers(c.setShareReferences(shareKey, refs))
+	c.informersLock.Unlock()
+
+	c.dropStoppedInformers(stopped, stoppedObjects)
+}
+
+// backingResourcesLoaded returns true if the objects of the informers watching the backing resources of a share
+// are in the objcache; the backing resources that are not watched, being invalid or left out, have nothing to wait
+// for
+func (c *Controller) backingResourcesLoaded(share sharev1alpha1.ShareObject) bool {
+	c.informersLock.Lock()
+	defer c.informersLock.Unlock()
+	for _, br := range share.GetSpec().GetBackingResources() {
+		kind, namespace := strings.TrimSpace(br.Kind), strings.TrimSpace(br.Namespace)
+		if kind == "ConfigMap" || kind == "Secret" {
+			if ni, ok := c.namespaceInformers[namespace]; ok && !ni.loaded {
+				return false
+			}
+			continue
+		}
+		key := objectInformerKey{kindKey: objcache.KindKey(br.APIVersion, kind), namespace: namespace}
+		if oi, ok := c.objectInformers[key]; ok && !oi.loaded {
+			return false
+		}
+	}
+	return true
+}
+
+// watchBackingResources starts watching the backing resources of a share, and returns an error until their objects
+// are listed, so that the share is requeued rather than the worker waiting on the informers
+func (c *Controller) watchBackingResources(share sharev1alpha1.ShareObject) error {
+	c.watchShare(share)
+	if !c.backingResourcesLoaded(share) {
+		return fmt.Errorf("the backing resources of share %s are not listed yet", objcache.GetShareKey(share))
+	}
+	return nil
+}
+
+// restMapping returns the mapping of the kind of a backing resource to the resource the dynamic client lists,
+// discovering the kinds the apiserver serves the first time it is called with mapper unset
+func (c *Controller) restMapping(mapper *meta.RESTMapper, br sharev1alpha1.BackingResource) (*meta.RESTMapping, error) {
+	if *mapper == nil {
+		groupResources, err := restmapper.GetAPIGroupResources(c.discoveryClient)
+		if err != nil {
+			return nil, err
+		}
+		*mapper = restmapper.NewDiscoveryRESTMapper(groupResources)
+	}
+	gvk := schema.FromAPIVersionAndKind(strings.TrimSpace(br.APIVersion), strings.TrimSpace(br.Kind))
+	mapping, err := (*mapper).RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return nil, fmt.Errorf("kind %s is not namespaced", gvk.String())
+	}
+	return mapping, nil
+}
+
+// stopInformers stops the informers of the backing resources once the controller is stopped
+func (c *Controller) stopInformers() {
+	c.informersLock.Lock()
+	defer c.informersLock.Unlock()
+	for _, ni := range c.namespaceInformers {
+		close(ni.stopCh)
+	}
+	c.namespaceInformers = map[string]*namespaceInformers{}
+	for _, oi := range c.objectInformers {
+		close(oi.stopCh)
+	}
+	c.objectInformers = map[objectInformerKey]*objectInformer{}
+}
+
+// watchedNamespace returns the informers of a namespace, or nil if its ConfigMaps and Secrets are not watched
+func (c *Controller) watchedNamespace(namespace string) *namespaceInformers {
+	c.informersLock.Lock()
+	defer c.informersLock.Unlock()
+	return c.namespaceInformers[namespace]
+}
+
+// watchedNamespaces returns the informers of the namespaces whose ConfigMaps and Secrets are watched
+func (c *Controller) watchedNamespaces() []*namespaceInformers {
+	c.informersLock.Lock()
+	defer c.informersLock.Unlock()
+	watched := []*namespaceInformers{}
+	for _, ni := range c.namespaceInformers {
+		watched = append(watched, ni)
+	}
+	return watched
+}
+
+// watchedObject returns the informer of a kind in a namespace, or nil if its objects there are not watched
+func (c *Controller) watchedObject(kindKey, namespace string) *objectInformer {
+	c.informersLock.Lock()
+	defer c.informersLock.Unlock()
+	return c.objectInformers[objectInformerKey{kindKey: kindKey, namespace: namespace}]
+}
+
+// watchedObjects returns the informers of the namespaces the objects of a kind are watched in
+func (c *Controller) watchedObjects(kindKey string) []*objectInformer {
+	c.informersLock.Lock()
+	defer c.informersLock.Unlock()
+	watched := []*objectInformer{}
+	for key, oi := range c.objectInformers {
+		if key.kindKey == kindKey {
+			watched = append(watched, oi)
+		}
+	}
+	return watched
+}
+
+// emptyIndexer backs the listers of the namespaces that are not watched
+func emptyIndexer() cache.Indexer {
+	return cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+}
+
+// configMapLister lists the ConfigMaps of the namespaces watched, through the informer of each
+type configMapLister struct {
+	c *Controller
+}
+
+func (l configMapLister) List(selector labels.Selector) ([]*corev1.ConfigMap, error) {
+	ret := []*corev1.ConfigMap{}
+	for _, ni := range l.c.watchedNamespaces() {
+		cms, err := corelisters.NewConfigMapLister(ni.cfgMapInformer.GetIndexer()).List(selector)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, cms...)
+	}
+	return ret, nil
+}
+
+func (l configMapLister) ConfigMaps(namespace string) corelisters.ConfigMapNamespaceLister {
+	indexer := emptyIndexer()
+	if ni := l.c.watchedNamespace(namespace); ni != nil {
+		indexer = ni.cfgMapInformer.GetIndexer()
+	}
+	return corelisters.NewConfigMapLister(indexer).ConfigMaps(namespace)
+}
+
+// secretLister lists the Secrets of the namespaces watched, through the informer of each
+type secretLister struct {
+	c *Controller
+}
+
+func (l secretLister) List(selector labels.Selector) ([]*corev1.Secret, error) {
+	ret := []*corev1.Secret{}
+	for _, ni := range l.c.watchedNamespaces() {
+		secrets, err := corelisters.NewSecretLister(ni.secInformer.GetIndexer()).List(selector)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, secrets...)
+	}
+	return ret, nil
+}
+
+func (l secretLister) Secrets(namespace string) corelisters.SecretNamespaceLister {
+	indexer := emptyIndexer()
+	if ni := l.c.watchedNamespace(namespace); ni != nil {
+		indexer = ni.secInformer.GetIndexer()
+	}
+	return corelisters.NewSecretLister(indexer).Secrets(namespace)
+}
+
+// objectLister lists the objects of a kind other than ConfigMap and Secret in the namespaces it is watched in,
+// through the informer of each
+type objectLister struct {
+	c        *Controller
+	kindKey  string
+	resource schema.GroupResource
+}
+
+func (l objectLister) List(selector labels.Selector) ([]runtime.Object, error) {
+	ret := []runtime.Object{}
+	for _, oi := range l.c.watchedObjects(l.kindKey) {
+		objs, err := cache.NewGenericLister(oi.informer.GetIndexer(), l.resource).List(selector)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, objs...)
+	}
+	return ret, nil
+}
+
+// Get returns the object of a namespace/name key
+func (l objectLister) Get(key string) (runtime.Object, error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return l.ByNamespace(namespace).Get(name)
+}
+
+func (l objectLister) ByNamespace(namespace string) cache.GenericNamespaceLister {
+	indexer := emptyIndexer()
+	if oi := l.c.watchedObject(l.kindKey, namespace); oi != nil {
+		indexer = oi.informer.GetIndexer()
+	}
+	return cache.NewGenericLister(indexer, l.resource).ByNamespace(namespace)
+}
@@ -0,0 +1,246 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	objcache "github.com/openshift/csi-driver-projected-resource/pkg/cache"
+	"github.com/openshift/csi-driver-projected-resource/pkg/client"
+	"github.com/openshift/csi-driver-projected-resource/pkg/validation"
+)
+
+func TestSyncNamespaceInformers(t *testing.T) {
+	watched := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "namespace1", Name: "cm1"}}
+	unwatched := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "namespace2", Name: "cm2"}}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "secret1"}}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	c := &Controller{
+		kubeClient:         fakekubeclientset.NewSimpleClientset(watched, unwatched, secret),
+		cfgMapWorkqueue:    workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		secretWorkqueue:    workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		namespaceInformers: map[string]*namespaceInformers{},
+		stopCh:             stopCh,
+	}
+	defer c.cfgMapWorkqueue.ShutDown()
+	defer c.secretWorkqueue.ShutDown()
+	defer objcache.DelConfigMap(watched)
+
+	// the namespaced share references kube-system, which is excluded, so only namespace1 is watched
+	c.listers = testListers(testShare("ConfigMap", "namespace1", "cm1"))
+	c.listers.NamespacedShares = testListers(testNamespacedShare("kube-system", "kube-system")).NamespacedShares
+	// the informers are not waited for, the objects they list are added once a later sync finds them synced
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return c.syncNamespaceInformers(), nil
+	}); err != nil {
+		t.Fatalf("expected the informers to sync")
+	}
+	if !c.backingResourcesLoaded(testShare("ConfigMap", "namespace1", "cm1")) {
+		t.Fatalf("expected the backing resources of the share to be loaded")
+	}
+	if len(c.namespaceInformers) != 1 || c.watchedNamespace("namespace1") == nil {
+		t.Fatalf("expected only namespace1 to be watched got %v", c.namespaceInformers)
+	}
+	if cms := objcache.ListObjects("ConfigMap", "namespace1", &metav1.LabelSelector{}); len(cms) != 1 {
+		t.Fatalf("expected the configmap of namespace1 in the objcache got %v", cms)
+	}
+	lister := configMapLister{c}
+	if _, err := lister.ConfigMaps("namespace1").Get("cm1"); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if _, err := lister.ConfigMaps("namespace2").Get("cm2"); err == nil {
+		t.Fatalf("expected the configmap of namespace2 not to be listed")
+	}
+	if cms, _ := lister.List(labels.Everything()); len(cms) != 1 {
+		t.Fatalf("expected one configmap listed got %d", len(cms))
+	}
+	if secrets, _ := (secretLister{c}).List(labels.Everything()); len(secrets) != 0 {
+		t.Fatalf("expected no secret listed got %d", len(secrets))
+	}
+
+	// the share is gone, namespace1 is no longer watched and its configmap is dropped
+	c.listers = testListers()
+	if !c.syncNamespaceInformers() {
+		t.Fatalf("expected the informers to sync")
+	}
+	if len(c.namespaceInformers) != 0 {
+		t.Fatalf("expected no namespace to be watched got %v", c.namespaceInformers)
+	}
+	for c.cfgMapWorkqueue.Len() > 0 {
+		obj, _ := c.cfgMapWorkqueue.Get()
+		event := obj.(client.Event)
+		c.cfgMapWorkqueue.Done(obj)
+		if event.Verb == client.DeleteObjectAction && objcache.GetKey(event.Object) == objcache.GetKey(watched) {
+			return
+		}
+	}
+	t.Fatalf("expected the configmap of namespace1 to be queued for deletion")
+}
+
+func TestReferencedShares(t *testing.T) {
+	endpointKey := objcache.KindKey("example.com/v1", "Endpoint")
+	clusterShare := testFieldShare("example.com/v1", "Endpoint", "endpoint1")
+	namespacedShare := testNamespacedFieldShare("namespace2", "example.com/v1", "Endpoint", "endpoint2")
+	role := testNamespacedFieldShare("namespace3", "rbac.authorization.k8s.io/v1", "Role", "role1")
+	role.Name = "share2"
+	c := &Controller{listers: testListers(clusterShare, namespacedShare, role)}
+
+	// NamespacedShares may not use a kind other than ConfigMap and Secret until it is listed
+	referenced := c.referencedShares()
+	if len(referenced) != 3 {
+		t.Fatalf("expected the references of every share got %v", referenced)
+	}
+	if objects := referenced["share1"].objects; len(objects) != 1 {
+		t.Fatalf("expected endpoints to be watched in namespace1 got %v", objects)
+	} else if _, ok := objects[objectInformerKey{kindKey: endpointKey, namespace: "namespace1"}]; !ok {
+		t.Fatalf("expected endpoints to be watched in namespace1 got %v", objects)
+	}
+	if objects := referenced[objcache.GetShareKey(namespacedShare)].objects; len(objects) != 0 {
+		t.Fatalf("expected the kind of the namespaced share not to be watched got %v", objects)
+	}
+	if objects := referenced[objcache.GetShareKey(role)].objects; len(objects) != 0 {
+		t.Fatalf("expected roles not to be watched got %v", objects)
+	}
+
+	defer validation.SetNamespacedShareKinds(nil)
+	validation.SetNamespacedShareKinds([]metav1.TypeMeta{{APIVersion: "example.com/v1", Kind: "Endpoint"}})
+	referenced = c.referencedShares()
+	if objects := referenced[objcache.GetShareKey(namespacedShare)].objects; len(objects) != 1 {
+		t.Fatalf("expected endpoints to be watched in namespace2 got %v", objects)
+	} else if _, ok := objects[objectInformerKey{kindKey: endpointKey, namespace: "namespace2"}]; !ok {
+		t.Fatalf("expected endpoints to be watched in namespace2 got %v", objects)
+	}
+}
+
+func TestObjectLister(t *testing.T) {
+	endpointKey := objcache.KindKey("example.com/v1", "Endpoint")
+	c := &Controller{objectInformers: map[objectInformerKey]*objectInformer{}}
+	for _, namespace := range []string{"namespace1", "namespace2"} {
+		endpoint := &unstructured.Unstructured{}
+		endpoint.SetAPIVersion("example.com/v1")
+		endpoint.SetKind("Endpoint")
+		endpoint.SetNamespace(namespace)
+		endpoint.SetName("endpoint1")
+		informer := cache.NewSharedIndexInformer(&cache.ListWatch{}, &unstructured.Unstructured{}, 0,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		informer.GetIndexer().Add(endpoint)
+		c.objectInformers[objectInformerKey{kindKey: endpointKey, namespace: namespace}] = &objectInformer{informer: informer}
+	}
+	lister := objectLister{c: c, kindKey: endpointKey, resource: schema.GroupResource{Group: "example.com", Resource: "endpoints"}}
+
+	if objs, _ := lister.List(labels.Everything()); len(objs) != 2 {
+		t.Fatalf("expected the endpoints of both namespaces listed got %d", len(objs))
+	}
+	if _, err := lister.ByNamespace("namespace2").Get("endpoint1"); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if _, err := lister.Get("namespace1/endpoint1"); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if _, err := lister.ByNamespace("namespace3").Get("endpoint1"); !kerrors.IsNotFound(err) {
+		t.Fatalf("expected the endpoint of a namespace not watched not to be found got %v", err)
+	}
+}
+
+func TestWatchBackingResources(t *testing.T) {
+	share := testShare("ConfigMap", "namespace1", "cm1")
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	c := &Controller{
+		kubeClient:         fakekubeclientset.NewSimpleClientset(),
+		namespaceInformers: map[string]*namespaceInformers{},
+		objectInformers:    map[objectInformerKey]*objectInformer{},
+		listers:            testListers(share),
+		stopCh:             stopCh,
+	}
+
+	// an informer that has not synced yet has the share requeued rather than waited for
+	c.namespaceInformers["namespace1"] = c.newNamespaceInformers("namespace1")
+	if err := c.watchBackingResources(share); err == nil {
+		t.Fatalf("expected an error while the informers of namespace1 have not synced")
+	}
+	if c.backingResourcesLoaded(share) {
+		t.Fatalf("expected the backing resources of the share not to be loaded")
+	}
+
+	// backing resources that are not watched have nothing to wait for
+	if !c.backingResourcesLoaded(testShare("ConfigMap", "kube-system", "cm1")) {
+		t.Fatalf("expected the backing resources of a namespace not watched to be loaded")
+	}
+}
+
+func TestWatchShare(t *testing.T) {
+	share1 := testShare("ConfigMap", "namespace1", "cm1")
+	share2 := testShare("Secret", "namespace1", "secret1")
+	share2.Name = "share2"
+	share3 := testShare("ConfigMap", "namespace2", "cm2")
+	share3.Name = "share3"
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	c := &Controller{
+		kubeClient:         fakekubeclientset.NewSimpleClientset(),
+		cfgMapWorkqueue:    workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		secretWorkqueue:    workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		namespaceInformers: map[string]*namespaceInformers{},
+		objectInformers:    map[objectInformerKey]*objectInformer{},
+		listers:            testListers(),
+		stopCh:             stopCh,
+	}
+	defer c.cfgMapWorkqueue.ShutDown()
+	defer c.secretWorkqueue.ShutDown()
+
+	c.watchShare(share1)
+	c.watchShare(share2)
+	c.watchShare(share3)
+	if len(c.namespaceInformers) != 2 {
+		t.Fatalf("expected namespace1 and namespace2 to be watched got %v", c.namespaceInformers)
+	}
+	namespace1 := c.watchedNamespace("namespace1")
+	if c.namespaceReferences["namespace1"] != 2 || c.namespaceReferences["namespace2"] != 1 {
+		t.Fatalf("expected two shares to reference namespace1 and one namespace2 got %v", c.namespaceReferences)
+	}
+
+	// a share moving to another namespace leaves the informers the others reference alone
+	share3.Spec.BackingResource.Namespace = "namespace3"
+	c.watchShare(share3)
+	if c.watchedNamespace("namespace1") != namespace1 {
+		t.Fatalf("expected the informers of namespace1 to be kept")
+	}
+	if c.watchedNamespace("namespace2") != nil || c.watchedNamespace("namespace3") == nil {
+		t.Fatalf("expected namespace3 to be watched in place of namespace2 got %v", c.namespaceInformers)
+	}
+
+	// namespace1 is watched until the last share referencing it is deleted
+	c.unwatchShare(share1)
+	if c.watchedNamespace("namespace1") != namespace1 {
+		t.Fatalf("expected namespace1 to be watched while share2 references it")
+	}
+	c.unwatchShare(share2)
+	if c.watchedNamespace("namespace1") != nil {
+		t.Fatalf("expected namespace1 no longer to be watched")
+	}
+	if len(c.shareReferences) != 1 || c.namespaceReferences["namespace3"] != 1 || len(c.namespaceReferences) != 1 {
+		t.Fatalf("expected only share3 to reference namespace3 got %v %v", c.shareReferences, c.namespaceReferences)
+	}
+
+	// the full rebuild works the references out again from the shares listed
+	c.listers = testListers(share1)
+	c.syncNamespaceInformers()
+	if len(c.namespaceInformers) != 1 || c.watchedNamespace("namespace1") == nil {
+		t.Fatalf("expected only namespace1 to be watched got %v", c.namespaceInformers)
+	}
+	if len(c.shareReferences) != 1 || c.namespaceReferences["namespace1"] != 1 || len(c.namespaceReferences) != 1 {
+		t.Fatalf("expected only share1 to reference namespace1 got %v %v", c.shareReferences, c.namespaceReferences)
+	}
+}
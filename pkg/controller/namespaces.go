@@ -0,0 +1,298 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/csi-driver-projected-resource/pkg/client"
+	"github.com/openshift/csi-driver-projected-resource/pkg/validation"
+)
+
+const (
+	// NamespaceConfigPollInterval is how often the namespace configuration file is read for changes; a ConfigMap
+	// mounted in the pod is updated by the kubelet, without the pod being restarted
+	NamespaceConfigPollInterval = 30 * time.Second
+)
+
+var (
+	// DefaultExcludedNamespaces are the namespaces excluded when no namespace configuration is given; OpenShift
+	// "system" namespaces with chatty configmaps like the leaderelection related ones that are updated every
+	// few seconds
+	DefaultExcludedNamespaces = []string{"kube-system",
+		"openshift-machine-api",
+		"openshift-kube-apiserver",
+		"openshift-kube-apiserver-operator",
+		"openshift-kube-scheduler",
+		"openshift-kube-controller-manager",
+		"openshift-kube-controller-manager-operator",
+		"openshift-kube-scheduler-operator",
+		"openshift-console-operator",
+		"openshift-controller-manager",
+		"openshift-controller-manager-operator",
+		"openshift-cloud-credential-operator",
+		"openshift-authentication-operator",
+		"openshift-service-ca",
+		"openshift-kube-storage-version-migrator-operator",
+		"openshift-config-operator",
+		"openshift-etcd-operator",
+		"openshift-apiserver-operator",
+		"openshift-cluster-csi-drivers",
+		"openshift-cluster-storage-operator",
+		"openshift-cluster-version",
+		"openshift-image-registry",
+		"openshift-machine-config-operator",
+		"openshift-sdn",
+		"openshift-service-ca-operator",
+	}
+
+	// currentFilter holds the *namespaceFilter of the namespace configuration in use
+	currentFilter atomic.Value
+)
+
+// NamespaceConfig configures the namespaces the controller watches ConfigMaps, Secrets and the other backing
+// resources of shares in, and the kinds NamespacedShares may use. It is read, as YAML or JSON, from the file given with --namespace-config, which can be
+// the key of a ConfigMap mounted in the pod
+type NamespaceConfig struct {
+	// Include lists the namespaces that are watched; all namespaces are when it is empty
+	Include []string `json:"include,omitempty"`
+	// Exclude lists the namespaces that are not watched, even when they are included
+	Exclude []string `json:"exclude,omitempty"`
+	// LabelSelector restricts the namespaces that are watched to those with matching labels
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+	// NamespacedShareKinds lists, by apiVersion and kind, the kinds other than ConfigMap and Secret that the
+	// backing resources of NamespacedShares may be of; Shares, created by cluster admins, may use any kind
+	NamespacedShareKinds []metav1.TypeMeta `json:"namespacedShareKinds,omitempty"`
+}
+
+// namespaceFilter is a NamespaceConfig ready to be matched against namespaces
+type namespaceFilter struct {
+	config   NamespaceConfig
+	include  sets.String
+	exclude  sets.String
+	selector labels.Selector
+}
+
+func init() {
+	currentFilter.Store(mustNamespaceFilter(DefaultNamespaceConfig()))
+}
+
+// DefaultNamespaceConfig returns the namespace configuration used when none is given, excluding
+// DefaultExcludedNamespaces
+func DefaultNamespaceConfig() *NamespaceConfig {
+	return &NamespaceConfig{Exclude: append([]string{}, DefaultExcludedNamespaces...)}
+}
+
+// LoadNamespaceConfig reads a namespace configuration from a file, or returns the default one if path is empty.
+// An empty file watches all namespaces
+func LoadNamespaceConfig(path string) (*NamespaceConfig, error) {
+	if len(path) == 0 {
+		return DefaultNamespaceConfig(), nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &NamespaceConfig{}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), len(data)).Decode(config); err != nil {
+			return nil, fmt.Errorf("invalid namespace configuration %s: %s", path, err.Error())
+		}
+	}
+	if _, err := newNamespaceFilter(config); err != nil {
+		return nil, fmt.Errorf("invalid namespace configuration %s: %s", path, err.Error())
+	}
+	return config, nil
+}
+
+func newNamespaceFilter(config *NamespaceConfig) (*namespaceFilter, error) {
+	filter := &namespaceFilter{
+		config:  *config,
+		include: sets.NewString(),
+		exclude: sets.NewString(),
+	}
+	for _, ns := range config.Include {
+		filter.include.Insert(strings.TrimSpace(ns))
+	}
+	for _, ns := range config.Exclude {
+		filter.exclude.Insert(strings.TrimSpace(ns))
+	}
+	// a nil selector would match nothing
+	if config.LabelSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(config.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid labelSelector: %s", err.Error())
+		}
+		filter.selector = selector
+	}
+	for _, kind := range config.NamespacedShareKinds {
+		if len(strings.TrimSpace(kind.Kind)) == 0 || len(strings.TrimSpace(kind.APIVersion)) == 0 {
+			return nil, fmt.Errorf("invalid namespacedShareKinds: apiVersion and kind need to be set")
+		}
+		if _, err := schema.ParseGroupVersion(strings.TrimSpace(kind.APIVersion)); err != nil {
+			return nil, fmt.Errorf("invalid namespacedShareKinds: apiVersion %s is invalid: %s", kind.APIVersion, err.Error())
+		}
+	}
+	return filter, nil
+}
+
+func mustNamespaceFilter(config *NamespaceConfig) *namespaceFilter {
+	filter, err := newNamespaceFilter(config)
+	if err != nil {
+		panic(err)
+	}
+	return filter
+}
+
+func getNamespaceFilter() *namespaceFilter {
+	return currentFilter.Load().(*namespaceFilter)
+}
+
+// SetNamespaceConfig sets the namespace configuration in use
+func SetNamespaceConfig(config *NamespaceConfig) error {
+	filter, err := newNamespaceFilter(config)
+	if err != nil {
+		return err
+	}
+	currentFilter.Store(filter)
+	validation.SetNamespacedShareKinds(config.NamespacedShareKinds)
+	return nil
+}
+
+// GetNamespaceConfig returns the namespace configuration in use
+func GetNamespaceConfig() NamespaceConfig {
+	return getNamespaceFilter().config
+}
+
+// IsNamespaceExcluded returns true if the controller does not watch ConfigMaps and Secrets
+// in the given namespace.
+func IsNamespaceExcluded(namespace string) bool {
+	return getNamespaceFilter().excludes(namespace)
+}
+
+func (f *namespaceFilter) excludes(namespace string) bool {
+	if f.exclude.Has(namespace) {
+		return true
+	}
+	if f.include.Len() > 0 && !f.include.Has(namespace) {
+		return true
+	}
+	if f.selector == nil {
+		return false
+	}
+	// the labels of a namespace are only known where namespaces are watched, which the webhook does not do;
+	// a namespace not seen yet is matched once it is, through namespaceEventHandler
+	lister := client.GetListers().Namespaces
+	if lister == nil {
+		return false
+	}
+	ns, err := lister.Get(namespace)
+	if err != nil {
+		return false
+	}
+	return !f.selector.Matches(labels.Set(ns.Labels))
+}
+
+// WatchNamespaceConfig reads the namespace configuration file every NamespaceConfigPollInterval until stopCh is
+// closed, and sets the configuration in use when it changes, calling onChange after it has been set. An invalid
+// or missing file leaves the configuration in use as it is
+func WatchNamespaceConfig(path string, stopCh <-chan struct{}, onChange func()) {
+	if len(path) == 0 {
+		return
+	}
+	wait.Until(func() {
+		config, err := LoadNamespaceConfig(path)
+		if err != nil {
+			klog.Warningf("keeping the namespace configuration in use: %s", err.Error())
+			return
+		}
+		if reflect.DeepEqual(*config, GetNamespaceConfig()) {
+			return
+		}
+		if err := SetNamespaceConfig(config); err != nil {
+			klog.Warningf("keeping the namespace configuration in use: %s", err.Error())
+			return
+		}
+		klog.V(0).Infof("namespace configuration %s changed, include %v exclude %v labelSelector %s", path,
+			config.Include, config.Exclude, metav1.FormatLabelSelector(config.LabelSelector))
+		if onChange != nil {
+			onChange()
+		}
+	}, NamespaceConfigPollInterval, stopCh)
+}
+
+// namespaceEventHandler drops the objects of a namespace, or adds them back, when its labels stop or start
+// matching the label selector of the namespace configuration
+func (c *Controller) namespaceEventHandler() cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(o interface{}) {
+			switch v := o.(type) {
+			case *corev1.Namespace:
+				// objects listed before their namespace was seen were let through; until the listers have
+				// synced, the namespaces watched are yet to be worked out
+				if client.ListersSynced() && getNamespaceFilter().selector != nil && IsNamespaceExcluded(v.Name) {
+					c.resyncNamespace(v.Name)
+				}
+			default:
+				//log unrecognized type
+			}
+		},
+		UpdateFunc: func(o, n interface{}) {
+			old, ok := o.(*corev1.Namespace)
+			if !ok {
+				return
+			}
+			switch v := n.(type) {
+			case *corev1.Namespace:
+				selector := getNamespaceFilter().selector
+				if selector != nil && selector.Matches(labels.Set(old.Labels)) != selector.Matches(labels.Set(v.Labels)) {
+					c.resyncNamespace(v.Name)
+				}
+			default:
+				//log unrecognized type
+			}
+		},
+	}
+}
+
+// resyncNamespace starts or stops watching the backing resources of a namespace as it starts or stops being
+// watched
+func (c *Controller) resyncNamespace(namespace string) {
+	klog.V(2).Infof("namespace %s labels changed, excluded %v", namespace, IsNamespaceExcluded(namespace))
+	c.syncNamespaceInformers()
+}
+
+// queueObject queues an object held by one of the informers of the backing resources of shares
+func (c *Controller) queueObject(obj interface{}, verb client.ObjectAction) {
+	switch v := obj.(type) {
+	case *corev1.ConfigMap:
+		c.addConfigMapToQueue(v, verb)
+	case *corev1.Secret:
+		c.addSecretToQueue(v, verb)
+	case *unstructured.Unstructured:
+		c.addObjectToQueue(v, verb)
+	}
+}
+
+// applyNamespaceConfig applies a change of the namespace configuration: the backing resources of the namespaces
+// now watched are, those of the namespaces left out are dropped along with their informers, and the shares are
+// synced again, once the informers they need have synced, so their status reflects the change
+func (c *Controller) applyNamespaceConfig() {
+	c.syncNamespaceInformers()
+	c.queueShares()
+}
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/csi-driver-projected-resource/pkg/client"
+)
+
+func TestLoadNamespaceConfig(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "ut")
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range []struct {
+		name        string
+		content     string
+		expected    NamespaceConfig
+		expectedErr string
+	}{
+		{
+			name:     "empty file",
+			content:  "",
+			expected: NamespaceConfig{},
+		},
+		{
+			name: "yaml",
+			content: `include:
+  - team-a
+  - team-b
+exclude:
+  - team-b
+labelSelector:
+  matchLabels:
+    projectedresource/source: "true"
+`,
+			expected: NamespaceConfig{
+				Include:       []string{"team-a", "team-b"},
+				Exclude:       []string{"team-b"},
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"projectedresource/source": "true"}},
+			},
+		},
+		{
+			name:     "json",
+			content:  `{"exclude": ["kube-system"]}`,
+			expected: NamespaceConfig{Exclude: []string{"kube-system"}},
+		},
+		{
+			name: "invalid label selector",
+			content: `labelSelector:
+  matchExpressions:
+    - key: projectedresource/source
+      operator: Sometimes
+`,
+			expectedErr: "invalid labelSelector",
+		},
+		{
+			name: "namespaced share kinds",
+			content: `namespacedShareKinds:
+  - apiVersion: example.com/v1
+    kind: Endpoint
+`,
+			expected: NamespaceConfig{NamespacedShareKinds: []metav1.TypeMeta{{APIVersion: "example.com/v1", Kind: "Endpoint"}}},
+		},
+		{
+			name: "namespaced share kind without apiVersion",
+			content: `namespacedShareKinds:
+  - kind: Endpoint
+`,
+			expectedErr: "invalid namespacedShareKinds",
+		},
+	} {
+		path := filepath.Join(dir, strings.ReplaceAll(test.name, " ", "-"))
+		if err := ioutil.WriteFile(path, []byte(test.content), 0644); err != nil {
+			t.Fatalf("unexpected err %s", err.Error())
+		}
+		config, err := LoadNamespaceConfig(path)
+		if len(test.expectedErr) > 0 {
+			if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+				t.Fatalf("test %s expected error %s got %v", test.name, test.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test %s unexpected err %s", test.name, err.Error())
+		}
+		if !equalNamespaceConfigs(*config, test.expected) {
+			t.Fatalf("test %s expected %#v got %#v", test.name, test.expected, *config)
+		}
+	}
+
+	config, err := LoadNamespaceConfig("")
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if len(config.Exclude) != len(DefaultExcludedNamespaces) {
+		t.Fatalf("expected the default excluded namespaces got %v", config.Exclude)
+	}
+}
+
+func equalNamespaceConfigs(a, b NamespaceConfig) bool {
+	return strings.Join(a.Include, ",") == strings.Join(b.Include, ",") &&
+		strings.Join(a.Exclude, ",") == strings.Join(b.Exclude, ",") &&
+		metav1.FormatLabelSelector(a.LabelSelector) == metav1.FormatLabelSelector(b.LabelSelector) &&
+		fmt.Sprint(a.NamespacedShareKinds) == fmt.Sprint(b.NamespacedShareKinds)
+}
+
+func TestIsNamespaceExcluded(t *testing.T) {
+	defer SetNamespaceConfig(DefaultNamespaceConfig())
+	nsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	nsIndexer.Add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "labeled", Labels: map[string]string{"projectedresource/source": "true"}}})
+	nsIndexer.Add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}})
+	client.SetNamespacesLister(corelisters.NewNamespaceLister(nsIndexer))
+	defer client.SetNamespacesLister(nil)
+
+	for _, test := range []struct {
+		name     string
+		config   NamespaceConfig
+		excluded map[string]bool
+	}{
+		{
+			name:     "default",
+			config:   *DefaultNamespaceConfig(),
+			excluded: map[string]bool{"kube-system": true, "unlabeled": false},
+		},
+		{
+			name:     "include and exclude",
+			config:   NamespaceConfig{Include: []string{"labeled", "unlabeled"}, Exclude: []string{"unlabeled"}},
+			excluded: map[string]bool{"labeled": false, "unlabeled": true, "other": true},
+		},
+		{
+			name: "label selector",
+			config: NamespaceConfig{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"projectedresource/source": "true"}},
+			},
+			// a namespace not seen yet is not excluded until its labels are known
+			excluded: map[string]bool{"labeled": false, "unlabeled": true, "unknown": false},
+		},
+	} {
+		if err := SetNamespaceConfig(&test.config); err != nil {
+			t.Fatalf("test %s unexpected err %s", test.name, err.Error())
+		}
+		for namespace, excluded := range test.excluded {
+			if IsNamespaceExcluded(namespace) != excluded {
+				t.Fatalf("test %s expected namespace %s excluded %v", test.name, namespace, excluded)
+			}
+		}
+	}
+}
@@ -0,0 +1,399 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+
+	sharev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+	objcache "github.com/openshift/csi-driver-projected-resource/pkg/cache"
+	"github.com/openshift/csi-driver-projected-resource/pkg/client"
+	"github.com/openshift/csi-driver-projected-resource/pkg/validation"
+)
+
+const (
+	// StatusLeaseName is the name of the Lease electing the driver instance that writes the status of shares
+	StatusLeaseName = "projected-resource-share-status"
+	// DefaultStatusLeaseNamespace is the namespace of the status lease when the driver does not run in a pod
+	DefaultStatusLeaseNamespace = "csi-driver-projected-resource"
+
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// backingResourceProblem captures why one of the backing resources of a share cannot be projected
+type backingResourceProblem struct {
+	reason  string
+	message string
+}
+
+func validateBackingResource(br sharev1alpha1.BackingResource) *backingResourceProblem {
+	if err := validation.ValidateBackingResourceKind(br); err != nil {
+		return &backingResourceProblem{"InvalidKind", fmt.Sprintf("backing resource kind %q is invalid: %s", br.Kind, err.Error())}
+	}
+	switch {
+	case len(strings.TrimSpace(br.Namespace)) == 0:
+		return &backingResourceProblem{"MissingNamespace", fmt.Sprintf("backing resource %s %q namespace needs to be set", br.Kind, br.Name)}
+	case len(strings.TrimSpace(br.Name)) == 0 && br.Selector == nil:
+		return &backingResourceProblem{"MissingName", fmt.Sprintf("backing resource %s in namespace %q name or selector needs to be set", br.Kind, br.Namespace)}
+	}
+	if err := validation.ValidateSelector(br); err != nil {
+		return &backingResourceProblem{"InvalidSelector", fmt.Sprintf("backing resource %s in namespace %q has an invalid selector: %s", br.Kind, br.Namespace, err.Error())}
+	}
+	for _, item := range br.Items {
+		if err := validation.ValidateKeyToPath(item); err != nil {
+			return &backingResourceProblem{"InvalidItem", fmt.Sprintf("backing resource %s %s:%s has an invalid item: %s", br.Kind, br.Namespace, br.Name, err.Error())}
+		}
+	}
+	return nil
+}
+
+// applyProblems sets the condition to false when problems were found, using the reason of
+// the first one and the messages of all of them
+func applyProblems(condition *metav1.Condition, problems []backingResourceProblem) {
+	if len(problems) == 0 {
+		return
+	}
+	messages := []string{}
+	for _, problem := range problems {
+		messages = append(messages, problem.message)
+	}
+	condition.Status = metav1.ConditionFalse
+	condition.Reason = problems[0].reason
+	condition.Message = strings.Join(messages, "; ")
+}
+
+// buildShareConditions determines the conditions of a share from the state of its spec
+// and the contents of the controller's caches
+func buildShareConditions(share sharev1alpha1.ShareObject, listers *client.Listers) []metav1.Condition {
+	valid := metav1.Condition{
+		Type:    sharev1alpha1.ShareConditionValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Valid",
+		Message: "all backing resources are fully specified",
+	}
+	watched := metav1.Condition{
+		Type:    sharev1alpha1.ShareConditionBackingResourceNamespaceWatched,
+		Status:  metav1.ConditionTrue,
+		Reason:  "NamespaceWatched",
+		Message: "the namespaces of all backing resources are watched by the controller",
+	}
+	found := metav1.Condition{
+		Type:    sharev1alpha1.ShareConditionBackingResourceFound,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Found",
+		Message: "all backing resources were found",
+	}
+
+	brs := share.GetSpec().GetBackingResources()
+	if len(brs) == 0 {
+		problem := backingResourceProblem{"MissingBackingResource", "neither backingResource nor backingResources are set"}
+		applyProblems(&valid, []backingResourceProblem{problem})
+		applyProblems(&found, []backingResourceProblem{{"InvalidBackingResource", problem.message}})
+		return []metav1.Condition{valid, watched, found}
+	}
+
+	invalid := []backingResourceProblem{}
+	excluded := []backingResourceProblem{}
+	missing := []backingResourceProblem{}
+	lookupErrors := []string{}
+	for _, br := range brs {
+		if problem := validateBackingResource(br); problem != nil {
+			invalid = append(invalid, *problem)
+			missing = append(missing, backingResourceProblem{"InvalidBackingResource", problem.message})
+			continue
+		}
+		if len(share.GetNamespace()) > 0 {
+			if err := validation.ValidateNamespacedBackingResourceKind(br); err != nil {
+				problem := backingResourceProblem{"KindNotAllowed", err.Error()}
+				invalid = append(invalid, problem)
+				missing = append(missing, backingResourceProblem{"InvalidBackingResource", problem.message})
+				continue
+			}
+			if err := validation.ValidateNamespacedBackingResource(share.GetNamespace(), br, listers.Namespaces); err != nil {
+				problem := backingResourceProblem{"NamespaceNotAllowed", err.Error()}
+				invalid = append(invalid, problem)
+				missing = append(missing, backingResourceProblem{"InvalidBackingResource", problem.message})
+				continue
+			}
+		}
+		if IsNamespaceExcluded(br.Namespace) {
+			problem := backingResourceProblem{"NamespaceExcluded",
+				fmt.Sprintf("namespace %q is in the list of namespaces excluded by the controller", br.Namespace)}
+			excluded = append(excluded, problem)
+			missing = append(missing, problem)
+			continue
+		}
+		if br.Selector != nil {
+			matched, err := hasSelectedObjects(br, listers)
+			switch {
+			case err != nil:
+				lookupErrors = append(lookupErrors, err.Error())
+			case !matched:
+				missing = append(missing, backingResourceProblem{"NoMatch",
+					fmt.Sprintf("no %s in namespace %q matches selector %s", br.Kind, br.Namespace, metav1.FormatLabelSelector(br.Selector))})
+			}
+			continue
+		}
+		var err error
+		switch strings.TrimSpace(br.Kind) {
+		case "ConfigMap":
+			_, err = listers.ConfigMaps.ConfigMaps(br.Namespace).Get(br.Name)
+		case "Secret":
+			_, err = listers.Secrets.Secrets(br.Namespace).Get(br.Name)
+		default:
+			kindKey := objcache.KindKey(br.APIVersion, br.Kind)
+			if lister := listers.GetObjectLister(kindKey); lister != nil {
+				_, err = lister.ByNamespace(br.Namespace).Get(br.Name)
+			} else {
+				err = fmt.Errorf("backing resource kind %s is not watched by the controller", kindKey)
+			}
+		}
+		switch {
+		case kerrors.IsNotFound(err):
+			missing = append(missing, backingResourceProblem{"NotFound",
+				fmt.Sprintf("%s %s not found", br.Kind, objcache.BuildKey(br.Namespace, br.Name))})
+		case err != nil:
+			lookupErrors = append(lookupErrors, err.Error())
+		}
+	}
+	applyProblems(&valid, invalid)
+	applyProblems(&watched, excluded)
+	applyProblems(&found, missing)
+	if len(missing) == 0 && len(lookupErrors) > 0 {
+		found.Status = metav1.ConditionUnknown
+		found.Reason = "LookupError"
+		found.Message = strings.Join(lookupErrors, "; ")
+	}
+
+	return []metav1.Condition{valid, watched, found}
+}
+
+// hasSelectedObjects returns true if any ConfigMap or Secret in the controller's caches matches the
+// selector of the backing resource
+func hasSelectedObjects(br sharev1alpha1.BackingResource, listers *client.Listers) (bool, error) {
+	selector, err := metav1.LabelSelectorAsSelector(br.Selector)
+	if err != nil {
+		return false, err
+	}
+	switch strings.TrimSpace(br.Kind) {
+	case "ConfigMap":
+		cms, err := listers.ConfigMaps.ConfigMaps(br.Namespace).List(selector)
+		return len(cms) > 0, err
+	case "Secret":
+		secrets, err := listers.Secrets.Secrets(br.Namespace).List(selector)
+		return len(secrets) > 0, err
+	}
+	return false, nil
+}
+
+// desiredConditions returns the conditions of the share once the ones built from its current state are
+// applied, along with whether they differ from what is currently recorded
+func desiredConditions(share sharev1alpha1.ShareObject, listers *client.Listers) ([]metav1.Condition, bool) {
+	current := share.GetStatus().Conditions
+	conditions := make([]metav1.Condition, len(current))
+	copy(conditions, current)
+	for _, condition := range buildShareConditions(share, listers) {
+		condition.ObservedGeneration = share.GetGeneration()
+		meta.SetStatusCondition(&conditions, condition)
+	}
+	return conditions, !equality.Semantic.DeepEqual(conditions, current)
+}
+
+// runStatusLeaderElection campaigns, until stopCh is closed, for the lease of the driver instance writing the
+// status of shares, so that the instances of the other nodes do not race it on the updates. Once it holds the
+// lease every share is queued, bringing their status up to date; once it loses it, it campaigns again
+func (c *Controller) runStatusLeaderElection(stopCh <-chan struct{}) {
+	identity := c.nodeID
+	if len(identity) == 0 {
+		identity, _ = os.Hostname()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Namespace: statusLeaseNamespace(), Name: StatusLeaseName},
+		Client:     c.kubeClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+	wait.Until(func() {
+		leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			LeaseDuration:   15 * time.Second,
+			RenewDeadline:   10 * time.Second,
+			RetryPeriod:     2 * time.Second,
+			ReleaseOnCancel: true,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(context.Context) {
+					klog.V(2).Infof("%s now writes the status of shares", identity)
+					atomic.StoreInt32(&c.statusWriter, 1)
+					c.queueShares()
+				},
+				OnStoppedLeading: func() {
+					klog.V(2).Infof("%s no longer writes the status of shares", identity)
+					atomic.StoreInt32(&c.statusWriter, 0)
+				},
+			},
+		})
+	}, time.Second, stopCh)
+}
+
+// statusLeaseNamespace returns the namespace of the status lease, the one the driver runs in
+func statusLeaseNamespace() string {
+	if data, err := ioutil.ReadFile(serviceAccountNamespaceFile); err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		return strings.TrimSpace(string(data))
+	}
+	return DefaultStatusLeaseNamespace
+}
+
+// writesStatus returns true if this driver instance holds the status lease
+func (c *Controller) writesStatus() bool {
+	return atomic.LoadInt32(&c.statusWriter) == 1
+}
+
+// handleStatusUpdateError filters out the errors from a status update that do not warrant a retry
+func handleStatusUpdateError(shareKey string, err error) error {
+	switch {
+	case kerrors.IsNotFound(err):
+		return nil
+	case kerrors.IsConflict(err):
+		// the share changed since it was listed, or the instance that held the status lease before this one
+		// just updated it; the update of the share that follows, or the next share relist, reconciles it
+		klog.V(4).Infof("conflict updating status of share %s: %s", shareKey, err.Error())
+		return nil
+	}
+	return err
+}
+
+// syncShareStatus updates the conditions of the named share via the status subresource
+// if they differ from what is currently recorded, when this driver instance holds the status lease
+func (c *Controller) syncShareStatus(name string) error {
+	if !c.writesStatus() {
+		return nil
+	}
+	share, err := c.listers.Shares.Get(name)
+	if kerrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	conditions, changed := desiredConditions(share, c.listers)
+	if !changed {
+		return nil
+	}
+
+	share = share.DeepCopy()
+	share.Status.Conditions = conditions
+	klog.V(4).Infof("updating status conditions of share %s", share.Name)
+	_, err = c.shareClient.ProjectedresourceV1alpha1().Shares().UpdateStatus(context.TODO(), share, metav1.UpdateOptions{})
+	return handleStatusUpdateError(share.Name, err)
+}
+
+// syncNamespacedShareStatus updates the conditions of the named namespaced share via the status subresource
+// if they differ from what is currently recorded, when this driver instance holds the status lease
+func (c *Controller) syncNamespacedShareStatus(namespace, name string) error {
+	if !c.writesStatus() {
+		return nil
+	}
+	share, err := c.listers.NamespacedShares.NamespacedShares(namespace).Get(name)
+	if kerrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	conditions, changed := desiredConditions(share, c.listers)
+	if !changed {
+		return nil
+	}
+
+	share = share.DeepCopy()
+	share.Status.Conditions = conditions
+	klog.V(4).Infof("updating status conditions of namespaced share %s", objcache.GetShareKey(share))
+	_, err = c.shareClient.ProjectedresourceV1alpha1().NamespacedShares(namespace).UpdateStatus(context.TODO(), share, metav1.UpdateOptions{})
+	return handleStatusUpdateError(objcache.GetShareKey(share), err)
+}
+
+// referencesBackingResource returns true if one of the backing resources of the share is the given object, whose
+// kind is identified by its objcache.KindKey, or selects objects of its kind in its namespace, in which case it may
+// have started or stopped matching
+func referencesBackingResource(share sharev1alpha1.ShareObject, kindKey, namespace, name string) bool {
+	for _, br := range share.GetSpec().GetBackingResources() {
+		if objcache.KindKey(br.APIVersion, br.Kind) == kindKey && br.Namespace == namespace && (br.Name == name || br.Selector != nil) {
+			return true
+		}
+	}
+	return false
+}
+
+// upsertUpdatedObject upserts an updated object, whose kind is identified by its objcache.KindKey, and updates the
+// status of the shares referencing it when it started or stopped being referenced or its labels changed, as it may
+// have started or stopped matching the selector of a share; no other update changes whether a backing resource
+// is found
+func (c *Controller) upsertUpdatedObject(kindKey string, obj runtime.Object) error {
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	key := objcache.GetKey(obj)
+	previous := objcache.GetObject(kindKey, key)
+	objcache.UpsertObject(kindKey, obj)
+	if current := objcache.GetObject(kindKey, key); previous == nil && current == nil {
+		return nil
+	} else if previous != nil && current != nil {
+		if previousMeta, err := meta.Accessor(previous); err == nil && labels.Equals(previousMeta.GetLabels(), objMeta.GetLabels()) {
+			return nil
+		}
+	}
+	return c.syncStatusOfSharesFor(kindKey, objMeta.GetNamespace(), objMeta.GetName())
+}
+
+// syncStatusOfSharesFor updates the status of any share or namespaced share whose backing resource is the
+// given object
+func (c *Controller) syncStatusOfSharesFor(kindKey, namespace, name string) error {
+	shares, err := c.listers.Shares.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, share := range shares {
+		if !referencesBackingResource(share, kindKey, namespace, name) {
+			continue
+		}
+		if err := c.syncShareStatus(share.Name); err != nil {
+			return err
+		}
+	}
+	namespacedShares, err := c.listers.NamespacedShares.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, share := range namespacedShares {
+		if !referencesBackingResource(share, kindKey, namespace, name) {
+			continue
+		}
+		if err := c.syncNamespacedShareStatus(share.Namespace, share.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,453 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	sharev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+	objcache "github.com/openshift/csi-driver-projected-resource/pkg/cache"
+	"github.com/openshift/csi-driver-projected-resource/pkg/client"
+	fakeshareclientset "github.com/openshift/csi-driver-projected-resource/pkg/generated/clientset/versioned/fake"
+	sharelisters "github.com/openshift/csi-driver-projected-resource/pkg/generated/listers/projectedresource/v1alpha1"
+)
+
+func testListers(objs ...interface{}) *client.Listers {
+	cmIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	secIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	nsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	shareIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	namespacedShareIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	endpointIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, obj := range objs {
+		switch obj.(type) {
+		case *corev1.ConfigMap:
+			cmIndexer.Add(obj)
+		case *corev1.Secret:
+			secIndexer.Add(obj)
+		case *corev1.Namespace:
+			nsIndexer.Add(obj)
+		case *sharev1alpha1.Share:
+			shareIndexer.Add(obj)
+		case *sharev1alpha1.NamespacedShare:
+			namespacedShareIndexer.Add(obj)
+		case *unstructured.Unstructured:
+			endpointIndexer.Add(obj)
+		}
+	}
+	listers := &client.Listers{
+		ConfigMaps:       corelisters.NewConfigMapLister(cmIndexer),
+		Secrets:          corelisters.NewSecretLister(secIndexer),
+		Namespaces:       corelisters.NewNamespaceLister(nsIndexer),
+		Shares:           sharelisters.NewShareLister(shareIndexer),
+		NamespacedShares: sharelisters.NewNamespacedShareLister(namespacedShareIndexer),
+	}
+	listers.Objects.Store(objcache.KindKey("example.com/v1", "Endpoint"),
+		cache.NewGenericLister(endpointIndexer, schema.GroupResource{Group: "example.com", Resource: "endpoints"}))
+	return listers
+}
+
+func testFieldShare(apiVersion, kind, name string) *sharev1alpha1.Share {
+	share := testShare(kind, "namespace1", name)
+	share.Spec.BackingResource.APIVersion = apiVersion
+	share.Spec.BackingResource.Fields = []sharev1alpha1.FieldToPath{{JSONPath: ".spec.url", Path: "url"}}
+	return share
+}
+
+func testNamespacedFieldShare(namespace, apiVersion, kind, name string) *sharev1alpha1.NamespacedShare {
+	share := testNamespacedShare(namespace, namespace)
+	share.Spec.BackingResource.APIVersion = apiVersion
+	share.Spec.BackingResource.Kind = kind
+	share.Spec.BackingResource.Name = name
+	share.Spec.BackingResource.Fields = []sharev1alpha1.FieldToPath{{JSONPath: ".spec.url", Path: "url"}}
+	return share
+}
+
+func testShare(kind, namespace, name string) *sharev1alpha1.Share {
+	return &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "share1",
+			Generation: 2,
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       kind,
+				APIVersion: "v1",
+				Name:       name,
+				Namespace:  namespace,
+			},
+		},
+	}
+}
+
+func testNamespacedShare(namespace, backingNamespace string) *sharev1alpha1.NamespacedShare {
+	return &sharev1alpha1.NamespacedShare{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "share1",
+			Namespace:  namespace,
+			Generation: 2,
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "ConfigMap",
+				APIVersion: "v1",
+				Name:       "configmap1",
+				Namespace:  backingNamespace,
+			},
+		},
+	}
+}
+
+func TestBuildShareConditions(t *testing.T) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "namespace1", Name: "configmap1",
+		Labels: map[string]string{"trust.example.com/bundle": "true"}}}
+	bundleSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"trust.example.com/bundle": "true"}}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "namespace1", Name: "secret1"}}
+	sharedNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "namespace1",
+		Annotations: map[string]string{sharev1alpha1.AllowedShareNamespacesAnnotation: "namespace2, namespace3"},
+	}}
+	endpoint := &unstructured.Unstructured{}
+	endpoint.SetAPIVersion("example.com/v1")
+	endpoint.SetKind("Endpoint")
+	endpoint.SetNamespace("namespace1")
+	endpoint.SetName("endpoint1")
+	listers := testListers(cm, secret, sharedNamespace, endpoint)
+
+	tests := []struct {
+		name           string
+		share          sharev1alpha1.ShareObject
+		valid          metav1.ConditionStatus
+		watched        metav1.ConditionStatus
+		found          metav1.ConditionStatus
+		expectedReason string
+	}{
+		{
+			name:    "configmap found",
+			share:   testShare("ConfigMap", "namespace1", "configmap1"),
+			valid:   metav1.ConditionTrue,
+			watched: metav1.ConditionTrue,
+			found:   metav1.ConditionTrue,
+		},
+		{
+			name:    "secret found",
+			share:   testShare("Secret", "namespace1", "secret1"),
+			valid:   metav1.ConditionTrue,
+			watched: metav1.ConditionTrue,
+			found:   metav1.ConditionTrue,
+		},
+		{
+			name:           "secret name typo",
+			share:          testShare("Secret", "namespace1", "secert1"),
+			valid:          metav1.ConditionTrue,
+			watched:        metav1.ConditionTrue,
+			found:          metav1.ConditionFalse,
+			expectedReason: "NotFound",
+		},
+		{
+			name:           "bad kind",
+			share:          testShare("Pod", "namespace1", "configmap1"),
+			valid:          metav1.ConditionFalse,
+			watched:        metav1.ConditionTrue,
+			found:          metav1.ConditionFalse,
+			expectedReason: "InvalidBackingResource",
+		},
+		{
+			name:    "custom kind found",
+			share:   testFieldShare("example.com/v1", "Endpoint", "endpoint1"),
+			valid:   metav1.ConditionTrue,
+			watched: metav1.ConditionTrue,
+			found:   metav1.ConditionTrue,
+		},
+		{
+			name:           "custom kind not found",
+			share:          testFieldShare("example.com/v1", "Endpoint", "endpoint2"),
+			valid:          metav1.ConditionTrue,
+			watched:        metav1.ConditionTrue,
+			found:          metav1.ConditionFalse,
+			expectedReason: "NotFound",
+		},
+		{
+			name:           "custom kind not watched",
+			share:          testFieldShare("example.com/v1", "Gateway", "gateway1"),
+			valid:          metav1.ConditionTrue,
+			watched:        metav1.ConditionTrue,
+			found:          metav1.ConditionUnknown,
+			expectedReason: "LookupError",
+		},
+		{
+			name:           "missing name",
+			share:          testShare("ConfigMap", "namespace1", ""),
+			valid:          metav1.ConditionFalse,
+			watched:        metav1.ConditionTrue,
+			found:          metav1.ConditionFalse,
+			expectedReason: "InvalidBackingResource",
+		},
+		{
+			name:           "excluded namespace",
+			share:          testShare("ConfigMap", "kube-system", "configmap1"),
+			valid:          metav1.ConditionTrue,
+			watched:        metav1.ConditionFalse,
+			found:          metav1.ConditionFalse,
+			expectedReason: "NamespaceExcluded",
+		},
+		{
+			name: "one of several backing resources missing",
+			share: &sharev1alpha1.Share{
+				ObjectMeta: metav1.ObjectMeta{Name: "share1"},
+				Spec: sharev1alpha1.ShareSpec{
+					BackingResources: []sharev1alpha1.BackingResource{
+						{Kind: "ConfigMap", Namespace: "namespace1", Name: "configmap1"},
+						{Kind: "Secret", Namespace: "namespace1", Name: "secret2"},
+					},
+				},
+			},
+			valid:          metav1.ConditionTrue,
+			watched:        metav1.ConditionTrue,
+			found:          metav1.ConditionFalse,
+			expectedReason: "NotFound",
+		},
+		{
+			name: "item path escapes volume",
+			share: &sharev1alpha1.Share{
+				ObjectMeta: metav1.ObjectMeta{Name: "share1"},
+				Spec: sharev1alpha1.ShareSpec{
+					BackingResource: sharev1alpha1.BackingResource{
+						Kind:      "ConfigMap",
+						Namespace: "namespace1",
+						Name:      "configmap1",
+						Items:     []corev1.KeyToPath{{Key: "ca.crt", Path: "/etc/ca.crt"}},
+					},
+				},
+			},
+			valid:          metav1.ConditionFalse,
+			watched:        metav1.ConditionTrue,
+			found:          metav1.ConditionFalse,
+			expectedReason: "InvalidBackingResource",
+		},
+		{
+			name: "selector matches a configmap",
+			share: &sharev1alpha1.Share{
+				ObjectMeta: metav1.ObjectMeta{Name: "share1"},
+				Spec: sharev1alpha1.ShareSpec{
+					BackingResource: sharev1alpha1.BackingResource{Kind: "ConfigMap", Namespace: "namespace1", Selector: bundleSelector},
+				},
+			},
+			valid:   metav1.ConditionTrue,
+			watched: metav1.ConditionTrue,
+			found:   metav1.ConditionTrue,
+		},
+		{
+			name: "selector matches no secret",
+			share: &sharev1alpha1.Share{
+				ObjectMeta: metav1.ObjectMeta{Name: "share1"},
+				Spec: sharev1alpha1.ShareSpec{
+					BackingResource: sharev1alpha1.BackingResource{Kind: "Secret", Namespace: "namespace1", Selector: bundleSelector},
+				},
+			},
+			valid:          metav1.ConditionTrue,
+			watched:        metav1.ConditionTrue,
+			found:          metav1.ConditionFalse,
+			expectedReason: "NoMatch",
+		},
+		{
+			name: "selector along with name",
+			share: &sharev1alpha1.Share{
+				ObjectMeta: metav1.ObjectMeta{Name: "share1"},
+				Spec: sharev1alpha1.ShareSpec{
+					BackingResource: sharev1alpha1.BackingResource{Kind: "ConfigMap", Namespace: "namespace1", Name: "configmap1", Selector: bundleSelector},
+				},
+			},
+			valid:          metav1.ConditionFalse,
+			watched:        metav1.ConditionTrue,
+			found:          metav1.ConditionFalse,
+			expectedReason: "InvalidBackingResource",
+		},
+		{
+			name:    "namespaced share in the same namespace",
+			share:   testNamespacedShare("namespace1", "namespace1"),
+			valid:   metav1.ConditionTrue,
+			watched: metav1.ConditionTrue,
+			found:   metav1.ConditionTrue,
+		},
+		{
+			name:    "namespaced share from an allowed namespace",
+			share:   testNamespacedShare("namespace3", "namespace1"),
+			valid:   metav1.ConditionTrue,
+			watched: metav1.ConditionTrue,
+			found:   metav1.ConditionTrue,
+		},
+		{
+			name:           "namespaced share from a namespace not allowed",
+			share:          testNamespacedShare("namespace4", "namespace1"),
+			valid:          metav1.ConditionFalse,
+			watched:        metav1.ConditionTrue,
+			found:          metav1.ConditionFalse,
+			expectedReason: "InvalidBackingResource",
+		},
+		{
+			name:           "namespaced share of a kind namespaced shares may not use",
+			share:          testNamespacedFieldShare("namespace1", "example.com/v1", "Endpoint", "endpoint1"),
+			valid:          metav1.ConditionFalse,
+			watched:        metav1.ConditionTrue,
+			found:          metav1.ConditionFalse,
+			expectedReason: "InvalidBackingResource",
+		},
+		{
+			name:           "no backing resources",
+			share:          &sharev1alpha1.Share{ObjectMeta: metav1.ObjectMeta{Name: "share1"}},
+			valid:          metav1.ConditionFalse,
+			watched:        metav1.ConditionTrue,
+			found:          metav1.ConditionFalse,
+			expectedReason: "InvalidBackingResource",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conditions := buildShareConditions(test.share, listers)
+			if !meta.IsStatusConditionPresentAndEqual(conditions, sharev1alpha1.ShareConditionValid, test.valid) {
+				t.Errorf("expected Valid %s got %#v", test.valid, conditions)
+			}
+			if !meta.IsStatusConditionPresentAndEqual(conditions, sharev1alpha1.ShareConditionBackingResourceNamespaceWatched, test.watched) {
+				t.Errorf("expected BackingResourceNamespaceWatched %s got %#v", test.watched, conditions)
+			}
+			if !meta.IsStatusConditionPresentAndEqual(conditions, sharev1alpha1.ShareConditionBackingResourceFound, test.found) {
+				t.Errorf("expected BackingResourceFound %s got %#v", test.found, conditions)
+			}
+			found := meta.FindStatusCondition(conditions, sharev1alpha1.ShareConditionBackingResourceFound)
+			if len(test.expectedReason) > 0 && found.Reason != test.expectedReason {
+				t.Errorf("expected BackingResourceFound reason %s got %s", test.expectedReason, found.Reason)
+			}
+		})
+	}
+}
+
+func TestSyncShareStatus(t *testing.T) {
+	share := testShare("ConfigMap", "namespace1", "configmap1")
+	shareClient := fakeshareclientset.NewSimpleClientset(share)
+	c := &Controller{
+		shareClient:  shareClient,
+		listers:      testListers(share),
+		statusWriter: 1,
+	}
+
+	if err := c.syncShareStatus(share.Name); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	updated, err := shareClient.ProjectedresourceV1alpha1().Shares().Get(context.TODO(), share.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	found := meta.FindStatusCondition(updated.Status.Conditions, sharev1alpha1.ShareConditionBackingResourceFound)
+	if found == nil || found.Status != metav1.ConditionFalse || found.ObservedGeneration != share.Generation {
+		t.Fatalf("unexpected BackingResourceFound condition %#v", found)
+	}
+
+	// once the recorded conditions match, no further status updates should be issued
+	c.listers = testListers(updated)
+	shareClient.ClearActions()
+	if err := c.syncShareStatus(share.Name); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(shareClient.Actions()) != 0 {
+		t.Fatalf("unexpected actions %#v", shareClient.Actions())
+	}
+}
+
+func TestSyncShareStatusWithoutLease(t *testing.T) {
+	share := testShare("ConfigMap", "namespace1", "configmap1")
+	namespacedShare := testNamespacedShare("namespace2", "namespace1")
+	shareClient := fakeshareclientset.NewSimpleClientset(share, namespacedShare)
+	c := &Controller{
+		shareClient: shareClient,
+		listers:     testListers(share, namespacedShare),
+	}
+
+	// only the driver instance holding the status lease writes the status of shares
+	if err := c.syncShareStatus(share.Name); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if err := c.syncNamespacedShareStatus(namespacedShare.Namespace, namespacedShare.Name); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(shareClient.Actions()) != 0 {
+		t.Fatalf("unexpected actions %#v", shareClient.Actions())
+	}
+}
+
+func TestSyncNamespacedShareStatus(t *testing.T) {
+	share := testNamespacedShare("namespace2", "namespace1")
+	shareClient := fakeshareclientset.NewSimpleClientset(share)
+	c := &Controller{
+		shareClient:  shareClient,
+		listers:      testListers(share),
+		statusWriter: 1,
+	}
+
+	if err := c.syncNamespacedShareStatus(share.Namespace, share.Name); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	updated, err := shareClient.ProjectedresourceV1alpha1().NamespacedShares(share.Namespace).Get(context.TODO(), share.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	valid := meta.FindStatusCondition(updated.Status.Conditions, sharev1alpha1.ShareConditionValid)
+	if valid == nil || valid.Status != metav1.ConditionFalse || valid.Reason != "NamespaceNotAllowed" {
+		t.Fatalf("unexpected Valid condition %#v", valid)
+	}
+}
+
+func TestSyncStatusOnObjectUpdate(t *testing.T) {
+	share := testShare("ConfigMap", "namespace1", "")
+	share.Spec.BackingResource.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}}
+	objcache.AddShare(share)
+	defer objcache.DelShare(share)
+	unlabeled := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "namespace1", Name: "configmap1"}}
+	labeled := unlabeled.DeepCopy()
+	labeled.Labels = map[string]string{"app": "a"}
+	objcache.UpsertConfigMap(unlabeled)
+	defer objcache.DelConfigMap(labeled)
+
+	shareClient := fakeshareclientset.NewSimpleClientset(share)
+	c := &Controller{
+		shareClient:  shareClient,
+		listers:      testListers(share, labeled),
+		statusWriter: 1,
+	}
+	expectStatusUpdates := func(step string, cm *corev1.ConfigMap, updates int) {
+		if err := c.syncConfigMap(client.Event{Object: cm, Verb: client.UpdateObjectAction}); err != nil {
+			t.Fatalf("%s: unexpected error: %s", step, err.Error())
+		}
+		if len(shareClient.Actions()) != updates {
+			t.Fatalf("%s: expected %d status updates got %#v", step, updates, shareClient.Actions())
+		}
+		shareClient.ClearActions()
+	}
+
+	// the configmap starts matching the selector of the share, which is found
+	expectStatusUpdates("labels added", labeled, 1)
+	updated, err := shareClient.ProjectedresourceV1alpha1().Shares().Get(context.TODO(), share.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	shareClient.ClearActions()
+	found := meta.FindStatusCondition(updated.Status.Conditions, sharev1alpha1.ShareConditionBackingResourceFound)
+	if found == nil || found.Status != metav1.ConditionTrue {
+		t.Fatalf("unexpected BackingResourceFound condition %#v", found)
+	}
+
+	// an update leaving the labels as they are does not recompute the status of the share, still the one the
+	// listers have from before it was found
+	expectStatusUpdates("labels unchanged", labeled, 0)
+
+	// the configmap stops matching the selector of the share, which is no longer found
+	c.listers = testListers(updated, unlabeled)
+	expectStatusUpdates("labels removed", unlabeled, 1)
+}
@@ -12,6 +12,7 @@ import (
 	"fmt"
 
 	projectedresourcev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/generated/clientset/versioned/typed/projectedresource/v1alpha1"
+	projectedresourcev1beta1 "github.com/openshift/csi-driver-projected-resource/pkg/generated/clientset/versioned/typed/projectedresource/v1beta1"
 	discovery "k8s.io/client-go/discovery"
 	rest "k8s.io/client-go/rest"
 	flowcontrol "k8s.io/client-go/util/flowcontrol"
@@ -20,6 +21,7 @@ import (
 type Interface interface {
 	Discovery() discovery.DiscoveryInterface
 	ProjectedresourceV1alpha1() projectedresourcev1alpha1.ProjectedresourceV1alpha1Interface
+	ProjectedresourceV1beta1() projectedresourcev1beta1.ProjectedresourceV1beta1Interface
 }
 
 // Clientset contains the clients for groups. Each group has exactly one
@@ -27,6 +29,7 @@ type Interface interface {
 type Clientset struct {
 	*discovery.DiscoveryClient
 	projectedresourceV1alpha1 *projectedresourcev1alpha1.ProjectedresourceV1alpha1Client
+	projectedresourceV1beta1  *projectedresourcev1beta1.ProjectedresourceV1beta1Client
 }
 
 // ProjectedresourceV1alpha1 retrieves the ProjectedresourceV1alpha1Client
@@ -34,6 +37,11 @@ func (c *Clientset) ProjectedresourceV1alpha1() projectedresourcev1alpha1.Projec
 	return c.projectedresourceV1alpha1
 }
 
+// ProjectedresourceV1beta1 retrieves the ProjectedresourceV1beta1Client
+func (c *Clientset) ProjectedresourceV1beta1() projectedresourcev1beta1.ProjectedresourceV1beta1Interface {
+	return c.projectedresourceV1beta1
+}
+
 // Discovery retrieves the DiscoveryClient
 func (c *Clientset) Discovery() discovery.DiscoveryInterface {
 	if c == nil {
@@ -59,6 +67,10 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 	if err != nil {
 		return nil, err
 	}
+	cs.projectedresourceV1beta1, err = projectedresourcev1beta1.NewForConfig(&configShallowCopy)
+	if err != nil {
+		return nil, err
+	}
 
 	cs.DiscoveryClient, err = discovery.NewDiscoveryClientForConfig(&configShallowCopy)
 	if err != nil {
@@ -72,6 +84,7 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 func NewForConfigOrDie(c *rest.Config) *Clientset {
 	var cs Clientset
 	cs.projectedresourceV1alpha1 = projectedresourcev1alpha1.NewForConfigOrDie(c)
+	cs.projectedresourceV1beta1 = projectedresourcev1beta1.NewForConfigOrDie(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClientForConfigOrDie(c)
 	return &cs
@@ -81,6 +94,7 @@ func NewForConfigOrDie(c *rest.Config) *Clientset {
 func New(c rest.Interface) *Clientset {
 	var cs Clientset
 	cs.projectedresourceV1alpha1 = projectedresourcev1alpha1.New(c)
+	cs.projectedresourceV1beta1 = projectedresourcev1beta1.New(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClient(c)
 	return &cs
@@ -12,6 +12,8 @@ import (
 	clientset "github.com/openshift/csi-driver-projected-resource/pkg/generated/clientset/versioned"
 	projectedresourcev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/generated/clientset/versioned/typed/projectedresource/v1alpha1"
 	fakeprojectedresourcev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/generated/clientset/versioned/typed/projectedresource/v1alpha1/fake"
+	projectedresourcev1beta1 "github.com/openshift/csi-driver-projected-resource/pkg/generated/clientset/versioned/typed/projectedresource/v1beta1"
+	fakeprojectedresourcev1beta1 "github.com/openshift/csi-driver-projected-resource/pkg/generated/clientset/versioned/typed/projectedresource/v1beta1/fake"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/discovery"
@@ -70,3 +72,8 @@ var _ clientset.Interface = &Clientset{}
 func (c *Clientset) ProjectedresourceV1alpha1() projectedresourcev1alpha1.ProjectedresourceV1alpha1Interface {
 	return &fakeprojectedresourcev1alpha1.FakeProjectedresourceV1alpha1{Fake: &c.Fake}
 }
+
+// ProjectedresourceV1beta1 retrieves the ProjectedresourceV1beta1Client
+func (c *Clientset) ProjectedresourceV1beta1() projectedresourcev1beta1.ProjectedresourceV1beta1Interface {
+	return &fakeprojectedresourcev1beta1.FakeProjectedresourceV1beta1{Fake: &c.Fake}
+}
@@ -0,0 +1,132 @@
+/*
+Copyright The OpenShift authors.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	v1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeNamespacedShares implements NamespacedShareInterface
+type FakeNamespacedShares struct {
+	Fake *FakeProjectedresourceV1alpha1
+	ns   string
+}
+
+var namespacedsharesResource = schema.GroupVersionResource{Group: "projectedresource.storage.openshift.io", Version: "v1alpha1", Resource: "namespacedshares"}
+
+var namespacedsharesKind = schema.GroupVersionKind{Group: "projectedresource.storage.openshift.io", Version: "v1alpha1", Kind: "NamespacedShare"}
+
+// Get takes name of the namespacedShare, and returns the corresponding namespacedShare object, and an error if there is any.
+func (c *FakeNamespacedShares) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.NamespacedShare, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(namespacedsharesResource, c.ns, name), &v1alpha1.NamespacedShare{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.NamespacedShare), err
+}
+
+// List takes label and field selectors, and returns the list of NamespacedShares that match those selectors.
+func (c *FakeNamespacedShares) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.NamespacedShareList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(namespacedsharesResource, namespacedsharesKind, c.ns, opts), &v1alpha1.NamespacedShareList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.NamespacedShareList{ListMeta: obj.(*v1alpha1.NamespacedShareList).ListMeta}
+	for _, item := range obj.(*v1alpha1.NamespacedShareList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested namespacedShares.
+func (c *FakeNamespacedShares) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(namespacedsharesResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a namespacedShare and creates it.  Returns the server's representation of the namespacedShare, and an error, if there is any.
+func (c *FakeNamespacedShares) Create(ctx context.Context, namespacedShare *v1alpha1.NamespacedShare, opts v1.CreateOptions) (result *v1alpha1.NamespacedShare, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(namespacedsharesResource, c.ns, namespacedShare), &v1alpha1.NamespacedShare{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.NamespacedShare), err
+}
+
+// Update takes the representation of a namespacedShare and updates it. Returns the server's representation of the namespacedShare, and an error, if there is any.
+func (c *FakeNamespacedShares) Update(ctx context.Context, namespacedShare *v1alpha1.NamespacedShare, opts v1.UpdateOptions) (result *v1alpha1.NamespacedShare, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(namespacedsharesResource, c.ns, namespacedShare), &v1alpha1.NamespacedShare{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.NamespacedShare), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeNamespacedShares) UpdateStatus(ctx context.Context, namespacedShare *v1alpha1.NamespacedShare, opts v1.UpdateOptions) (*v1alpha1.NamespacedShare, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(namespacedsharesResource, "status", c.ns, namespacedShare), &v1alpha1.NamespacedShare{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.NamespacedShare), err
+}
+
+// Delete takes name of the namespacedShare and deletes it. Returns an error if one occurs.
+func (c *FakeNamespacedShares) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(namespacedsharesResource, c.ns, name), &v1alpha1.NamespacedShare{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeNamespacedShares) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(namespacedsharesResource, c.ns, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.NamespacedShareList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched namespacedShare.
+func (c *FakeNamespacedShares) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.NamespacedShare, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(namespacedsharesResource, c.ns, name, pt, data, subresources...), &v1alpha1.NamespacedShare{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.NamespacedShare), err
+}
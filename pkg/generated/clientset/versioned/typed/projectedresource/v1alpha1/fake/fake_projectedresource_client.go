@@ -18,6 +18,10 @@ type FakeProjectedresourceV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeProjectedresourceV1alpha1) NamespacedShares(namespace string) v1alpha1.NamespacedShareInterface {
+	return &FakeNamespacedShares{c, namespace}
+}
+
 func (c *FakeProjectedresourceV1alpha1) Shares() v1alpha1.ShareInterface {
 	return &FakeShares{c}
 }
@@ -0,0 +1,185 @@
+/*
+Copyright The OpenShift authors.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	"time"
+
+	v1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+	scheme "github.com/openshift/csi-driver-projected-resource/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// NamespacedSharesGetter has a method to return a NamespacedShareInterface.
+// A group's client should implement this interface.
+type NamespacedSharesGetter interface {
+	NamespacedShares(namespace string) NamespacedShareInterface
+}
+
+// NamespacedShareInterface has methods to work with NamespacedShare resources.
+type NamespacedShareInterface interface {
+	Create(ctx context.Context, namespacedShare *v1alpha1.NamespacedShare, opts v1.CreateOptions) (*v1alpha1.NamespacedShare, error)
+	Update(ctx context.Context, namespacedShare *v1alpha1.NamespacedShare, opts v1.UpdateOptions) (*v1alpha1.NamespacedShare, error)
+	UpdateStatus(ctx context.Context, namespacedShare *v1alpha1.NamespacedShare, opts v1.UpdateOptions) (*v1alpha1.NamespacedShare, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.NamespacedShare, error)
+	List(ctx context.Context, opts v1.ListOptions) (*v1alpha1.NamespacedShareList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.NamespacedShare, err error)
+	NamespacedShareExpansion
+}
+
+// namespacedShares implements NamespacedShareInterface
+type namespacedShares struct {
+	client rest.Interface
+	ns     string
+}
+
+// newNamespacedShares returns a NamespacedShares
+func newNamespacedShares(c *ProjectedresourceV1alpha1Client, namespace string) *namespacedShares {
+	return &namespacedShares{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the namespacedShare, and returns the corresponding namespacedShare object, and an error if there is any.
+func (c *namespacedShares) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.NamespacedShare, err error) {
+	result = &v1alpha1.NamespacedShare{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("namespacedshares").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of NamespacedShares that match those selectors.
+func (c *namespacedShares) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.NamespacedShareList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.NamespacedShareList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("namespacedshares").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested namespacedShares.
+func (c *namespacedShares) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("namespacedshares").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a namespacedShare and creates it.  Returns the server's representation of the namespacedShare, and an error, if there is any.
+func (c *namespacedShares) Create(ctx context.Context, namespacedShare *v1alpha1.NamespacedShare, opts v1.CreateOptions) (result *v1alpha1.NamespacedShare, err error) {
+	result = &v1alpha1.NamespacedShare{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("namespacedshares").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(namespacedShare).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a namespacedShare and updates it. Returns the server's representation of the namespacedShare, and an error, if there is any.
+func (c *namespacedShares) Update(ctx context.Context, namespacedShare *v1alpha1.NamespacedShare, opts v1.UpdateOptions) (result *v1alpha1.NamespacedShare, err error) {
+	result = &v1alpha1.NamespacedShare{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("namespacedshares").
+		Name(namespacedShare.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(namespacedShare).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *namespacedShares) UpdateStatus(ctx context.Context, namespacedShare *v1alpha1.NamespacedShare, opts v1.UpdateOptions) (result *v1alpha1.NamespacedShare, err error) {
+	result = &v1alpha1.NamespacedShare{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("namespacedshares").
+		Name(namespacedShare.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(namespacedShare).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the namespacedShare and deletes it. Returns an error if one occurs.
+func (c *namespacedShares) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("namespacedshares").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *namespacedShares) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("namespacedshares").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched namespacedShare.
+func (c *namespacedShares) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.NamespacedShare, err error) {
+	result = &v1alpha1.NamespacedShare{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("namespacedshares").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
@@ -16,6 +16,7 @@ import (
 
 type ProjectedresourceV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	NamespacedSharesGetter
 	SharesGetter
 }
 
@@ -24,6 +25,10 @@ type ProjectedresourceV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *ProjectedresourceV1alpha1Client) NamespacedShares(namespace string) NamespacedShareInterface {
+	return newNamespacedShares(c, namespace)
+}
+
 func (c *ProjectedresourceV1alpha1Client) Shares() ShareInterface {
 	return newShares(c)
 }
@@ -0,0 +1,34 @@
+/*
+Copyright The OpenShift authors.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1beta1 "github.com/openshift/csi-driver-projected-resource/pkg/generated/clientset/versioned/typed/projectedresource/v1beta1"
+	rest "k8s.io/client-go/rest"
+	testing "k8s.io/client-go/testing"
+)
+
+type FakeProjectedresourceV1beta1 struct {
+	*testing.Fake
+}
+
+func (c *FakeProjectedresourceV1beta1) NamespacedShares(namespace string) v1beta1.NamespacedShareInterface {
+	return &FakeNamespacedShares{c, namespace}
+}
+
+func (c *FakeProjectedresourceV1beta1) Shares() v1beta1.ShareInterface {
+	return &FakeShares{c}
+}
+
+// RESTClient returns a RESTClient that is used to communicate
+// with API server by this client implementation.
+func (c *FakeProjectedresourceV1beta1) RESTClient() rest.Interface {
+	var ret *rest.RESTClient
+	return ret
+}
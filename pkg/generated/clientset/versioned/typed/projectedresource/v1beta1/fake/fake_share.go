@@ -0,0 +1,123 @@
+/*
+Copyright The OpenShift authors.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	v1beta1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeShares implements ShareInterface
+type FakeShares struct {
+	Fake *FakeProjectedresourceV1beta1
+}
+
+var sharesResource = schema.GroupVersionResource{Group: "projectedresource.storage.openshift.io", Version: "v1beta1", Resource: "shares"}
+
+var sharesKind = schema.GroupVersionKind{Group: "projectedresource.storage.openshift.io", Version: "v1beta1", Kind: "Share"}
+
+// Get takes name of the share, and returns the corresponding share object, and an error if there is any.
+func (c *FakeShares) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1beta1.Share, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootGetAction(sharesResource, name), &v1beta1.Share{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.Share), err
+}
+
+// List takes label and field selectors, and returns the list of Shares that match those selectors.
+func (c *FakeShares) List(ctx context.Context, opts v1.ListOptions) (result *v1beta1.ShareList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootListAction(sharesResource, sharesKind, opts), &v1beta1.ShareList{})
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1beta1.ShareList{ListMeta: obj.(*v1beta1.ShareList).ListMeta}
+	for _, item := range obj.(*v1beta1.ShareList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested shares.
+func (c *FakeShares) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewRootWatchAction(sharesResource, opts))
+}
+
+// Create takes the representation of a share and creates it.  Returns the server's representation of the share, and an error, if there is any.
+func (c *FakeShares) Create(ctx context.Context, share *v1beta1.Share, opts v1.CreateOptions) (result *v1beta1.Share, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootCreateAction(sharesResource, share), &v1beta1.Share{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.Share), err
+}
+
+// Update takes the representation of a share and updates it. Returns the server's representation of the share, and an error, if there is any.
+func (c *FakeShares) Update(ctx context.Context, share *v1beta1.Share, opts v1.UpdateOptions) (result *v1beta1.Share, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateAction(sharesResource, share), &v1beta1.Share{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.Share), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeShares) UpdateStatus(ctx context.Context, share *v1beta1.Share, opts v1.UpdateOptions) (*v1beta1.Share, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateSubresourceAction(sharesResource, "status", share), &v1beta1.Share{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.Share), err
+}
+
+// Delete takes name of the share and deletes it. Returns an error if one occurs.
+func (c *FakeShares) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewRootDeleteAction(sharesResource, name), &v1beta1.Share{})
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeShares) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewRootDeleteCollectionAction(sharesResource, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1beta1.ShareList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched share.
+func (c *FakeShares) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1beta1.Share, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(sharesResource, name, pt, data, subresources...), &v1beta1.Share{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.Share), err
+}
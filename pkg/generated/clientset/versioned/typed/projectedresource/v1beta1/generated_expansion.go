@@ -0,0 +1,13 @@
+/*
+Copyright The OpenShift authors.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1beta1
+
+type NamespacedShareExpansion interface{}
+
+type ShareExpansion interface{}
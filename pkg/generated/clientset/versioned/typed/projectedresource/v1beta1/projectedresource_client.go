@@ -0,0 +1,84 @@
+/*
+Copyright The OpenShift authors.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	v1beta1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1beta1"
+	"github.com/openshift/csi-driver-projected-resource/pkg/generated/clientset/versioned/scheme"
+	rest "k8s.io/client-go/rest"
+)
+
+type ProjectedresourceV1beta1Interface interface {
+	RESTClient() rest.Interface
+	NamespacedSharesGetter
+	SharesGetter
+}
+
+// ProjectedresourceV1beta1Client is used to interact with features provided by the projectedresource.storage.openshift.io group.
+type ProjectedresourceV1beta1Client struct {
+	restClient rest.Interface
+}
+
+func (c *ProjectedresourceV1beta1Client) NamespacedShares(namespace string) NamespacedShareInterface {
+	return newNamespacedShares(c, namespace)
+}
+
+func (c *ProjectedresourceV1beta1Client) Shares() ShareInterface {
+	return newShares(c)
+}
+
+// NewForConfig creates a new ProjectedresourceV1beta1Client for the given config.
+func NewForConfig(c *rest.Config) (*ProjectedresourceV1beta1Client, error) {
+	config := *c
+	if err := setConfigDefaults(&config); err != nil {
+		return nil, err
+	}
+	client, err := rest.RESTClientFor(&config)
+	if err != nil {
+		return nil, err
+	}
+	return &ProjectedresourceV1beta1Client{client}, nil
+}
+
+// NewForConfigOrDie creates a new ProjectedresourceV1beta1Client for the given config and
+// panics if there is an error in the config.
+func NewForConfigOrDie(c *rest.Config) *ProjectedresourceV1beta1Client {
+	client, err := NewForConfig(c)
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// New creates a new ProjectedresourceV1beta1Client for the given RESTClient.
+func New(c rest.Interface) *ProjectedresourceV1beta1Client {
+	return &ProjectedresourceV1beta1Client{c}
+}
+
+func setConfigDefaults(config *rest.Config) error {
+	gv := v1beta1.SchemeGroupVersion
+	config.GroupVersion = &gv
+	config.APIPath = "/apis"
+	config.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+
+	if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+
+	return nil
+}
+
+// RESTClient returns a RESTClient that is used to communicate
+// with API server by this client implementation.
+func (c *ProjectedresourceV1beta1Client) RESTClient() rest.Interface {
+	if c == nil {
+		return nil
+	}
+	return c.restClient
+}
@@ -12,6 +12,7 @@ import (
 	"fmt"
 
 	v1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+	v1beta1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1beta1"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	cache "k8s.io/client-go/tools/cache"
 )
@@ -43,9 +44,17 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=projectedresource.storage.openshift.io, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("namespacedshares"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Projectedresource().V1alpha1().NamespacedShares().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("shares"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Projectedresource().V1alpha1().Shares().Informer()}, nil
 
+		// Group=projectedresource.storage.openshift.io, Version=v1beta1
+	case v1beta1.SchemeGroupVersion.WithResource("namespacedshares"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Projectedresource().V1beta1().NamespacedShares().Informer()}, nil
+	case v1beta1.SchemeGroupVersion.WithResource("shares"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Projectedresource().V1beta1().Shares().Informer()}, nil
+
 	}
 
 	return nil, fmt.Errorf("no informer found for %v", resource)
@@ -14,6 +14,8 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// NamespacedShares returns a NamespacedShareInformer.
+	NamespacedShares() NamespacedShareInformer
 	// Shares returns a ShareInformer.
 	Shares() ShareInformer
 }
@@ -29,6 +31,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// NamespacedShares returns a NamespacedShareInformer.
+func (v *version) NamespacedShares() NamespacedShareInformer {
+	return &namespacedShareInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // Shares returns a ShareInformer.
 func (v *version) Shares() ShareInformer {
 	return &shareInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
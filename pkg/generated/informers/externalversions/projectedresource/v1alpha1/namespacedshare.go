@@ -0,0 +1,80 @@
+/*
+Copyright The OpenShift authors.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	time "time"
+
+	projectedresourcev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+	versioned "github.com/openshift/csi-driver-projected-resource/pkg/generated/clientset/versioned"
+	internalinterfaces "github.com/openshift/csi-driver-projected-resource/pkg/generated/informers/externalversions/internalinterfaces"
+	v1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/generated/listers/projectedresource/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// NamespacedShareInformer provides access to a shared informer and lister for
+// NamespacedShares.
+type NamespacedShareInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1alpha1.NamespacedShareLister
+}
+
+type namespacedShareInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewNamespacedShareInformer constructs a new informer for NamespacedShare type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewNamespacedShareInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredNamespacedShareInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredNamespacedShareInformer constructs a new informer for NamespacedShare type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredNamespacedShareInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.ProjectedresourceV1alpha1().NamespacedShares(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.ProjectedresourceV1alpha1().NamespacedShares(namespace).Watch(context.TODO(), options)
+			},
+		},
+		&projectedresourcev1alpha1.NamespacedShare{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *namespacedShareInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredNamespacedShareInformer(client, f.namespace, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *namespacedShareInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&projectedresourcev1alpha1.NamespacedShare{}, f.defaultInformer)
+}
+
+func (f *namespacedShareInformer) Lister() v1alpha1.NamespacedShareLister {
+	return v1alpha1.NewNamespacedShareLister(f.Informer().GetIndexer())
+}
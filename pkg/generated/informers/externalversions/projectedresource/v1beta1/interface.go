@@ -0,0 +1,42 @@
+/*
+Copyright The OpenShift authors.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	internalinterfaces "github.com/openshift/csi-driver-projected-resource/pkg/generated/informers/externalversions/internalinterfaces"
+)
+
+// Interface provides access to all the informers in this group version.
+type Interface interface {
+	// NamespacedShares returns a NamespacedShareInformer.
+	NamespacedShares() NamespacedShareInformer
+	// Shares returns a ShareInformer.
+	Shares() ShareInformer
+}
+
+type version struct {
+	factory          internalinterfaces.SharedInformerFactory
+	namespace        string
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+}
+
+// New returns a new Interface.
+func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakListOptions internalinterfaces.TweakListOptionsFunc) Interface {
+	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
+}
+
+// NamespacedShares returns a NamespacedShareInformer.
+func (v *version) NamespacedShares() NamespacedShareInformer {
+	return &namespacedShareInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// Shares returns a ShareInformer.
+func (v *version) Shares() ShareInformer {
+	return &shareInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright The OpenShift authors.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	"context"
+	time "time"
+
+	projectedresourcev1beta1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1beta1"
+	versioned "github.com/openshift/csi-driver-projected-resource/pkg/generated/clientset/versioned"
+	internalinterfaces "github.com/openshift/csi-driver-projected-resource/pkg/generated/informers/externalversions/internalinterfaces"
+	v1beta1 "github.com/openshift/csi-driver-projected-resource/pkg/generated/listers/projectedresource/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// ShareInformer provides access to a shared informer and lister for
+// Shares.
+type ShareInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1beta1.ShareLister
+}
+
+type shareInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+}
+
+// NewShareInformer constructs a new informer for Share type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewShareInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredShareInformer(client, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredShareInformer constructs a new informer for Share type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredShareInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.ProjectedresourceV1beta1().Shares().List(context.TODO(), options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.ProjectedresourceV1beta1().Shares().Watch(context.TODO(), options)
+			},
+		},
+		&projectedresourcev1beta1.Share{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *shareInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredShareInformer(client, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *shareInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&projectedresourcev1beta1.Share{}, f.defaultInformer)
+}
+
+func (f *shareInformer) Lister() v1beta1.ShareLister {
+	return v1beta1.NewShareLister(f.Informer().GetIndexer())
+}
@@ -8,6 +8,14 @@ SPDX-License-Identifier: Apache-2.0
 
 package v1alpha1
 
+// NamespacedShareListerExpansion allows custom methods to be added to
+// NamespacedShareLister.
+type NamespacedShareListerExpansion interface{}
+
+// NamespacedShareNamespaceListerExpansion allows custom methods to be added to
+// NamespacedShareNamespaceLister.
+type NamespacedShareNamespaceListerExpansion interface{}
+
 // ShareListerExpansion allows custom methods to be added to
 // ShareLister.
 type ShareListerExpansion interface{}
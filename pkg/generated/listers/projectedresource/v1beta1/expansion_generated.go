@@ -0,0 +1,21 @@
+/*
+Copyright The OpenShift authors.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1beta1
+
+// NamespacedShareListerExpansion allows custom methods to be added to
+// NamespacedShareLister.
+type NamespacedShareListerExpansion interface{}
+
+// NamespacedShareNamespaceListerExpansion allows custom methods to be added to
+// NamespacedShareNamespaceLister.
+type NamespacedShareNamespaceListerExpansion interface{}
+
+// ShareListerExpansion allows custom methods to be added to
+// ShareLister.
+type ShareListerExpansion interface{}
@@ -0,0 +1,89 @@
+/*
+Copyright The OpenShift authors.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	v1beta1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NamespacedShareLister helps list NamespacedShares.
+// All objects returned here must be treated as read-only.
+type NamespacedShareLister interface {
+	// List lists all NamespacedShares in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1beta1.NamespacedShare, err error)
+	// NamespacedShares returns an object that can list and get NamespacedShares.
+	NamespacedShares(namespace string) NamespacedShareNamespaceLister
+	NamespacedShareListerExpansion
+}
+
+// namespacedShareLister implements the NamespacedShareLister interface.
+type namespacedShareLister struct {
+	indexer cache.Indexer
+}
+
+// NewNamespacedShareLister returns a new NamespacedShareLister.
+func NewNamespacedShareLister(indexer cache.Indexer) NamespacedShareLister {
+	return &namespacedShareLister{indexer: indexer}
+}
+
+// List lists all NamespacedShares in the indexer.
+func (s *namespacedShareLister) List(selector labels.Selector) (ret []*v1beta1.NamespacedShare, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1beta1.NamespacedShare))
+	})
+	return ret, err
+}
+
+// NamespacedShares returns an object that can list and get NamespacedShares.
+func (s *namespacedShareLister) NamespacedShares(namespace string) NamespacedShareNamespaceLister {
+	return namespacedShareNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// NamespacedShareNamespaceLister helps list and get NamespacedShares.
+// All objects returned here must be treated as read-only.
+type NamespacedShareNamespaceLister interface {
+	// List lists all NamespacedShares in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1beta1.NamespacedShare, err error)
+	// Get retrieves the NamespacedShare from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1beta1.NamespacedShare, error)
+	NamespacedShareNamespaceListerExpansion
+}
+
+// namespacedShareNamespaceLister implements the NamespacedShareNamespaceLister
+// interface.
+type namespacedShareNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all NamespacedShares in the indexer for a given namespace.
+func (s namespacedShareNamespaceLister) List(selector labels.Selector) (ret []*v1beta1.NamespacedShare, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1beta1.NamespacedShare))
+	})
+	return ret, err
+}
+
+// Get retrieves the NamespacedShare from the indexer for a given namespace and name.
+func (s namespacedShareNamespaceLister) Get(name string) (*v1beta1.NamespacedShare, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1beta1.Resource("namespacedshare"), name)
+	}
+	return obj.(*v1beta1.NamespacedShare), nil
+}
@@ -0,0 +1,58 @@
+/*
+Copyright The OpenShift authors.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	v1beta1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ShareLister helps list Shares.
+// All objects returned here must be treated as read-only.
+type ShareLister interface {
+	// List lists all Shares in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1beta1.Share, err error)
+	// Get retrieves the Share from the index for a given name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1beta1.Share, error)
+	ShareListerExpansion
+}
+
+// shareLister implements the ShareLister interface.
+type shareLister struct {
+	indexer cache.Indexer
+}
+
+// NewShareLister returns a new ShareLister.
+func NewShareLister(indexer cache.Indexer) ShareLister {
+	return &shareLister{indexer: indexer}
+}
+
+// List lists all Shares in the indexer.
+func (s *shareLister) List(selector labels.Selector) (ret []*v1beta1.Share, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1beta1.Share))
+	})
+	return ret, err
+}
+
+// Get retrieves the Share from the index for a given name.
+func (s *shareLister) Get(name string) (*v1beta1.Share, error) {
+	obj, exists, err := s.indexer.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1beta1.Resource("share"), name)
+	}
+	return obj.(*v1beta1.Share), nil
+}
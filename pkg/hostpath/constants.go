@@ -3,6 +3,8 @@ package hostpath
 const (
 	deviceID           = "deviceID"
 	maxStorageCapacity = tib
+	// minVolumeSize is the smallest size limit derived for a volume from the size of its data
+	minVolumeSize = mib
 )
 
 type accessType int
@@ -0,0 +1,214 @@
+package hostpath
+
+import (
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/csi-driver-projected-resource/pkg/client"
+)
+
+const (
+	// PVCNameKey and PVCNamespaceKey are the parameters the external-provisioner adds to CreateVolume requests
+	// when run with --extra-create-metadata
+	PVCNameKey      = "csi.storage.k8s.io/pvc/name"
+	PVCNamespaceKey = "csi.storage.k8s.io/pvc/namespace"
+
+	// ShareAnnotation and NamespacedShareAnnotation set on a PVC pick the share its volume projects, over the
+	// 'share' and 'namespacedShare' parameters of its StorageClass
+	ShareAnnotation           = "projectedresource.storage.openshift.io/share"
+	NamespacedShareAnnotation = "projectedresource.storage.openshift.io/namespacedShare"
+)
+
+// volumeAttributeKeys are the StorageClass parameters passed on to the volume attributes of the persistent
+// volumes provisioned for it
+var volumeAttributeKeys = []string{
+	ProjectedResourceShareKey,
+	ProjectedResourceNamespacedShareKey,
+	ProjectedResourceUpdatePolicyKey,
+	ProjectedResourceDefaultModeKey,
+	ProjectedResourceSecretDefaultModeKey,
+	ProjectedResourceConfigMapDefaultModeKey,
+	ProjectedResourceSizeLimitKey,
+}
+
+// controllerServer provisions persistent volumes. It has nothing to allocate: a persistent volume only records
+// the share it projects in its volume attributes, and its data is written for each pod when it is published on
+// a node, just as for an ephemeral volume
+type controllerServer struct {
+	caps []*csi.ControllerServiceCapability
+}
+
+func NewControllerServer() *controllerServer {
+	return &controllerServer{
+		caps: []*csi.ControllerServiceCapability{
+			{
+				Type: &csi.ControllerServiceCapability_Rpc{
+					Rpc: &csi.ControllerServiceCapability_RPC{
+						Type: csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+					},
+				},
+			},
+		},
+	}
+}
+
+// validateVolumeCapabilities checks the capabilities of a persistent volume: the volume of each pod is a tmpfs
+// of its own, so it cannot be a block device, nor be written to by several pods
+func validateVolumeCapabilities(caps []*csi.VolumeCapability) error {
+	if len(caps) == 0 {
+		return status.Error(codes.InvalidArgument, "Volume capabilities missing in request")
+	}
+	for _, c := range caps {
+		if c.GetMount() == nil {
+			return status.Error(codes.InvalidArgument, "only support mount access type")
+		}
+		if c.GetAccessMode().GetMode() == csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER {
+			return status.Error(codes.InvalidArgument,
+				"each pod has its own copy of the data of the volume, it cannot be written to by several pods")
+		}
+	}
+	return nil
+}
+
+// volumeAttributes returns the volume attributes of a persistent volume, from the parameters of its
+// StorageClass and the annotations of its PVC
+func volumeAttributes(params map[string]string) (map[string]string, error) {
+	attributes := map[string]string{}
+	for _, key := range volumeAttributeKeys {
+		if value, ok := params[key]; ok {
+			attributes[key] = value
+		}
+	}
+	pvcNamespace, pvcName := params[PVCNamespaceKey], params[PVCNameKey]
+	if len(pvcNamespace) > 0 && len(pvcName) > 0 {
+		pvc, err := client.GetPersistentVolumeClaim(pvcNamespace, pvcName)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "could not get the PVC %s:%s: %s", pvcNamespace, pvcName, err.Error())
+		}
+		shareAnnotation, namespacedShareAnnotation := pvc.Annotations[ShareAnnotation], pvc.Annotations[NamespacedShareAnnotation]
+		switch {
+		case len(shareAnnotation) > 0 && len(namespacedShareAnnotation) > 0:
+			return nil, status.Errorf(codes.InvalidArgument,
+				"the PVC %s:%s annotations '%s' and '%s' cannot both be set", pvcNamespace, pvcName, ShareAnnotation, NamespacedShareAnnotation)
+		// a share annotation replaces the share reference of the StorageClass, whichever kind of share it is
+		case len(shareAnnotation) > 0:
+			delete(attributes, ProjectedResourceNamespacedShareKey)
+			attributes[ProjectedResourceShareKey] = shareAnnotation
+		case len(namespacedShareAnnotation) > 0:
+			delete(attributes, ProjectedResourceShareKey)
+			attributes[ProjectedResourceNamespacedShareKey] = namespacedShareAnnotation
+		}
+	}
+
+	shareName := strings.TrimSpace(attributes[ProjectedResourceShareKey])
+	namespacedShareName := strings.TrimSpace(attributes[ProjectedResourceNamespacedShareKey])
+	switch {
+	case len(shareName) > 0 && len(namespacedShareName) > 0:
+		return nil, status.Errorf(codes.InvalidArgument,
+			"the StorageClass parameters 'share' and 'namespacedShare' cannot both be set")
+	case len(shareName) == 0 && len(namespacedShareName) == 0:
+		return nil, status.Errorf(codes.InvalidArgument,
+			"the volume is missing a share, from the StorageClass parameter 'share' or 'namespacedShare', or the PVC annotation '%s' or '%s'",
+			ShareAnnotation, NamespacedShareAnnotation)
+	}
+	return attributes, nil
+}
+
+func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
+	if len(req.GetName()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Name missing in request")
+	}
+	if err := validateVolumeCapabilities(req.GetVolumeCapabilities()); err != nil {
+		return nil, err
+	}
+	if req.GetVolumeContentSource() != nil {
+		return nil, status.Error(codes.InvalidArgument, "volume content sources are not supported")
+	}
+	attributes, err := volumeAttributes(req.GetParameters())
+	if err != nil {
+		return nil, err
+	}
+
+	// the name of the request, unique to its PVC, is the volume ID, so retries of the request get the same volume
+	klog.V(4).Infof("CreateVolume %s with attributes %v", req.GetName(), attributes)
+	return &csi.CreateVolumeResponse{
+		Volume: &csi.Volume{
+			VolumeId:      req.GetName(),
+			CapacityBytes: req.GetCapacityRange().GetRequiredBytes(),
+			VolumeContext: attributes,
+		},
+	}, nil
+}
+
+func (cs *controllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
+	if len(req.GetVolumeId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume ID missing in request")
+	}
+	// the data of the volume is removed from the nodes when it is unpublished, there is nothing left to delete
+	klog.V(4).Infof("DeleteVolume %s", req.GetVolumeId())
+	return &csi.DeleteVolumeResponse{}, nil
+}
+
+func (cs *controllerServer) ValidateVolumeCapabilities(ctx context.Context, req *csi.ValidateVolumeCapabilitiesRequest) (*csi.ValidateVolumeCapabilitiesResponse, error) {
+	if len(req.GetVolumeId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume ID missing in request")
+	}
+	if err := validateVolumeCapabilities(req.GetVolumeCapabilities()); err != nil {
+		return &csi.ValidateVolumeCapabilitiesResponse{Message: err.Error()}, nil
+	}
+	return &csi.ValidateVolumeCapabilitiesResponse{
+		Confirmed: &csi.ValidateVolumeCapabilitiesResponse_Confirmed{
+			VolumeContext:      req.GetVolumeContext(),
+			VolumeCapabilities: req.GetVolumeCapabilities(),
+			Parameters:         req.GetParameters(),
+		},
+	}, nil
+}
+
+func (cs *controllerServer) ControllerGetCapabilities(ctx context.Context, req *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
+	return &csi.ControllerGetCapabilitiesResponse{
+		Capabilities: cs.caps,
+	}, nil
+}
+
+func (cs *controllerServer) ControllerPublishVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+func (cs *controllerServer) ControllerUnpublishVolume(ctx context.Context, req *csi.ControllerUnpublishVolumeRequest) (*csi.ControllerUnpublishVolumeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+func (cs *controllerServer) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+func (cs *controllerServer) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+func (cs *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+func (cs *controllerServer) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+func (cs *controllerServer) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+func (cs *controllerServer) ControllerExpandVolume(ctx context.Context, req *csi.ControllerExpandVolumeRequest) (*csi.ControllerExpandVolumeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+func (cs *controllerServer) ControllerGetVolume(ctx context.Context, req *csi.ControllerGetVolumeRequest) (*csi.ControllerGetVolumeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
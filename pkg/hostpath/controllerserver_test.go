@@ -0,0 +1,154 @@
+package hostpath
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/openshift/csi-driver-projected-resource/pkg/client"
+	"golang.org/x/net/context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCreateVolume(t *testing.T) {
+	mountCapabilities := []*csi.VolumeCapability{
+		{
+			AccessType: &csi.VolumeCapability_Mount{
+				Mount: &csi.VolumeCapability_MountVolume{},
+			},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY},
+		},
+	}
+	pvcParameters := map[string]string{
+		ProjectedResourceShareKey: "share1",
+		PVCNamespaceKey:           "namespace1",
+		PVCNameKey:                "pvc1",
+	}
+	tests := []struct {
+		name               string
+		req                csi.CreateVolumeRequest
+		pvcAnnotations     map[string]string
+		expectedMsg        string
+		expectedAttributes map[string]string
+	}{
+		{
+			name:        "name is empty",
+			req:         csi.CreateVolumeRequest{},
+			expectedMsg: "Name missing in request",
+		},
+		{
+			name: "block access",
+			req: csi.CreateVolumeRequest{
+				Name: "pvc-1",
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{AccessType: &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}}},
+				},
+			},
+			expectedMsg: "only support mount access type",
+		},
+		{
+			name: "multi writer access",
+			req: csi.CreateVolumeRequest{
+				Name: "pvc-1",
+				VolumeCapabilities: []*csi.VolumeCapability{
+					{
+						AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+						AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+					},
+				},
+			},
+			expectedMsg: "cannot be written to by several pods",
+		},
+		{
+			name: "missing share",
+			req: csi.CreateVolumeRequest{
+				Name:               "pvc-1",
+				VolumeCapabilities: mountCapabilities,
+				Parameters:         map[string]string{ProjectedResourceUpdatePolicyKey: "OnPodRestart"},
+			},
+			expectedMsg: "the volume is missing a share",
+		},
+		{
+			name: "share and namespaced share both set",
+			req: csi.CreateVolumeRequest{
+				Name:               "pvc-1",
+				VolumeCapabilities: mountCapabilities,
+				Parameters: map[string]string{
+					ProjectedResourceShareKey:           "share1",
+					ProjectedResourceNamespacedShareKey: "share1",
+				},
+			},
+			expectedMsg: "cannot both be set",
+		},
+		{
+			name: "storage class parameters",
+			req: csi.CreateVolumeRequest{
+				Name:               "pvc-1",
+				VolumeCapabilities: mountCapabilities,
+				Parameters: map[string]string{
+					ProjectedResourceShareKey:        "share1",
+					ProjectedResourceUpdatePolicyKey: "OnPodRestart",
+					"unknown":                        "value",
+				},
+			},
+			expectedAttributes: map[string]string{
+				ProjectedResourceShareKey:        "share1",
+				ProjectedResourceUpdatePolicyKey: "OnPodRestart",
+			},
+		},
+		{
+			name: "pvc annotation",
+			req: csi.CreateVolumeRequest{
+				Name:               "pvc-1",
+				VolumeCapabilities: mountCapabilities,
+				Parameters:         pvcParameters,
+			},
+			pvcAnnotations: map[string]string{NamespacedShareAnnotation: "share2"},
+			expectedAttributes: map[string]string{
+				ProjectedResourceNamespacedShareKey: "share2",
+			},
+		},
+		{
+			name: "pvc annotations both set",
+			req: csi.CreateVolumeRequest{
+				Name:               "pvc-1",
+				VolumeCapabilities: mountCapabilities,
+				Parameters:         pvcParameters,
+			},
+			pvcAnnotations: map[string]string{ShareAnnotation: "share2", NamespacedShareAnnotation: "share2"},
+			expectedMsg:    "cannot both be set",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client.SetClient(fakekubeclientset.NewSimpleClientset(&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "pvc1",
+					Namespace:   "namespace1",
+					Annotations: test.pvcAnnotations,
+				},
+			}))
+			cs := NewControllerServer()
+			resp, err := cs.CreateVolume(context.TODO(), &test.req)
+			if len(test.expectedMsg) > 0 && err == nil || len(test.expectedMsg) == 0 && err != nil {
+				t.Fatalf("expected err msg: %s, got: %+v", test.expectedMsg, err)
+			}
+			if len(test.expectedMsg) > 0 {
+				if !strings.Contains(err.Error(), test.expectedMsg) {
+					t.Fatalf("instead of expected err msg containing %s got %s", test.expectedMsg, err.Error())
+				}
+				return
+			}
+			if resp.GetVolume().GetVolumeId() != test.req.GetName() {
+				t.Fatalf("expected the volume ID %s got %s", test.req.GetName(), resp.GetVolume().GetVolumeId())
+			}
+			if !reflect.DeepEqual(resp.GetVolume().GetVolumeContext(), test.expectedAttributes) {
+				t.Fatalf("expected the volume attributes %#v got %#v", test.expectedAttributes, resp.GetVolume().GetVolumeContext())
+			}
+		})
+	}
+}
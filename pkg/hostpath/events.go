@@ -0,0 +1,87 @@
+package hostpath
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	sharev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+	objcache "github.com/openshift/csi-driver-projected-resource/pkg/cache"
+	"github.com/openshift/csi-driver-projected-resource/pkg/client"
+)
+
+// the reasons of the events recorded against the pods using shares, and against their shares
+const (
+	ShareAccessDeniedReason      = "ShareAccessDenied"
+	ShareAccessRevokedReason     = "ShareAccessRevoked"
+	ShareAccessGrantedReason     = "ShareAccessGranted"
+	ShareDeletedReason           = "ShareDeleted"
+	BackingResourceMissingReason = "BackingResourceMissing"
+	DataUpdatedReason            = "DataUpdated"
+)
+
+// podReference returns a reference to a pod for its events
+func podReference(namespace, name, uid string) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Namespace:  namespace,
+		Name:       name,
+		UID:        types.UID(uid),
+	}
+}
+
+// shareReference returns a reference to a Share or NamespacedShare for its events, from its key as built by
+// objcache.GetShareKey
+func shareReference(shareKey string) *corev1.ObjectReference {
+	ref := &corev1.ObjectReference{
+		APIVersion: sharev1alpha1.SchemeGroupVersion.String(),
+		Kind:       "Share",
+		Name:       shareKey,
+	}
+	if parts := strings.SplitN(shareKey, ":", 2); len(parts) == 2 {
+		ref.Kind = "NamespacedShare"
+		ref.Namespace, ref.Name = parts[0], parts[1]
+	}
+	return ref
+}
+
+// recordEvent records an event against a pod and against the share it uses
+func recordEvent(pod, share *corev1.ObjectReference, eventType, reason, messageFmt string, args ...interface{}) {
+	recorder := client.GetRecorder()
+	if recorder == nil {
+		return
+	}
+	recorder.Eventf(pod, eventType, reason, messageFmt, args...)
+	recorder.Eventf(share, eventType, reason, messageFmt, args...)
+}
+
+// recordVolumeEvent records an event against the pod of a volume and against its share
+func recordVolumeEvent(hpv *hostPathVolume, eventType, reason, messageFmt string, args ...interface{}) {
+	recordEvent(podReference(hpv.PodNamespace, hpv.PodName, hpv.PodUID), shareReference(hpv.SharedDataId),
+		eventType, reason, messageFmt, args...)
+}
+
+// missingItems returns the shared data items of a volume naming an object that does not exist
+func missingItems(hpv *hostPathVolume) []sharedDataItem {
+	missing := []sharedDataItem{}
+	for _, item := range hpv.SharedData {
+		if item.Selector != nil {
+			continue
+		}
+		if objcache.GetObject(objcache.KindKey(item.APIVersion, item.Kind), item.Key) == nil {
+			missing = append(missing, item)
+		}
+	}
+	return missing
+}
+
+// recordMissingItems records a BackingResourceMissing event for each shared data item of a volume naming an
+// object that does not exist
+func recordMissingItems(hpv *hostPathVolume) {
+	for _, item := range missingItems(hpv) {
+		recordVolumeEvent(hpv, corev1.EventTypeWarning, BackingResourceMissingReason,
+			"the %s %s of the share %s is missing, its data is not projected", item.Kind, item.Key, hpv.SharedDataId)
+	}
+}
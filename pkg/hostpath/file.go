@@ -1,23 +1,298 @@
 package hostpath
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/klog/v2"
 
 	"github.com/openshift/csi-driver-projected-resource/pkg/client"
+	"github.com/openshift/csi-driver-projected-resource/pkg/metrics"
+	"github.com/openshift/csi-driver-projected-resource/pkg/validation"
 )
 
+const (
+	defaultFileMode os.FileMode = 0644
+	// defaultDirMode is the mode of the directories of a volume, which only its files are protected by
+	defaultDirMode os.FileMode = 0755
+	// dataDirName is the symlink to the directory holding the current files of a volume
+	dataDirName = "..data"
+	// newDataDirName is the symlink renamed over dataDirName to swap it atomically
+	newDataDirName = "..data_tmp"
+)
+
+var errPayloadChanged = errors.New("payload changed")
+
 type Payload struct {
 	StringData map[string]string
 	ByteData   map[string][]byte
 }
 
-func ProcessFileSystemError(obj runtime.Object, err error) {
+// projectedFile is the content and mode of a file written into a volume, or of a directory when its mode
+// has os.ModeDir set
+type projectedFile struct {
+	data []byte
+	mode os.FileMode
+}
+
+// projectedFiles maps the keys of a payload to the files written for a shared data item,
+// keyed by path relative to the target path of the volume
+func projectedFiles(item sharedDataItem, payload Payload, defaultMode os.FileMode) map[string]projectedFile {
+	content := map[string][]byte{}
+	for dataKey, dataValue := range payload.ByteData {
+		content[dataKey] = dataValue
+	}
+	for dataKey, dataValue := range payload.StringData {
+		content[dataKey] = []byte(dataValue)
+	}
+
+	files := map[string]projectedFile{}
+	if len(item.Items) == 0 {
+		dir := filepath.Join(validation.KindDirectory(item.Kind), item.Key)
+		files[dir] = projectedFile{mode: os.ModeDir | defaultDirMode}
+		for dataKey, dataValue := range content {
+			files[filepath.Join(dir, dataKey)] = projectedFile{data: dataValue, mode: defaultMode}
+		}
+		return files
+	}
+	for _, keyToPath := range item.Items {
+		dataValue, ok := content[keyToPath.Key]
+		if !ok {
+			klog.V(2).Infof("key %s is not present in %s %s so it is not projected", keyToPath.Key, item.Kind, item.Key)
+			continue
+		}
+		mode := defaultMode
+		if keyToPath.Mode != nil {
+			mode = os.FileMode(*keyToPath.Mode)
+		}
+		files[filepath.Clean(keyToPath.Path)] = projectedFile{data: dataValue, mode: mode}
+	}
+	return files
+}
+
+// upToDate returns true if the file at the given path already has the content and mode to be written
+func upToDate(path string, file projectedFile) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != file.mode.Perm() {
+		return false
+	}
+	data, err := ioutil.ReadFile(path)
+	return err == nil && bytes.Equal(data, file.data)
+}
+
+// samePayload returns true if the files written to a directory are exactly the given ones
+func samePayload(dir string, files map[string]projectedFile) bool {
+	// the directories expected to exist, whether listed or holding a listed path
+	expected := map[string]bool{}
+	for path := range files {
+		for p := path; p != "."; p = filepath.Dir(p) {
+			expected[p] = true
+		}
+	}
+	found := 0
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		file, listed := files[rel]
+		switch {
+		case !expected[rel]:
+			return errPayloadChanged
+		case info.IsDir() && listed && !file.mode.IsDir():
+			return errPayloadChanged
+		case !info.IsDir() && (!listed || !upToDate(path, file)):
+			return errPayloadChanged
+		}
+		found++
+		return nil
+	})
+	return err == nil && found == len(expected)
+}
+
+// writeFiles writes files to a new data directory; the ones identical to their counterpart in the
+// current data directory are hard linked to it rather than written again
+func writeFiles(dir, currentDir string, files map[string]projectedFile) error {
+	for path, file := range files {
+		filePath := filepath.Join(dir, path)
+		if file.mode.IsDir() {
+			if err := os.MkdirAll(filePath, defaultDirMode); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(filePath), defaultDirMode); err != nil {
+			return err
+		}
+		if len(currentDir) > 0 {
+			currentPath := filepath.Join(currentDir, path)
+			if upToDate(currentPath, file) && os.Link(currentPath, filePath) == nil {
+				continue
+			}
+		}
+		klog.V(4).Infof("create/update file %s", filePath)
+		if err := ioutil.WriteFile(filePath, file.data, file.mode); err != nil {
+			return err
+		}
+		// WriteFile only applies the mode on create, and subject to the umask
+		if err := os.Chmod(filePath, file.mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// topLevelPaths returns the first element of each path of a volume
+func topLevelPaths(files map[string]projectedFile) map[string]bool {
+	paths := map[string]bool{}
+	for path := range files {
+		paths[strings.SplitN(path, string(filepath.Separator), 2)[0]] = true
+	}
+	return paths
+}
+
+// chownFiles changes the owner and group of a directory and everything in it; a uid or gid of -1 is left
+// unchanged
+func chownFiles(dir string, uid, gid int) error {
+	if uid == -1 && gid == -1 {
+		return nil
+	}
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, uid, gid)
+	})
+}
+
+// writePayload makes the files of a volume visible all at once, with the same layout kubelet's AtomicWriter
+// uses for configMap and secret volumes: the files are written to a new timestamped directory, the ..data
+// symlink is swapped to it, and each top level path of the volume is a symlink through ..data; nothing is
+// written when the files are unchanged, and false is returned. The files are owned by the given uid and gid, unless -1
+func writePayload(targetPath string, files map[string]projectedFile, uid, gid int) (bool, error) {
+	if err := os.MkdirAll(targetPath, os.ModePerm); err != nil {
+		return false, err
+	}
+	dataDirPath := filepath.Join(targetPath, dataDirName)
+	currentDir, err := os.Readlink(dataDirPath)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	currentDirPath := ""
+	if len(currentDir) > 0 {
+		currentDirPath = filepath.Join(targetPath, currentDir)
+		if samePayload(currentDirPath, files) {
+			return false, nil
+		}
+	}
+
+	dirPath, err := ioutil.TempDir(targetPath, time.Now().UTC().Format("..2006_01_02_15_04_05."))
+	if err != nil {
+		return false, err
+	}
+	if err := os.Chmod(dirPath, defaultDirMode); err != nil {
+		os.RemoveAll(dirPath)
+		return false, err
+	}
+	if err := writeFiles(dirPath, currentDirPath, files); err != nil {
+		os.RemoveAll(dirPath)
+		return false, err
+	}
+	if err := chownFiles(dirPath, uid, gid); err != nil {
+		os.RemoveAll(dirPath)
+		return false, err
+	}
+
+	// a rename is atomic, so readers see either the old or the new data directory
+	newDataDirPath := filepath.Join(targetPath, newDataDirName)
+	os.Remove(newDataDirPath)
+	if err := os.Symlink(filepath.Base(dirPath), newDataDirPath); err != nil {
+		os.RemoveAll(dirPath)
+		return false, err
+	}
+	if err := os.Rename(newDataDirPath, dataDirPath); err != nil {
+		os.Remove(newDataDirPath)
+		os.RemoveAll(dirPath)
+		return false, err
+	}
+
+	topLevel := topLevelPaths(files)
+	entries, err := ioutil.ReadDir(targetPath)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range entries {
+		// the paths left from a previous payload, or written by an earlier version of the driver
+		// directly into the volume
+		if !strings.HasPrefix(entry.Name(), "..") && (!topLevel[entry.Name()] || entry.Mode()&os.ModeSymlink == 0) {
+			if err := os.RemoveAll(filepath.Join(targetPath, entry.Name())); err != nil {
+				return false, err
+			}
+		}
+	}
+	for path := range topLevel {
+		linkPath := filepath.Join(targetPath, path)
+		if _, err := os.Lstat(linkPath); err == nil {
+			continue
+		}
+		if err := os.Symlink(filepath.Join(dataDirName, path), linkPath); err != nil {
+			return false, err
+		}
+	}
+
+	if len(currentDirPath) > 0 {
+		if err := os.RemoveAll(currentDirPath); err != nil {
+			klog.Warningf("error removing data directory %s: %s", currentDirPath, err.Error())
+		}
+	}
+	return true, nil
+}
+
+// sizeLimitError is returned when the data of a volume does not fit in its size limit
+type sizeLimitError struct {
+	volID string
+	size  int64
+	limit int64
+}
+
+func (e *sizeLimitError) Error() string {
+	return fmt.Sprintf("the data of volume %s is %d bytes, over its size limit of %d bytes", e.volID, e.size, e.limit)
+}
+
+// payloadSize returns the number of bytes of the files of a volume
+func payloadSize(files map[string]projectedFile) int64 {
+	size := int64(0)
+	for _, file := range files {
+		size += int64(len(file.data))
+	}
+	return size
+}
+
+// ProcessFileSystemError reports an error writing the data of a volume after a change of one of its backing
+// resources, with an event recorded against the object, and against the pod of the volume and its share
+func ProcessFileSystemError(hpv *hostPathVolume, obj runtime.Object, err error) {
 	msg := fmt.Sprintf("%s", err.Error())
 	klog.Errorf(msg)
-	client.GetRecorder().Eventf(obj, corev1.EventTypeWarning, "FileSystemError", msg)
+	reason := "FileSystemError"
+	var sizeErr *sizeLimitError
+	if errors.As(err, &sizeErr) {
+		reason = "VolumeSizeLimitExceeded"
+	}
+	metrics.RecordFileSystemError(reason)
+	if recorder := client.GetRecorder(); recorder != nil {
+		recorder.Eventf(obj, corev1.EventTypeWarning, reason, msg)
+	}
+	recordVolumeEvent(hpv, corev1.EventTypeWarning, reason, msg)
 
 }
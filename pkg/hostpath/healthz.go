@@ -0,0 +1,68 @@
+package hostpath
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/csi-driver-projected-resource/pkg/client"
+	"github.com/openshift/csi-driver-projected-resource/pkg/metrics"
+)
+
+// ready returns why the driver is not ready to serve volumes, or nil once the informer caches have synced and
+// the volumes persisted on disk have been restored
+func (hp *hostPath) ready() error {
+	if !client.ListersSynced() {
+		return errors.New("the informer caches have not synced")
+	}
+	if atomic.LoadInt32(&hp.volMapLoaded) == 0 {
+		return errors.New("the volume map has not been loaded from disk")
+	}
+	return nil
+}
+
+// healthy returns why the driver is not healthy, or nil when its gRPC server listens and its volume map can be
+// written to disk
+func (hp *hostPath) healthy() error {
+	if hp.server == nil || !hp.server.Listening() {
+		return errors.New("the gRPC server is not listening")
+	}
+	f, err := ioutil.TempFile(filepath.Dir(volMapOnDiskPath), ".healthz")
+	if err != nil {
+		return fmt.Errorf("the volume map directory is not writable: %v", err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
+
+// checkHandler serves the result of checks, failing with the error of the first that fails
+func checkHandler(checks ...func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, check := range checks {
+			if err := check(); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Write([]byte("ok"))
+	}
+}
+
+// serveHealth serves /healthz, for the liveness of the driver, /readyz, for its readiness, and /metrics on addr
+func (hp *hostPath) serveHealth(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", checkHandler(hp.healthy))
+	mux.HandleFunc("/readyz", checkHandler(hp.healthy, hp.ready))
+	mux.Handle("/metrics", metrics.Handler())
+	klog.Infof("Serving health checks and metrics on address: %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		klog.Fatalf("Failed to serve health checks: %v", err)
+	}
+}
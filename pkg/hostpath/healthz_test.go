@@ -0,0 +1,61 @@
+package hostpath
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/openshift/csi-driver-projected-resource/pkg/client"
+	"golang.org/x/net/context"
+)
+
+func TestProbeAndHealthChecks(t *testing.T) {
+	hp, tmpDir, volPath, err := testHostPathDriver()
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	defer os.RemoveAll(tmpDir)
+	defer os.RemoveAll(volPath)
+	defer client.SetListersSynced(false)
+	ids := NewIdentityServer(hp.name, hp.version, hp.ready)
+	hp.server = NewNonBlockingGRPCServer()
+
+	check := func(handler http.HandlerFunc, expectedCode int, expectedBody string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != expectedCode || !strings.Contains(w.Body.String(), expectedBody) {
+			t.Fatalf("expected %d %q got %d %q", expectedCode, expectedBody, w.Code, w.Body.String())
+		}
+	}
+	probe := func(expected bool) {
+		t.Helper()
+		resp, err := ids.Probe(context.TODO(), &csi.ProbeRequest{})
+		if err != nil {
+			t.Fatalf("unexpected err %s", err.Error())
+		}
+		if resp.GetReady().GetValue() != expected {
+			t.Fatalf("expected ready %v got %#v", expected, resp)
+		}
+	}
+
+	check(checkHandler(hp.healthy), http.StatusServiceUnavailable, "the gRPC server is not listening")
+	atomic.StoreInt32(&hp.server.listening, 1)
+	check(checkHandler(hp.healthy), http.StatusOK, "ok")
+
+	probe(false)
+	check(checkHandler(hp.healthy, hp.ready), http.StatusServiceUnavailable, "the informer caches have not synced")
+	client.SetListersSynced(true)
+	probe(false)
+	check(checkHandler(hp.healthy, hp.ready), http.StatusServiceUnavailable, "the volume map has not been loaded")
+	if err := hp.loadVolMapFromDisk(); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	atomic.StoreInt32(&hp.volMapLoaded, 1)
+	probe(true)
+	check(checkHandler(hp.healthy, hp.ready), http.StatusOK, "ok")
+}
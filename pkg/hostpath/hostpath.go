@@ -18,22 +18,30 @@ package hostpath
 
 import (
 	"encoding/gob"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
 
 	sharev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
 	objcache "github.com/openshift/csi-driver-projected-resource/pkg/cache"
 	"github.com/openshift/csi-driver-projected-resource/pkg/client"
+	"github.com/openshift/csi-driver-projected-resource/pkg/metrics"
+	"github.com/openshift/csi-driver-projected-resource/pkg/validation"
 )
 
 const (
@@ -50,37 +58,101 @@ type hostPath struct {
 	endpoint          string
 	ephemeral         bool
 	maxVolumesPerNode int64
+	maxBytesPerNode   int64
 
-	ids *identityServer
-	ns  *nodeServer
+	ids    *identityServer
+	cs     *controllerServer
+	ns     *nodeServer
+	server *nonBlockingGRPCServer
+	// volMapLoaded is set once the volumes persisted on disk have been restored
+	volMapLoaded int32
 
-	root string
+	root        string
+	stagingRoot string
 }
 
 type hostPathVolume struct {
-	VolName        string     `json:"volName"`
-	VolID          string     `json:"volID"`
-	VolSize        int64      `json:"volSize"`
-	VolPath        string     `json:"volPath"`
-	VolAccessType  accessType `json:"volAccessType"`
-	TargetPath     string     `json:"targetPath"`
-	SharedDataKey  string     `json:"sharedDataKey"`
-	SharedDataKind string     `json:"sharedDataKind"`
-	SharedDataId   string     `json:"sharedDataId"`
-	PodNamespace   string     `json:"podNamespace"`
-	PodName        string     `json:"podName"`
-	PodUID         string     `json:"podUID"`
-	PodSA          string     `json:"podSA"`
-	Allowed        bool       `json:"allowed"`
+	VolName       string     `json:"volName"`
+	VolID         string     `json:"volID"`
+	VolSize       int64      `json:"volSize"`
+	VolPath       string     `json:"volPath"`
+	VolAccessType accessType `json:"volAccessType"`
+	TargetPath    string     `json:"targetPath"`
+	// StagingPath, set for read-only volumes, is where the driver writes the data of the volume, which is
+	// bind mounted read-only onto TargetPath
+	StagingPath  string           `json:"stagingPath,omitempty"`
+	SharedData   []sharedDataItem `json:"sharedData"`
+	SharedDataId string           `json:"sharedDataId"`
+	PodNamespace string           `json:"podNamespace"`
+	PodName      string           `json:"podName"`
+	PodUID       string           `json:"podUID"`
+	PodSA        string           `json:"podSA"`
+	Allowed      bool             `json:"allowed"`
+	// UpdatePolicy is the update policy of the share when the volume was mounted, unless overridden by
+	// the volume attributes of the pod
+	UpdatePolicy sharev1alpha1.UpdatePolicy `json:"updatePolicy,omitempty"`
+	// DefaultMode, SecretDefaultMode and ConfigMapDefaultMode are the default file modes of the share when
+	// the volume was mounted, unless overridden by the volume attributes of the pod
+	DefaultMode          *int32 `json:"defaultMode,omitempty"`
+	SecretDefaultMode    *int32 `json:"secretDefaultMode,omitempty"`
+	ConfigMapDefaultMode *int32 `json:"configMapDefaultMode,omitempty"`
+	// RunAsUser and FSGroup, from the security context of the pod, own the files of the volume
+	RunAsUser *int64 `json:"runAsUser,omitempty"`
+	FSGroup   *int64 `json:"fsGroup,omitempty"`
+	// SharedDataKey and SharedDataKind are the backing resource of the volumes persisted before a share could
+	// have several; they are only read to restore the SharedData of those volumes.
+	// Deprecated: use SharedData.
+	SharedDataKey  string `json:"sharedDataKey,omitempty"`
+	SharedDataKind string `json:"sharedDataKind,omitempty"`
+}
+
+// dataPath returns the path the data of a volume is written to
+func (hpv *hostPathVolume) dataPath() string {
+	if len(hpv.StagingPath) > 0 {
+		return hpv.StagingPath
+	}
+	return hpv.TargetPath
+}
+
+// fileMode returns the mode of the files projected into a volume from a backing resource of the given kind,
+// when its items do not set one
+func (hpv *hostPathVolume) fileMode(kind string) os.FileMode {
+	mode := hpv.DefaultMode
+	switch {
+	case kind == "Secret" && hpv.SecretDefaultMode != nil:
+		mode = hpv.SecretDefaultMode
+	case kind == "ConfigMap" && hpv.ConfigMapDefaultMode != nil:
+		mode = hpv.ConfigMapDefaultMode
+	}
+	if mode == nil {
+		return defaultFileMode
+	}
+	return os.FileMode(*mode)
+}
+
+// sharedDataItem is one of the backing resources of a share projected into a volume; either the
+// object identified by Key, or those in Namespace matching Selector. For kinds other than ConfigMap
+// and Secret, the keys of Items are the JSONPath expressions of the fields to project.
+type sharedDataItem struct {
+	Kind       string                `json:"kind"`
+	APIVersion string                `json:"apiVersion,omitempty"`
+	Key        string                `json:"key"`
+	Items      []corev1.KeyToPath    `json:"items,omitempty"`
+	Namespace  string                `json:"namespace,omitempty"`
+	Selector   *metav1.LabelSelector `json:"selector,omitempty"`
 }
 
 var (
 	vendorVersion = "dev"
 
-	hostPathVolumes map[string]*hostPathVolume
+	hostPathVolumes *volumeStore
 
 	fileWriteLock = sync.Mutex{}
 
+	// payloadLock serializes the writes of volume payloads, which can be triggered by the events of
+	// several backing resources at once
+	payloadLock = sync.Mutex{}
+
 	volMapOnDiskPath = filepath.Join(VolumeMapRoot, VolumeMapFile)
 )
 
@@ -90,6 +162,12 @@ const (
 	// no bind mount, approach.
 	DataRoot = "/csi-data-dir"
 
+	// Directory where read-only volumes are mounted for the driver to write
+	// their data, before being bind mounted read-only for their pods.
+	// It needs to be on the host, with bidirectional mount propagation, for
+	// those mounts to survive restarts of the DaemonSet
+	StagingRoot = "/var/lib/kubelet/plugins/csi-driver-projected-resource/staging"
+
 	// Directory where we persist `hostPathVolumes`
 	// This is a hostpath volume on the local node
 	// to maintain state across restarts of the DaemonSet
@@ -98,17 +176,18 @@ const (
 )
 
 func init() {
-	hostPathVolumes = map[string]*hostPathVolume{}
+	hostPathVolumes = newVolumeStore()
 }
 
 type HostPathDriver interface {
-	createHostpathVolume(volID, targetPath string, volCtx map[string]string, share *sharev1alpha1.Share, cap int64, volAccessType accessType) (*hostPathVolume, error)
+	createHostpathVolume(volID, targetPath string, volCtx map[string]string, share sharev1alpha1.ShareObject, cap int64, volAccessType accessType) (*hostPathVolume, error)
 	deleteHostpathVolume(volID string) error
 	getVolumePath(volID string, volCtx map[string]string) string
+	getStagingPath(volID string) string
 	mapVolumeToPod(hpv *hostPathVolume) error
 }
 
-func NewHostPathDriver(root, volMapRoot, driverName, nodeID, endpoint string, maxVolumesPerNode int64, version string) (*hostPath, error) {
+func NewHostPathDriver(root, stagingRoot, volMapRoot, driverName, nodeID, endpoint string, maxVolumesPerNode, maxBytesPerNode int64, version string) (*hostPath, error) {
 	if driverName == "" {
 		return nil, errors.New("no driver name provided")
 	}
@@ -128,6 +207,10 @@ func NewHostPathDriver(root, volMapRoot, driverName, nodeID, endpoint string, ma
 		return nil, fmt.Errorf("failed to create DataRoot: %v", err)
 	}
 
+	if err := os.MkdirAll(stagingRoot, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create StagingRoot: %v", err)
+	}
+
 	if err := os.MkdirAll(volMapRoot, 0750); err != nil {
 		return nil, fmt.Errorf("failed to create VolMapRoot: %v", err)
 	}
@@ -141,25 +224,44 @@ func NewHostPathDriver(root, volMapRoot, driverName, nodeID, endpoint string, ma
 		nodeID:            nodeID,
 		endpoint:          endpoint,
 		maxVolumesPerNode: maxVolumesPerNode,
+		maxBytesPerNode:   maxBytesPerNode,
 		root:              root,
+		stagingRoot:       stagingRoot,
 	}
 
 	volMapOnDiskPath = filepath.Join(volMapRoot, VolumeMapFile)
-	if err := hp.loadVolMapFromDisk(); err != nil {
-		return nil, fmt.Errorf("failed to load volume map on disk: %v", err)
-	}
 
 	return hp, nil
 }
 
-func (hp *hostPath) Run() {
+// Run serves the driver, and its health checks on healthAddress unless it is empty
+func (hp *hostPath) Run(healthAddress string) {
 	// Create GRPC servers
-	hp.ids = NewIdentityServer(hp.name, hp.version)
+	hp.ids = NewIdentityServer(hp.name, hp.version, hp.ready)
+	hp.cs = NewControllerServer()
 	hp.ns = NewNodeServer(hp)
 
-	s := NewNonBlockingGRPCServer()
-	s.Start(hp.endpoint, hp.ids, hp.ns)
-	s.Wait()
+	hp.server = NewNonBlockingGRPCServer()
+	if len(healthAddress) > 0 {
+		go hp.serveHealth(healthAddress)
+	}
+	hp.server.Start(hp.endpoint, hp.ids, hp.cs, hp.ns)
+
+	// the volumes persisted on disk are restored once the listers have synced, so that their data is projected
+	// from the current backing resources, rather than from an empty cache
+	wait.PollInfinite(time.Second, func() (bool, error) {
+		return client.ListersSynced(), nil
+	})
+	if err := hp.loadVolMapFromDisk(); err != nil {
+		klog.Fatalf("failed to load volume map on disk: %v", err)
+	}
+	atomic.StoreInt32(&hp.volMapLoaded, 1)
+	hp.server.Wait()
+}
+
+// getStagingPath returns the path a read-only volume is mounted at for the driver to write its data
+func (hp *hostPath) getStagingPath(volID string) string {
+	return filepath.Join(hp.stagingRoot, volID)
 }
 
 // getVolumePath returns the canonical path for hostpath volume
@@ -168,12 +270,68 @@ func (hp *hostPath) getVolumePath(volID string, volCtx map[string]string) string
 	return filepath.Join(hp.root, volID, podNamespace, podName, podUID, podSA)
 }
 
-func commonUpsertRanger(obj runtime.Object, podPath, filter string, key, value interface{}) error {
-	if key != filter {
-		return nil
+// volumeFiles returns the files projected into a volume for its shared data items, from the objects
+// currently in the cache
+func volumeFiles(hpv *hostPathVolume) (map[string]projectedFile, error) {
+	files := map[string]projectedFile{}
+	for _, item := range hpv.SharedData {
+		kindKey := objcache.KindKey(item.APIVersion, item.Kind)
+		if item.Selector != nil {
+			for _, obj := range objcache.ListObjects(kindKey, item.Namespace, item.Selector) {
+				selected := sharedDataItem{Kind: item.Kind, Key: objcache.GetKey(obj)}
+				for path, file := range projectedFiles(selected, payloadFor(obj, selected), hpv.fileMode(item.Kind)) {
+					files[path] = file
+				}
+			}
+			continue
+		}
+		// the items are checked when the volume is published, but the share can change afterwards
+		for _, keyToPath := range item.Items {
+			if err := validation.ValidateKeyToPath(keyToPath); err != nil {
+				return nil, fmt.Errorf("invalid item for %s %s: %s", item.Kind, item.Key, err.Error())
+			}
+		}
+		obj := objcache.GetObject(kindKey, item.Key)
+		if obj == nil {
+			continue
+		}
+		for path, file := range projectedFiles(item, payloadFor(obj, item), hpv.fileMode(item.Kind)) {
+			files[path] = file
+		}
+	}
+	if hpv.FSGroup != nil {
+		// like kubelet does for the fsGroup of a pod, so that its containers can read the files whichever
+		// user they run as
+		for path, file := range files {
+			if !file.mode.IsDir() {
+				file.mode |= 0040
+				files[path] = file
+			}
+		}
 	}
-	payload, _ := value.(Payload)
-	podFileDir := filepath.Join(podPath, fmt.Sprintf("%s", key))
+	return files, nil
+}
+
+// syncVolume writes the current data of the shared data items of a volume, or removes it when the pod is
+// no longer allowed to use the share
+func syncVolume(hpv *hostPathVolume) error {
+	_, err := writeVolume(hpv)
+	return err
+}
+
+// updateVolume writes the current data of a volume after a change of its share or backing resources, recording
+// a DataUpdated event against its pod and share when its files changed
+func updateVolume(hpv *hostPathVolume, cause string) error {
+	written, err := writeVolume(hpv)
+	if err == nil && written && hpv.Allowed {
+		recordVolumeEvent(hpv, corev1.EventTypeNormal, DataUpdatedReason,
+			"the data of the share %s was updated after a change of %s", hpv.SharedDataId, cause)
+	}
+	return err
+}
+
+// writeVolume writes the data of a volume like syncVolume, returning whether its files changed
+func writeVolume(hpv *hostPathVolume) (bool, error) {
 	// So, what to do with error handling.  Errors with filesystem operations
 	// will almost always not be intermittent, but most likely the result of the
 	// host filesystem either being full or compromised in some long running fashion, so tight-loop retry, like we
@@ -183,265 +341,376 @@ func commonUpsertRanger(obj runtime.Object, podPath, filter string, key, value i
 	// action, writing the content will be retried.  And note, the relist interval is configurable (default 10 minutes)
 	// if users want more rapid retry...but by default, no tight loop more CPU intensive retry
 	// Lastly, with the understanding that an error log in the pod stdout may be missed, we will also generate a k8s
-	// event to facilitate exposure
-	// TODO: prometheus metrics/alerts may be desired here, though some due diligence on what k8s level metrics/alerts
-	// around host filesystem issues might already exist would be warranted with such an exploration/effort
-	if err := os.MkdirAll(podFileDir, os.ModePerm); err != nil {
-		return err
-	}
-	if payload.ByteData != nil {
-		for dataKey, dataValue := range payload.ByteData {
-			podFilePath := filepath.Join(podFileDir, dataKey)
-			klog.V(4).Infof("create/update file %s", podFilePath)
-			if err := ioutil.WriteFile(podFilePath, dataValue, 0644); err != nil {
-				return err
-			}
-
+	// event to facilitate exposure, and count it in the filesystem_errors_total metric
+	files := map[string]projectedFile{}
+	if hpv.Allowed {
+		var err error
+		if files, err = volumeFiles(hpv); err != nil {
+			return false, err
 		}
 	}
-	if payload.StringData != nil {
-		for dataKey, dataValue := range payload.StringData {
-			podFilePath := filepath.Join(podFileDir, dataKey)
-			klog.V(4).Infof("create/update file %s", podFilePath)
-			content := []byte(dataValue)
-			if err := ioutil.WriteFile(podFilePath, content, 0644); err != nil {
-				return err
-			}
-		}
+	if size := payloadSize(files); hpv.VolSize > 0 && size > hpv.VolSize {
+		// the volume is left as it is rather than filling up its tmpfs, and node memory with it
+		return false, &sizeLimitError{volID: hpv.VolID, size: size, limit: hpv.VolSize}
 	}
-	return nil
-}
-
-func commonDeleteRanger(podPath, filter string, key interface{}) bool {
-	if key != filter {
-		return true
+	uid, gid := -1, -1
+	if hpv.RunAsUser != nil {
+		uid = int(*hpv.RunAsUser)
 	}
-	podFilePath := filepath.Join(podPath, fmt.Sprintf("%s", key))
-	os.Remove(podFilePath)
-	return true
+	if hpv.FSGroup != nil {
+		gid = int(*hpv.FSGroup)
+	}
+	payloadLock.Lock()
+	defer payloadLock.Unlock()
+	return writePayload(hpv.dataPath(), files, uid, gid)
 }
 
-func shareDeleteRanger(hp *hostPath, key interface{}) bool {
-	shareId := key.(string)
-	targetPath := ""
-	volID := ""
-	for _, hpv := range hostPathVolumes {
-		if hpv.SharedDataId == shareId {
-			switch hpv.SharedDataKind {
-			case "ConfigMap":
-				targetPath = filepath.Join(hpv.TargetPath, "configmaps")
-			case "Secret":
-				targetPath = filepath.Join(hpv.TargetPath, "secrets")
-			}
-			volID = hpv.VolID
-			// deleting the share effectively deletes permission to the
-			// data so we set the allowed bit to false; this will have bearing
-			// if the share is added again at a later date and the associated
-			// pod in question is still up
-			hpv.Allowed = false
-			break
-		}
-	}
-	if len(volID) > 0 && len(targetPath) > 0 {
-		err := os.RemoveAll(targetPath)
-		if err != nil {
-			klog.Warningf("share %s vol %s target path %s delete error %s",
-				shareId, volID, targetPath, err.Error())
-		}
-		// we just delete the associated data from the previously provisioned volume;
-		// we don't delete the volume in case the share is added back
-		storeVolMapToDisk()
-	}
-	return true
+// shareDeleted removes the data of a share that was deleted from one of the volumes using it
+func shareDeleted(hpv *hostPathVolume, shareId string) {
+	// deleting the share effectively deletes permission to the
+	// data so we set the allowed bit to false; this will have bearing
+	// if the share is added again at a later date and the associated
+	// pod in question is still up
+	hpv.Allowed = false
+	if len(hpv.TargetPath) == 0 {
+		return
+	}
+	recordVolumeEvent(hpv, corev1.EventTypeWarning, ShareDeletedReason,
+		"the share %s was deleted, its data is removed from the volume", shareId)
+	if err := syncVolume(hpv); err != nil {
+		klog.Warningf("share %s vol %s target path %s delete error %s",
+			shareId, hpv.VolID, hpv.TargetPath, err.Error())
+	}
+	// we just delete the associated data from the previously provisioned volume;
+	// we don't delete the volume in case the share is added back
+	storeVolume(hpv)
 }
 
-func shareUpdateRanger(key, value interface{}) bool {
-	shareId := key.(string)
-	share := value.(*sharev1alpha1.Share)
-	klog.V(4).Infof("share update ranger id %s share name %s", shareId, share.Name)
-	oldTargetPath := ""
-	volID := ""
+// shareUpdated applies an update of a share to one of the volumes using it, checking again whether its
+// pod may use the share
+func shareUpdated(hpv *hostPathVolume, share sharev1alpha1.ShareObject) {
+	shareId := objcache.GetShareKey(share)
+	klog.V(4).Infof("share update id %s share name %s volume %s", shareId, share.GetName(), hpv.VolID)
+	newSharedData := sharedDataFromShare(share)
 	change := false
 	lostPermissions := false
 	gainedPermissions := false
-	hpv := &hostPathVolume{}
-	for _, hpv = range hostPathVolumes {
-		if hpv.SharedDataId == shareId {
-			klog.V(4).Infof("share update ranger id %s found volume %s", shareId, hpv.VolID)
-			a, err := client.ExecuteSAR(shareId, hpv.PodNamespace, hpv.PodName, hpv.PodSA)
-			allowed := a && err == nil
-
-			if allowed && !hpv.Allowed {
-				klog.V(0).Infof("pod %s regained permissions for share %s",
-					hpv.PodName, shareId)
-				gainedPermissions = true
-				hpv.Allowed = true
-			}
-			if !allowed && hpv.Allowed {
-				klog.V(0).Infof("pod %s no longer has permission for share %s",
-					hpv.PodName, shareId)
-				lostPermissions = true
-				hpv.Allowed = false
-			}
-
-			switch {
-			case share.Spec.BackingResource.Kind != hpv.SharedDataKind:
-				change = true
-			case objcache.BuildKey(share.Spec.BackingResource.Namespace, share.Spec.BackingResource.Name) != hpv.SharedDataKey:
-				change = true
-			}
-			if !change && !lostPermissions && !gainedPermissions {
-				break
-			}
-			switch hpv.SharedDataKind {
-			case "ConfigMap":
-				oldTargetPath = filepath.Join(hpv.TargetPath, "configmaps")
-			case "Secret":
-				oldTargetPath = filepath.Join(hpv.TargetPath, "secrets")
-			}
-			volID = hpv.VolID
-			break
+	allowed := isAllowed(hpv, share)
+
+	if allowed && !hpv.Allowed {
+		klog.V(0).Infof("pod %s regained permissions for share %s",
+			hpv.PodName, shareId)
+		gainedPermissions = true
+		hpv.Allowed = true
+	}
+	if !allowed && hpv.Allowed {
+		klog.V(0).Infof("pod %s no longer has permission for share %s",
+			hpv.PodName, shareId)
+		lostPermissions = true
+		hpv.Allowed = false
+	}
+
+	if !sameSharedData(hpv.SharedData, newSharedData) {
+		if hpv.UpdatePolicy == sharev1alpha1.UpdatePolicyOnPodRestart {
+			klog.V(4).Infof("share update id %s volume %s keeps its backing resources until its pod is recreated",
+				shareId, hpv.VolID)
+		} else {
+			change = true
 		}
 	}
 
 	if lostPermissions {
-		err := os.RemoveAll(oldTargetPath)
-		if err != nil {
+		recordVolumeEvent(hpv, corev1.EventTypeWarning, ShareAccessRevokedReason,
+			"the service account %s of the pod no longer has permission to use the share %s, its data is removed from the volume",
+			hpv.PodSA, shareId)
+		if err := syncVolume(hpv); err != nil {
 			klog.Warningf("share %s vol %s target path %s delete error %s",
-				shareId, volID, oldTargetPath, err.Error())
+				shareId, hpv.VolID, hpv.TargetPath, err.Error())
 		}
-		objcache.UnregisterSecretUpsertCallback(volID)
-		objcache.UnregisterSecretDeleteCallback(volID)
-		objcache.UnregisterConfigMapDeleteCallback(volID)
-		objcache.UnregisterConfigMapUpsertCallback(volID)
-		storeVolMapToDisk()
-		return true
+		objcache.UnregisterObjectCallbacks(hpv.VolID)
+		storeVolume(hpv)
+		return
 	}
 
 	if change {
-		err := os.RemoveAll(oldTargetPath)
-		if err != nil {
-			klog.Warningf("share %s vol %s target path %s delete error %s",
-				shareId, volID, oldTargetPath, err.Error())
-		}
-		objcache.UnregisterSecretUpsertCallback(volID)
-		objcache.UnregisterSecretDeleteCallback(volID)
-		objcache.UnregisterConfigMapDeleteCallback(volID)
-		objcache.UnregisterConfigMapUpsertCallback(volID)
+		// the entries no longer part of the share are dropped, and the remaining ones kept,
+		// when the volume is written again as the callbacks are registered
+		objcache.UnregisterObjectCallbacks(hpv.VolID)
 
-		hpv.SharedDataKind = share.Spec.BackingResource.Kind
-		hpv.SharedDataKey = objcache.BuildKey(share.Spec.BackingResource.Namespace, share.Spec.BackingResource.Name)
-		hpv.SharedDataId = share.Name
+		hpv.SharedData = newSharedData
+		hpv.SharedDataId = shareId
 
+		if err := updateVolume(hpv, "its backing resources"); err != nil {
+			klog.Warningf("share %s vol %s target path %s update error %s",
+				shareId, hpv.VolID, hpv.TargetPath, err.Error())
+		}
 		mapBackingResourceToPod(hpv)
 	}
 
 	if gainedPermissions {
+		recordVolumeEvent(hpv, corev1.EventTypeNormal, ShareAccessGrantedReason,
+			"the service account %s of the pod has permission to use the share %s again, its data is restored to the volume",
+			hpv.PodSA, shareId)
 		mapBackingResourceToPod(hpv)
 	}
 
 	if change || gainedPermissions {
-		storeVolMapToDisk()
+		storeVolume(hpv)
 	}
+}
 
+// isAllowed returns true if the pod of a volume may use the share; for a NamespacedShare, the namespaces
+// of its backing resources also need to still allow it
+func isAllowed(hpv *hostPathVolume, share sharev1alpha1.ShareObject) bool {
+	if len(share.GetNamespace()) == 0 {
+		allowed, err := client.ExecuteSAR(share.GetName(), hpv.PodNamespace, hpv.PodName, hpv.PodSA)
+		return allowed && err == nil
+	}
+	allowed, err := client.ExecuteNamespacedShareSAR(share.GetName(), hpv.PodNamespace, hpv.PodName, hpv.PodSA)
+	if !allowed || err != nil {
+		return false
+	}
+	for _, br := range share.GetSpec().GetBackingResources() {
+		if err := validation.ValidateNamespacedBackingResource(share.GetNamespace(), br, client.GetListers().Namespaces); err != nil {
+			klog.V(2).Infof("namespaced share %s: %s", objcache.GetShareKey(share), err.Error())
+			return false
+		}
+	}
 	return true
 }
 
-func mapBackingResourceToPod(hpv *hostPathVolume) error {
-	// for now, since os.MkdirAll does nothing and returns no error when the path already
-	// exists, we have a common path for both create and update; but if we change the file
-	// system interaction mechanism such that create and update are treated differently, we'll
-	// need separate callbacks for each
-	switch strings.TrimSpace(hpv.SharedDataKind) {
-	case "ConfigMap":
-		podConfigMapsPath := filepath.Join(hpv.TargetPath, "configmaps")
-		err := os.MkdirAll(podConfigMapsPath, 0777)
-		if err != nil {
-			return err
+// sharedDataFromShare builds the list of items a volume projects for the backing resources of a share
+func sharedDataFromShare(share sharev1alpha1.ShareObject) []sharedDataItem {
+	sharedData := []sharedDataItem{}
+	for _, br := range share.GetSpec().GetBackingResources() {
+		if br.Selector != nil {
+			sharedData = append(sharedData, sharedDataItem{
+				Kind:      strings.TrimSpace(br.Kind),
+				Namespace: br.Namespace,
+				Selector:  br.Selector,
+			})
+			continue
 		}
-		upsertRangerCM := func(key, value interface{}) bool {
-			cm, _ := value.(*corev1.ConfigMap)
-			payload := Payload{
-				StringData: cm.Data,
-				ByteData:   cm.BinaryData,
-			}
-			err := commonUpsertRanger(cm, podConfigMapsPath, hpv.SharedDataKey, key, payload)
-			if err != nil {
-				ProcessFileSystemError(cm, err)
+		item := sharedDataItem{
+			Kind:  strings.TrimSpace(br.Kind),
+			Key:   objcache.BuildKey(br.Namespace, br.Name),
+			Items: br.Items,
+		}
+		if validation.KindDirectory(item.Kind) == "" {
+			item.APIVersion = strings.TrimSpace(br.APIVersion)
+			item.Items = fieldItems(br.Fields)
+		}
+		sharedData = append(sharedData, item)
+	}
+	return sharedData
+}
+
+// fieldItems maps the fields of a backing resource of a kind other than ConfigMap or Secret to items, keyed
+// by their JSONPath expression
+func fieldItems(fields []sharev1alpha1.FieldToPath) []corev1.KeyToPath {
+	items := []corev1.KeyToPath{}
+	for _, field := range fields {
+		items = append(items, corev1.KeyToPath{Key: field.JSONPath, Path: field.Path, Mode: field.Mode})
+	}
+	return items
+}
+
+// subtractSharedData returns the items in a that are not in b
+func subtractSharedData(a, b []sharedDataItem) []sharedDataItem {
+	diff := []sharedDataItem{}
+	for _, itemA := range a {
+		found := false
+		for _, itemB := range b {
+			if equality.Semantic.DeepEqual(itemA, itemB) {
+				found = true
+				break
 			}
+		}
+		if !found {
+			diff = append(diff, itemA)
+		}
+	}
+	return diff
+}
 
-			// we always return true in the golang ranger to still attempt additional items
-			// on the off chance the filesystem error received was intermittent and other items
-			// will succeed ... remember, the ranger predominantly deals with pushing secret/configmap
-			// updates to disk
-			return true
+func sameSharedData(a, b []sharedDataItem) bool {
+	return len(subtractSharedData(a, b)) == 0 && len(subtractSharedData(b, a)) == 0
+}
+
+func mapBackingResourceToPod(hpv *hostPathVolume) error {
+	// the keys and selector items of each kind, keyed by its objcache.KindKey
+	kindKeys := map[string]map[string]bool{}
+	kindSelectors := map[string][]sharedDataItem{}
+	for _, item := range hpv.SharedData {
+		if len(strings.TrimSpace(item.Kind)) == 0 {
+			return fmt.Errorf("invalid share backing resource kind %s", item.Kind)
 		}
-		// we call the upsert ranger inline in case there are filesystem problems initially, so
-		// we can return the error back to volume provisioning, where the kubelet will retry at
-		// a controlled frequency
-		cm := objcache.GetConfigMap(hpv.SharedDataKey)
-		if cm != nil {
-			payload := Payload{
-				StringData: cm.Data,
-				ByteData:   cm.BinaryData,
-			}
+		kindKey := objcache.KindKey(item.APIVersion, item.Kind)
+		if _, ok := kindKeys[kindKey]; !ok {
+			kindKeys[kindKey] = map[string]bool{}
+		}
+		if item.Selector != nil {
+			kindSelectors[kindKey] = append(kindSelectors[kindKey], item)
+			continue
+		}
+		kindKeys[kindKey][item.Key] = true
+	}
+	// with the OnPodRestart update policy, the content written below is what the pod sees for as long as it runs
+	if hpv.UpdatePolicy != sharev1alpha1.UpdatePolicyOnPodRestart {
+		for kindKey, keys := range kindKeys {
+			mapObjectsToPod(hpv, kindKey, keys, kindSelectors[kindKey])
+		}
+	}
+	// we write the volume inline in case there are filesystem problems initially, so
+	// we can return the error back to volume provisioning, where the kubelet will retry at
+	// a controlled frequency; it is written once the callbacks are registered, since the
+	// objects upserted from then on wait for the lock of the volume held by our caller
+	if err := syncVolume(hpv); err != nil {
+		objcache.UnregisterObjectCallbacks(hpv.VolID)
+		return err
+	}
+	recordMissingItems(hpv)
+	return nil
+}
 
-			upsertError := commonUpsertRanger(cm, podConfigMapsPath, hpv.SharedDataKey, hpv.SharedDataKey, payload)
-			if upsertError != nil {
-				ProcessFileSystemError(cm, upsertError)
-				return upsertError
-			}
+// payloadFor returns the data of an object to project into a volume; the keys of a ConfigMap or Secret, or the
+// JSONPath expressions of the items of any other kind along with the fields they select
+func payloadFor(obj runtime.Object, item sharedDataItem) Payload {
+	switch o := obj.(type) {
+	case *corev1.ConfigMap:
+		return Payload{
+			StringData: o.Data,
+			ByteData:   o.BinaryData,
 		}
-		objcache.RegisterConfigMapUpsertCallback(hpv.VolID, upsertRangerCM)
-		deleteRangerCM := func(key, value interface{}) bool {
-			return commonDeleteRanger(podConfigMapsPath, hpv.SharedDataKey, key)
+	case *corev1.Secret:
+		return Payload{
+			ByteData: o.Data,
 		}
-		objcache.RegisterConfigMapDeleteCallback(hpv.VolID, deleteRangerCM)
-	case "Secret":
-		podSecretsPath := filepath.Join(hpv.TargetPath, "secrets")
-		err := os.MkdirAll(podSecretsPath, 0777)
-		if err != nil {
-			return err
+	case *unstructured.Unstructured:
+		payload := Payload{ByteData: map[string][]byte{}}
+		for _, keyToPath := range item.Items {
+			data, err := fieldData(o, keyToPath.Key)
+			if err != nil {
+				klog.V(2).Infof("field %s of %s %s is not projected: %s", keyToPath.Key, item.Kind, item.Key, err.Error())
+				continue
+			}
+			payload.ByteData[keyToPath.Key] = data
 		}
-		upsertRangerSec := func(key, value interface{}) bool {
-			s, _ := value.(*corev1.Secret)
-			payload := Payload{
-				ByteData: s.Data,
+		return payload
+	}
+	return Payload{}
+}
+
+// fieldData evaluates a JSONPath expression against an object; strings are written as is, while any
+// other value is written as JSON, with each result on its own line
+func fieldData(obj *unstructured.Unstructured, expression string) ([]byte, error) {
+	j, err := validation.ParseJSONPath(expression)
+	if err != nil {
+		return nil, err
+	}
+	results, err := j.FindResults(obj.UnstructuredContent())
+	if err != nil {
+		return nil, err
+	}
+	lines := []string{}
+	for _, result := range results {
+		for _, value := range result {
+			if s, ok := value.Interface().(string); ok {
+				lines = append(lines, s)
+				continue
 			}
-			err := commonUpsertRanger(s, podSecretsPath, hpv.SharedDataKey, key, payload)
+			data, err := json.Marshal(value.Interface())
 			if err != nil {
-				ProcessFileSystemError(s, err)
+				return nil, err
 			}
-			// we always return true in the golang ranger to still attempt additional items
-			// on the off chance the filesystem error received was intermittent and other items
-			// will succeed ... remember, the ranger predominantly deals with pushing secret/configmap
-			// updates to disk
+			lines = append(lines, string(data))
+		}
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no value found")
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// affectsVolume returns true if an object is one of the given keys, or is in the namespace of one of
+// the selector items, where it may have started or stopped matching its selector
+func affectsVolume(keys map[string]bool, selectors []sharedDataItem, key string, obj interface{}) bool {
+	if keys[key] {
+		return true
+	}
+	o, ok := obj.(metav1.Object)
+	if !ok {
+		return false
+	}
+	for _, selector := range selectors {
+		if selector.Namespace == o.GetNamespace() {
 			return true
 		}
-		// we call the upsert ranger inline in case there are filesystem problems initially,  so
-		// we can return the error back to volume provisioning, where the kubelet will retry at
-		// a controlled frequency
-		s := objcache.GetSecret(hpv.SharedDataKey)
-		if s != nil {
-			payload := Payload{
-				ByteData: s.Data,
-			}
+	}
+	return false
+}
 
-			upsertError := commonUpsertRanger(s, podSecretsPath, hpv.SharedDataKey, hpv.SharedDataKey, payload)
-			if upsertError != nil {
-				ProcessFileSystemError(s, upsertError)
-				return upsertError
-			}
+func mapObjectsToPod(hpv *hostPathVolume, kindKey string, keys map[string]bool, selectors []sharedDataItem) {
+	// the cache only calls the callbacks of the volume for the objects it names, and those of the namespaces
+	// it selects objects in
+	objects := objcache.VolumeObjects{}
+	for key := range keys {
+		objects.Keys = append(objects.Keys, key)
+	}
+	for _, selector := range selectors {
+		objects.Namespaces = append(objects.Namespaces, selector.Namespace)
+	}
+	// for now, since the whole volume is written again on any change, we have a common path
+	// for both create and update; but if we change the file system interaction mechanism such
+	// that create and update are treated differently, we'll need separate callbacks for each
+	upsertRanger := func(key, value interface{}) bool {
+		k, _ := key.(string)
+		// objects a share references that do not exist yet are stored as their key
+		obj, _ := value.(runtime.Object)
+		if obj == nil || !affectsVolume(keys, selectors, k, obj) {
+			return true
 		}
-		objcache.RegisterSecretUpsertCallback(hpv.VolID, upsertRangerSec)
-		deleteRangerSec := func(key, value interface{}) bool {
-			return commonDeleteRanger(podSecretsPath, hpv.SharedDataKey, key)
+		unlock, ok := lockCurrentVolume(hpv)
+		if !ok {
+			return true
 		}
-		objcache.RegisterSecretDeleteCallback(hpv.VolID, deleteRangerSec)
-	default:
-		return fmt.Errorf("invalid share backing resource kind %s", hpv.SharedDataKind)
+		defer unlock()
+		if err := updateVolume(hpv, fmt.Sprintf("the %s %s", kindKey, k)); err != nil {
+			ProcessFileSystemError(hpv, obj, err)
+		}
+
+		// we always return true in the golang ranger to still attempt additional items
+		// on the off chance the filesystem error received was intermittent and other items
+		// will succeed ... remember, the ranger predominantly deals with pushing backing resource
+		// updates to disk
+		return true
 	}
-	return nil
+	objcache.RegisterObjectUpsertCallback(kindKey, hpv.VolID, objects, upsertRanger)
+	deleteRanger := func(key, value interface{}) bool {
+		k, _ := key.(string)
+		if !affectsVolume(keys, selectors, k, value) {
+			return true
+		}
+		unlock, ok := lockCurrentVolume(hpv)
+		if !ok {
+			return true
+		}
+		defer unlock()
+		var err error
+		if keys[k] {
+			recordVolumeEvent(hpv, corev1.EventTypeWarning, BackingResourceMissingReason,
+				"the %s %s of the share %s was deleted, its data is removed from the volume", kindKey, k, hpv.SharedDataId)
+			err = syncVolume(hpv)
+		} else {
+			err = updateVolume(hpv, fmt.Sprintf("the %s %s", kindKey, k))
+		}
+		if err != nil {
+			klog.Warningf("share %s vol %s target path %s delete error %s",
+				hpv.SharedDataId, hpv.VolID, hpv.TargetPath, err.Error())
+		}
+		return true
+	}
+	objcache.RegisterObjectDeleteCallback(kindKey, hpv.VolID, objects, deleteRanger)
 }
 
 func (hp *hostPath) mapVolumeToPod(hpv *hostPathVolume) error {
@@ -449,21 +718,45 @@ func (hp *hostPath) mapVolumeToPod(hpv *hostPathVolume) error {
 	if err != nil {
 		return err
 	}
+	hp.registerShareCallbacks(hpv)
+	return nil
+}
+
+// lockCurrentVolume locks a volume for a callback of the cache, returning false if the volume was deleted or
+// replaced in the meantime, in which case its callbacks no longer apply
+func lockCurrentVolume(hpv *hostPathVolume) (func(), bool) {
+	unlock := hostPathVolumes.lockVolume(hpv.VolID)
+	if !hostPathVolumes.current(hpv) {
+		unlock()
+		return nil, false
+	}
+	return unlock, true
+}
+
+// registerShareCallbacks registers the callbacks applying the updates and the delete of its share to a volume;
+// the cache calls those of each volume using the share, so that every one of them is checked on its own
+func (hp *hostPath) registerShareCallbacks(hpv *hostPathVolume) {
 	deleteRangerShare := func(key, value interface{}) bool {
-		return shareDeleteRanger(hp, key)
+		if unlock, ok := lockCurrentVolume(hpv); ok {
+			defer unlock()
+			shareDeleted(hpv, key.(string))
+		}
+		return true
 	}
-	objcache.RegisterShareDeleteCallback(hpv.VolID, deleteRangerShare)
+	objcache.RegisterShareDeleteCallback(hpv.VolID, hpv.SharedDataId, deleteRangerShare)
 	updateRangerShare := func(key, value interface{}) bool {
-		return shareUpdateRanger(key, value)
+		if unlock, ok := lockCurrentVolume(hpv); ok {
+			defer unlock()
+			shareUpdated(hpv, value.(sharev1alpha1.ShareObject))
+		}
+		return true
 	}
-	objcache.RegisterShareUpdateCallback(hpv.VolID, updateRangerShare)
-
-	return nil
+	objcache.RegisterShareUpdateCallback(hpv.VolID, hpv.SharedDataId, updateRangerShare)
 }
 
 // createVolume create the directory for the hostpath volume.
 // It returns the volume path or err if one occurs.
-func (hp *hostPath) createHostpathVolume(volID, targetPath string, volCtx map[string]string, share *sharev1alpha1.Share, cap int64, volAccessType accessType) (*hostPathVolume, error) {
+func (hp *hostPath) createHostpathVolume(volID, targetPath string, volCtx map[string]string, share sharev1alpha1.ShareObject, cap int64, volAccessType accessType) (*hostPathVolume, error) {
 	volPath := hp.getVolumePath(volID, volCtx)
 	switch volAccessType {
 	case mountAccess:
@@ -478,24 +771,62 @@ func (hp *hostPath) createHostpathVolume(volID, targetPath string, volCtx map[st
 
 	podNamespace, podName, podUID, podSA := getPodDetails(volCtx)
 	hostpathVol := &hostPathVolume{
-		VolID:          volID,
-		VolSize:        cap,
-		VolPath:        volPath,
-		VolAccessType:  volAccessType,
-		TargetPath:     targetPath,
-		PodNamespace:   podNamespace,
-		PodName:        podName,
-		PodUID:         podUID,
-		PodSA:          podSA,
-		SharedDataKind: share.Spec.BackingResource.Kind,
-		SharedDataKey:  objcache.BuildKey(share.Spec.BackingResource.Namespace, share.Spec.BackingResource.Name),
-		SharedDataId:   share.Name,
-		Allowed:        true,
-	}
-	hostPathVolumes[volID] = hostpathVol
+		VolID:         volID,
+		VolSize:       cap,
+		VolPath:       volPath,
+		VolAccessType: volAccessType,
+		TargetPath:    targetPath,
+		PodNamespace:  podNamespace,
+		PodName:       podName,
+		PodUID:        podUID,
+		PodSA:         podSA,
+		SharedData:    sharedDataFromShare(share),
+		SharedDataId:  objcache.GetShareKey(share),
+		Allowed:       true,
+		UpdatePolicy:  share.GetSpec().UpdatePolicy,
+		// the default modes of the share are taken when the volume is mounted, like its update policy
+		DefaultMode:          share.GetSpec().DefaultMode,
+		SecretDefaultMode:    share.GetSpec().SecretDefaultMode,
+		ConfigMapDefaultMode: share.GetSpec().ConfigMapDefaultMode,
+	}
+	if policy := strings.TrimSpace(volCtx[ProjectedResourceUpdatePolicyKey]); len(policy) > 0 {
+		hostpathVol.UpdatePolicy = sharev1alpha1.UpdatePolicy(policy)
+	}
+	for key, mode := range map[string]**int32{
+		ProjectedResourceDefaultModeKey:          &hostpathVol.DefaultMode,
+		ProjectedResourceSecretDefaultModeKey:    &hostpathVol.SecretDefaultMode,
+		ProjectedResourceConfigMapDefaultModeKey: &hostpathVol.ConfigMapDefaultMode,
+	} {
+		value, ok := volCtx[key]
+		if !ok {
+			continue
+		}
+		// the volume attributes are checked when the volume is published
+		if m, err := validation.ParseFileMode(value); err == nil {
+			*mode = &m
+		}
+	}
+	if pod, err := client.GetPod(podNamespace, podName); err == nil && pod.Spec.SecurityContext != nil {
+		hostpathVol.RunAsUser = pod.Spec.SecurityContext.RunAsUser
+		hostpathVol.FSGroup = pod.Spec.SecurityContext.FSGroup
+	} else if err != nil {
+		klog.V(2).Infof("could not get pod %s:%s for its security context, its files are owned by the driver: %s",
+			podNamespace, podName, err.Error())
+	}
+	hostPathVolumes.put(hostpathVol)
+	updateVolumeMetrics()
 	return hostpathVol, nil
 }
 
+// updateVolumeMetrics sets the number of volumes published on the node for each share
+func updateVolumeMetrics() {
+	volumesByShare := map[string]int{}
+	for _, hpv := range hostPathVolumes.list() {
+		volumesByShare[hpv.SharedDataId]++
+	}
+	metrics.SetVolumes(volumesByShare)
+}
+
 func isDirEmpty(name string) (bool, error) {
 	f, err := os.Open(name)
 	if err != nil {
@@ -520,11 +851,11 @@ func deleteIfEmpty(name string) {
 	}
 }
 
-// deleteVolume deletes the directory for the hostpath volume.
+// deleteVolume deletes the directory for the hostpath volume; the lock of the volume is held by the caller.
 func (hp *hostPath) deleteHostpathVolume(volID string) error {
 	klog.V(4).Infof("deleting hostpath volume: %s", volID)
 
-	hpv, ok := hostPathVolumes[volID]
+	hpv, ok := hostPathVolumes.get(volID)
 	if ok {
 		// reminder, path is filepath.Join(DataRoot, volID, podNamespace, podName, podUID, podSA)
 		// delete SA dir
@@ -540,18 +871,22 @@ func (hp *hostPath) deleteHostpathVolume(volID string) error {
 		deleteIfEmpty(namespacePath)
 		volidPath := filepath.Dir(namespacePath)
 		deleteIfEmpty(volidPath)
-		delete(hostPathVolumes, volID)
+		hostPathVolumes.remove(volID)
+		updateVolumeMetrics()
 		storeVolMapToDisk()
 	}
-	objcache.UnregisterSecretUpsertCallback(volID)
-	objcache.UnregisterSecretDeleteCallback(volID)
-	objcache.UnregisterConfigMapDeleteCallback(volID)
-	objcache.UnregisterConfigMapUpsertCallback(volID)
+	objcache.UnregisterObjectCallbacks(volID)
 	objcache.UnregisterShareDeleteCallback(volID)
 	objcache.UnregisterShareUpdateCallback(volID)
 	return nil
 }
 
+// storeVolume saves the state of a volume, whose lock is held by the caller, and persists the volumes
+func storeVolume(hpv *hostPathVolume) error {
+	hostPathVolumes.save(hpv)
+	return storeVolMapToDisk()
+}
+
 func storeVolMapToDisk() error {
 	fileWriteLock.Lock()
 	defer fileWriteLock.Unlock()
@@ -563,11 +898,8 @@ func storeVolMapToDisk() error {
 	}
 	defer dataFile.Close()
 	dataEncoder := gob.NewEncoder(dataFile)
-	mapCopy := map[string]hostPathVolume{}
-	for k, v := range hostPathVolumes {
-		mapCopy[k] = *v
-	}
-	return dataEncoder.Encode(mapCopy)
+	// listed with the file lock held, so that the last write has the latest state of the volumes
+	return dataEncoder.Encode(hostPathVolumes.list())
 }
 
 func (hp *hostPath) loadVolMapFromDisk() error {
@@ -588,25 +920,60 @@ func (hp *hostPath) loadVolMapFromDisk() error {
 		klog.Warningf("error decoding map file: %s", err.Error())
 		return err
 	}
-	hostPathVolumes = map[string]*hostPathVolume{}
 	for k, v := range mapCopy {
-		klog.V(4).Infof("loadVolMapFromDisk looking at volume %s hpv %#v", k, v)
-		pod, err := client.GetPod(v.PodNamespace, v.PodName)
-		if err != nil {
-			klog.V(2).Infof("loadVolMapFromDisk could not find pod %s:%s so dropping: %s",
-				v.PodNamespace, v.PodName, err.Error())
-			continue
-		}
-		if string(pod.UID) != v.PodUID {
-			klog.V(2).Infof("loadVolMapFromDisk found pod %s:%s but UIDs do no match so dropping: %s vs %s",
-				v.PodNamespace, v.PodName, string(pod.UID), v.PodUID)
-			continue
-		}
-		hostPathVolumes[k] = &v
-		err = hp.mapVolumeToPod(&v)
-		if err != nil {
-			klog.Warningf("loadVolMapFromDisk error mapping volume %s to shares: %s", k, err.Error())
-		}
+		hp.loadVolume(k, v)
 	}
+	updateVolumeMetrics()
 	return nil
 }
+
+// loadVolume restores a volume persisted on disk, unless its pod is gone
+func (hp *hostPath) loadVolume(volID string, v hostPathVolume) {
+	klog.V(4).Infof("loadVolMapFromDisk looking at volume %s hpv %#v", volID, v)
+	unlock := hostPathVolumes.lockVolume(volID)
+	defer unlock()
+	// volumes published while the map was being loaded are kept
+	if _, ok := hostPathVolumes.get(volID); ok {
+		return
+	}
+	pod, err := client.GetPod(v.PodNamespace, v.PodName)
+	if err != nil {
+		klog.V(2).Infof("loadVolMapFromDisk could not find pod %s:%s so dropping: %s",
+			v.PodNamespace, v.PodName, err.Error())
+		return
+	}
+	if string(pod.UID) != v.PodUID {
+		klog.V(2).Infof("loadVolMapFromDisk found pod %s:%s but UIDs do no match so dropping: %s vs %s",
+			v.PodNamespace, v.PodName, string(pod.UID), v.PodUID)
+		return
+	}
+	hpv := &v
+	migrateSharedData(hpv)
+	hostPathVolumes.put(hpv)
+	if hpv.UpdatePolicy == sharev1alpha1.UpdatePolicyOnPodRestart {
+		// the content written when the pod was started is still on disk; a driver restart
+		// is not a pod restart, so do not refresh it
+		hp.registerShareCallbacks(hpv)
+		return
+	}
+	if err := hp.mapVolumeToPod(hpv); err != nil {
+		klog.Warningf("loadVolMapFromDisk error mapping volume %s to shares: %s", volID, err.Error())
+	}
+}
+
+// migrateSharedData sets the SharedData of a volume persisted before a share could have several backing
+// resources from its legacy SharedDataKey and SharedDataKind
+func migrateSharedData(hpv *hostPathVolume) {
+	if len(hpv.SharedData) > 0 || len(strings.TrimSpace(hpv.SharedDataKind)) == 0 {
+		return
+	}
+	klog.V(2).Infof("loadVolMapFromDisk migrating the %s %s of volume %s", hpv.SharedDataKind, hpv.SharedDataKey, hpv.VolID)
+	// the apiVersion of ConfigMaps and Secrets is left empty, as sharedDataFromShare does, so that the share
+	// is not seen as changed by its next update
+	hpv.SharedData = []sharedDataItem{{
+		Kind: strings.TrimSpace(hpv.SharedDataKind),
+		Key:  hpv.SharedDataKey,
+	}}
+	hpv.SharedDataKey = ""
+	hpv.SharedDataKind = ""
+}
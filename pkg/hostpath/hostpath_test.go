@@ -17,19 +17,26 @@ limitations under the License.
 package hostpath
 
 import (
+	"encoding/gob"
+	"fmt"
 	sharev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
 	"io/ioutil"
 	authorizationv1 "k8s.io/api/authorization/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
 	fakekubetesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"github.com/openshift/csi-driver-projected-resource/pkg/cache"
 	"github.com/openshift/csi-driver-projected-resource/pkg/client"
@@ -44,7 +51,7 @@ func testHostPathDriver() (*hostPath, string, string, error) {
 	if err != nil {
 		return nil, "", "", err
 	}
-	hp, err := NewHostPathDriver(tmpDir1, tmpDir2, "ut-driver", "nodeID1", "endpoint1", 0, "version1")
+	hp, err := NewHostPathDriver(tmpDir1, filepath.Join(tmpDir2, "staging"), tmpDir2, "ut-driver", "nodeID1", "endpoint1", 0, 0, "version1")
 	return hp, tmpDir1, tmpDir2, err
 }
 
@@ -58,6 +65,121 @@ func seedVolumeContext() map[string]string {
 	return volCtx
 }
 
+// legacyHostPathVolume is the hostPathVolume persisted before a share could have several backing resources
+type legacyHostPathVolume struct {
+	VolName        string
+	VolID          string
+	VolSize        int64
+	VolPath        string
+	VolAccessType  accessType
+	TargetPath     string
+	SharedDataKey  string
+	SharedDataKind string
+	SharedDataId   string
+	PodNamespace   string
+	PodName        string
+	PodUID         string
+	PodSA          string
+	Allowed        bool
+}
+
+func TestLoadLegacyVolumeMap(t *testing.T) {
+	hp, dir1, dir2, err := testHostPathDriver()
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := ioutil.TempDir(os.TempDir(), "ut")
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	hostPathVolumes = newVolumeStore()
+	defer func() { hostPathVolumes = newVolumeStore() }()
+
+	kubeClient := fakekubeclientset.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "podNamespace", Name: "podName", UID: "podUID"},
+	})
+	kubeClient.PrependReactor("create", "subjectaccessreviews", func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+	})
+	client.SetClient(kubeClient)
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share-legacy",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "Secret",
+				APIVersion: "v1",
+				Name:       "secret1",
+				Namespace:  "namespace",
+			},
+		},
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "secret1", Namespace: "namespace"}}
+	cache.UpsertSecret(secret)
+	cache.AddShare(share)
+	defer cache.DelShare(share)
+	defer cache.DelSecret(secret)
+
+	dataFile, err := os.Create(volMapOnDiskPath)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	err = gob.NewEncoder(dataFile).Encode(map[string]legacyHostPathVolume{
+		"volID-legacy": {
+			VolID:          "volID-legacy",
+			VolPath:        filepath.Join(dir1, "volID-legacy"),
+			VolAccessType:  mountAccess,
+			TargetPath:     targetPath,
+			SharedDataKey:  "namespace:secret1",
+			SharedDataKind: "Secret",
+			SharedDataId:   "share-legacy",
+			PodNamespace:   "podNamespace",
+			PodName:        "podName",
+			PodUID:         "podUID",
+			PodSA:          "podSA",
+			Allowed:        true,
+		},
+	})
+	dataFile.Close()
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+
+	if err := hp.loadVolMapFromDisk(); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	defer hp.deleteHostpathVolume("volID-legacy")
+	hpv, ok := hostPathVolumes.get("volID-legacy")
+	if !ok {
+		t.Fatalf("expected the legacy volume to be loaded")
+	}
+	expected := sharedDataFromShare(share)
+	if !reflect.DeepEqual(hpv.SharedData, expected) || len(hpv.SharedDataKey) > 0 || len(hpv.SharedDataKind) > 0 {
+		t.Fatalf("expected the shared data %#v got %#v", expected, hpv)
+	}
+	if foundSecret, _ := findSharedItems(targetPath, t); !foundSecret {
+		t.Fatalf("secret not found")
+	}
+
+	// an update leaving the share as it was does not change the migrated volume
+	recorder := record.NewFakeRecorder(10)
+	client.SetRecorder(recorder)
+	defer client.SetRecorder(nil)
+	cache.UpdateShare(share)
+	if updated, _ := hostPathVolumes.get("volID-legacy"); updated != hpv || !reflect.DeepEqual(updated.SharedData, expected) {
+		t.Fatalf("expected the migrated volume to be kept as it was got %#v", updated)
+	}
+	select {
+	case event := <-recorder.Events:
+		t.Fatalf("unexpected event %s", event)
+	default:
+	}
+}
+
 func TestCreateHostPathVolumeBadAccessType(t *testing.T) {
 	hp, dir1, dir2, err := testHostPathDriver()
 	if err != nil {
@@ -413,7 +535,7 @@ func TestPermChanges(t *testing.T) {
 	sarClient.PrependReactor("create", "subjectaccessreviews", denyReactorFunc)
 	client.SetClient(sarClient)
 
-	shareUpdateRanger(share.Name, share)
+	cache.UpdateShare(share)
 
 	foundSecret, _ = findSharedItems(targetPath, t)
 	if foundSecret {
@@ -424,7 +546,7 @@ func TestPermChanges(t *testing.T) {
 	sarClient.PrependReactor("create", "subjectaccessreviews", acceptReactorFunc)
 	client.SetClient(sarClient)
 
-	shareUpdateRanger(share.Name, share)
+	cache.UpdateShare(share)
 
 	foundSecret, _ = findSharedItems(targetPath, t)
 	if !foundSecret {
@@ -432,6 +554,1085 @@ func TestPermChanges(t *testing.T) {
 	}
 }
 
+func TestShareEvents(t *testing.T) {
+	hp, dir1, dir2, err := testHostPathDriver()
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := ioutil.TempDir(os.TempDir(), "ut")
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	acceptReactorFunc := func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+	}
+	sarClient := fakekubeclientset.NewSimpleClientset()
+	sarClient.PrependReactor("create", "subjectaccessreviews", acceptReactorFunc)
+	client.SetClient(sarClient)
+
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share-events",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "Secret",
+				APIVersion: "v1",
+				Name:       "secret1",
+				Namespace:  "namespace",
+			},
+		},
+	}
+	client.SetSharesLister(&fakeShareLister{share: share})
+	cache.AddShare(share)
+	primeSecretVolume(hp, targetPath, share, t)
+
+	recorder := record.NewFakeRecorder(10)
+	recorder.IncludeObject = true
+	client.SetRecorder(recorder)
+	defer client.SetRecorder(nil)
+
+	expectEvents := func(reason string) {
+		for _, kind := range []string{"Pod", "Share"} {
+			select {
+			case event := <-recorder.Events:
+				if !strings.Contains(event, reason) || !strings.Contains(event, "kind="+kind+",") {
+					t.Fatalf("expected a %s event for the %s got %s", reason, kind, event)
+				}
+			default:
+				t.Fatalf("expected a %s event for the %s", reason, kind)
+			}
+		}
+	}
+
+	denyReactorFunc := func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: false}}, nil
+	}
+	sarClient = fakekubeclientset.NewSimpleClientset()
+	sarClient.PrependReactor("create", "subjectaccessreviews", denyReactorFunc)
+	client.SetClient(sarClient)
+	cache.UpdateShare(share)
+	expectEvents(ShareAccessRevokedReason)
+
+	sarClient = fakekubeclientset.NewSimpleClientset()
+	sarClient.PrependReactor("create", "subjectaccessreviews", acceptReactorFunc)
+	client.SetClient(sarClient)
+	cache.UpdateShare(share)
+	expectEvents(ShareAccessGrantedReason)
+
+	cache.DelShare(share)
+	expectEvents(ShareDeletedReason)
+}
+
+func TestShareWithMultiplePods(t *testing.T) {
+	hp, dir1, dir2, err := testHostPathDriver()
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+
+	// only the service account podSA2 loses access to the share once deniedSA is set
+	deniedSA := ""
+	sarReactorFunc := func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		sar := action.(fakekubetesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		allowed := !strings.HasSuffix(sar.Spec.User, ":"+deniedSA)
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: allowed}}, nil
+	}
+	sarClient := fakekubeclientset.NewSimpleClientset()
+	sarClient.PrependReactor("create", "subjectaccessreviews", sarReactorFunc)
+	client.SetClient(sarClient)
+
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share-multiple-pods",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "Secret",
+				APIVersion: "v1",
+				Name:       "secret1",
+				Namespace:  "namespace",
+			},
+		},
+	}
+	client.SetSharesLister(&fakeShareLister{share: share})
+	cache.AddShare(share)
+	cache.UpsertSecret(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "secret1", Namespace: "namespace"}})
+
+	targetPaths := []string{}
+	for i := 1; i <= 3; i++ {
+		targetPath, err := ioutil.TempDir(os.TempDir(), "ut")
+		if err != nil {
+			t.Fatalf("err on targetPath %s", err.Error())
+		}
+		defer os.RemoveAll(targetPath)
+		targetPaths = append(targetPaths, targetPath)
+
+		volID := fmt.Sprintf("volID-multiple-pods-%d", i)
+		volCtx := seedVolumeContext()
+		volCtx[CSIPodName] = fmt.Sprintf("podName%d", i)
+		volCtx[CSIPodSA] = fmt.Sprintf("podSA%d", i)
+		volCtx[CSIPodUID] = fmt.Sprintf("podUID%d", i)
+		hpv, err := hp.createHostpathVolume(volID, targetPath, volCtx, share, 0, mountAccess)
+		if err != nil {
+			t.Fatalf("unexpected err %s", err.Error())
+		}
+		defer hp.deleteHostpathVolume(volID)
+		if err = hp.mapVolumeToPod(hpv); err != nil {
+			t.Fatalf("unexpected err %s", err.Error())
+		}
+	}
+
+	expectSecrets := func(step string, expected ...bool) {
+		for i, targetPath := range targetPaths {
+			if foundSecret, _ := findSharedItems(targetPath, t); foundSecret != expected[i] {
+				t.Fatalf("%s: expected secret found %v for pod %d", step, expected[i], i+1)
+			}
+		}
+	}
+	expectSecrets("publish", true, true, true)
+
+	deniedSA = "podSA2"
+	cache.UpdateShare(share)
+	expectSecrets("access revoked", true, false, true)
+
+	deniedSA = ""
+	cache.UpdateShare(share)
+	expectSecrets("access granted", true, true, true)
+
+	cache.DelShare(share)
+	expectSecrets("share deleted", false, false, false)
+}
+
+func TestMultipleBackingResources(t *testing.T) {
+	hp, dir1, dir2, err := testHostPathDriver()
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := ioutil.TempDir(os.TempDir(), "ut")
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	acceptReactorFunc := func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+	}
+	sarClient := fakekubeclientset.NewSimpleClientset()
+	sarClient.PrependReactor("create", "subjectaccessreviews", acceptReactorFunc)
+	client.SetClient(sarClient)
+
+	cache.UpsertConfigMap(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "ca-bundle", Namespace: "namespace"},
+		Data:       map[string]string{"ca.crt": "ca"},
+	})
+	cache.UpsertConfigMap(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "proxy", Namespace: "namespace"},
+		Data:       map[string]string{"proxy": "http://proxy"},
+	})
+	cache.UpsertSecret(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "pull-secret", Namespace: "namespace"},
+		Data:       map[string][]byte{"auth": []byte("auth")},
+	})
+
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share1",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResources: []sharev1alpha1.BackingResource{
+				{
+					Kind:       "ConfigMap",
+					APIVersion: "v1",
+					Name:       "ca-bundle",
+					Namespace:  "namespace",
+				},
+				{
+					Kind:       "Secret",
+					APIVersion: "v1",
+					Name:       "pull-secret",
+					Namespace:  "namespace",
+				},
+			},
+		},
+	}
+	client.SetSharesLister(&fakeShareLister{share: share})
+	cache.AddShare(share)
+
+	hpv, err := hp.createHostpathVolume("volID", targetPath, seedVolumeContext(), share, 0, mountAccess)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if err = hp.mapVolumeToPod(hpv); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+
+	caPath := filepath.Join(targetPath, "configmaps", "namespace:ca-bundle", "ca.crt")
+	proxyPath := filepath.Join(targetPath, "configmaps", "namespace:proxy", "proxy")
+	authPath := filepath.Join(targetPath, "secrets", "namespace:pull-secret", "auth")
+	for _, path := range []string{caPath, authPath} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("expected %s to exist: %s", path, err.Error())
+		}
+	}
+
+	updatedShare := share.DeepCopy()
+	updatedShare.Spec.BackingResources = []sharev1alpha1.BackingResource{
+		share.Spec.BackingResources[0],
+		{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+			Name:       "proxy",
+			Namespace:  "namespace",
+		},
+	}
+	cache.UpdateShare(updatedShare)
+
+	for _, path := range []string{caPath, proxyPath} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("expected %s to exist: %s", path, err.Error())
+		}
+	}
+	if _, err := os.Stat(filepath.Join(targetPath, "secrets")); !os.IsNotExist(err) {
+		t.Fatalf("expected secrets directory to be removed: %v", err)
+	}
+
+	cache.DelShare(updatedShare)
+	for _, path := range []string{caPath, proxyPath} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be removed: %v", path, err)
+		}
+	}
+}
+
+func TestBackingResourceItems(t *testing.T) {
+	hp, dir1, dir2, err := testHostPathDriver()
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := ioutil.TempDir(os.TempDir(), "ut")
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	acceptReactorFunc := func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+	}
+	sarClient := fakekubeclientset.NewSimpleClientset()
+	sarClient.PrependReactor("create", "subjectaccessreviews", acceptReactorFunc)
+	client.SetClient(sarClient)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "trusted-ca", Namespace: "namespace"},
+		Data: map[string]string{
+			"ca-bundle.crt": "ca",
+			"unlisted":      "unlisted",
+		},
+	}
+	cache.UpsertConfigMap(cm)
+
+	mode := int32(0600)
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share1",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "ConfigMap",
+				APIVersion: "v1",
+				Name:       "trusted-ca",
+				Namespace:  "namespace",
+				Items: []corev1.KeyToPath{
+					{
+						Key:  "ca-bundle.crt",
+						Path: "ssl/certs/ca-bundle.crt",
+						Mode: &mode,
+					},
+					{
+						Key:  "missing",
+						Path: "missing",
+					},
+				},
+			},
+		},
+	}
+	client.SetSharesLister(&fakeShareLister{share: share})
+	cache.AddShare(share)
+
+	hpv, err := hp.createHostpathVolume("volID", targetPath, seedVolumeContext(), share, 0, mountAccess)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if err = hp.mapVolumeToPod(hpv); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+
+	caPath := filepath.Join(targetPath, "ssl", "certs", "ca-bundle.crt")
+	info, err := os.Stat(caPath)
+	if err != nil {
+		t.Fatalf("expected %s to exist: %s", caPath, err.Error())
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expected mode 0600 got %o", info.Mode().Perm())
+	}
+	for _, path := range []string{"configmaps", "missing"} {
+		if _, err := os.Stat(filepath.Join(targetPath, path)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to not exist: %v", path, err)
+		}
+	}
+
+	cm.Data["ca-bundle.crt"] = "rotated"
+	cache.UpsertConfigMap(cm)
+	content, err := ioutil.ReadFile(caPath)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if string(content) != "rotated" {
+		t.Fatalf("expected updated content got %s", string(content))
+	}
+
+	cache.DelConfigMap(cm)
+	if _, err := os.Stat(filepath.Join(targetPath, "ssl")); !os.IsNotExist(err) {
+		t.Fatalf("expected ssl directory to be removed: %v", err)
+	}
+}
+
+func TestUpdatePolicyOnPodRestart(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		sharePolicy  sharev1alpha1.UpdatePolicy
+		volumePolicy string
+		expected     string
+	}{
+		{
+			name:     "default policy updates content",
+			expected: "rotated",
+		},
+		{
+			name:        "share policy freezes content",
+			sharePolicy: sharev1alpha1.UpdatePolicyOnPodRestart,
+			expected:    "ca",
+		},
+		{
+			name:         "volume attribute overrides share policy",
+			sharePolicy:  sharev1alpha1.UpdatePolicyImmediate,
+			volumePolicy: string(sharev1alpha1.UpdatePolicyOnPodRestart),
+			expected:     "ca",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			hp, dir1, dir2, err := testHostPathDriver()
+			if err != nil {
+				t.Fatalf("%s", err.Error())
+			}
+			defer os.RemoveAll(dir1)
+			defer os.RemoveAll(dir2)
+			targetPath, err := ioutil.TempDir(os.TempDir(), "ut")
+			if err != nil {
+				t.Fatalf("err on targetPath %s", err.Error())
+			}
+			defer os.RemoveAll(targetPath)
+			acceptReactorFunc := func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+				return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+			}
+			sarClient := fakekubeclientset.NewSimpleClientset()
+			sarClient.PrependReactor("create", "subjectaccessreviews", acceptReactorFunc)
+			client.SetClient(sarClient)
+
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "trusted-ca", Namespace: "namespace"},
+				Data:       map[string]string{"ca-bundle.crt": "ca"},
+			}
+			cache.UpsertConfigMap(cm)
+			defer cache.DelConfigMap(cm)
+
+			share := &sharev1alpha1.Share{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "share1",
+				},
+				Spec: sharev1alpha1.ShareSpec{
+					BackingResource: sharev1alpha1.BackingResource{
+						Kind:       "ConfigMap",
+						APIVersion: "v1",
+						Name:       "trusted-ca",
+						Namespace:  "namespace",
+						Items:      []corev1.KeyToPath{{Key: "ca-bundle.crt", Path: "ca-bundle.crt"}},
+					},
+					UpdatePolicy: test.sharePolicy,
+				},
+			}
+			client.SetSharesLister(&fakeShareLister{share: share})
+			cache.AddShare(share)
+			defer cache.DelShare(share)
+
+			volCtx := seedVolumeContext()
+			if len(test.volumePolicy) > 0 {
+				volCtx[ProjectedResourceUpdatePolicyKey] = test.volumePolicy
+			}
+			hpv, err := hp.createHostpathVolume("volID", targetPath, volCtx, share, 0, mountAccess)
+			if err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			if err = hp.mapVolumeToPod(hpv); err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			defer cache.UnregisterObjectCallbacks(hpv.VolID)
+
+			rotated := cm.DeepCopy()
+			rotated.Data["ca-bundle.crt"] = "rotated"
+			cache.UpsertConfigMap(rotated)
+
+			content, err := ioutil.ReadFile(filepath.Join(targetPath, "ca-bundle.crt"))
+			if err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			if string(content) != test.expected {
+				t.Fatalf("expected content %q got %q", test.expected, string(content))
+			}
+		})
+	}
+}
+
+func TestRemovedKeys(t *testing.T) {
+	hp, dir1, dir2, err := testHostPathDriver()
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := ioutil.TempDir(os.TempDir(), "ut")
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	acceptReactorFunc := func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+	}
+	sarClient := fakekubeclientset.NewSimpleClientset()
+	sarClient.PrependReactor("create", "subjectaccessreviews", acceptReactorFunc)
+	client.SetClient(sarClient)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "namespace"},
+		Data: map[string]string{
+			"kept":    "kept",
+			"renamed": "renamed",
+			"listed":  "listed",
+		},
+	}
+	cache.UpsertConfigMap(cm)
+	defer cache.DelConfigMap(cm)
+
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share1",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResources: []sharev1alpha1.BackingResource{
+				{
+					Kind:       "ConfigMap",
+					APIVersion: "v1",
+					Name:       "settings",
+					Namespace:  "namespace",
+				},
+				{
+					Kind:       "ConfigMap",
+					APIVersion: "v1",
+					Name:       "settings",
+					Namespace:  "namespace",
+					Items:      []corev1.KeyToPath{{Key: "listed", Path: "conf/listed"}},
+				},
+			},
+		},
+	}
+	client.SetSharesLister(&fakeShareLister{share: share})
+	cache.AddShare(share)
+	defer cache.DelShare(share)
+
+	hpv, err := hp.createHostpathVolume("volID", targetPath, seedVolumeContext(), share, 0, mountAccess)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if err = hp.mapVolumeToPod(hpv); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	defer cache.UnregisterObjectCallbacks(hpv.VolID)
+
+	dir := filepath.Join(targetPath, "configmaps", "namespace:settings")
+	keptPath := filepath.Join(dir, "kept")
+	written := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(keptPath, written, written); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+
+	updated := cm.DeepCopy()
+	updated.Data = map[string]string{
+		"kept":  "kept",
+		"moved": "renamed",
+	}
+	cache.UpsertConfigMap(updated)
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"kept", "moved"}) {
+		t.Fatalf("expected only the current keys got %v", names)
+	}
+	if _, err := os.Stat(filepath.Join(targetPath, "conf")); !os.IsNotExist(err) {
+		t.Fatalf("expected the directory of the removed listed key to be removed: %v", err)
+	}
+	info, err := os.Stat(keptPath)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if !info.ModTime().Equal(written) {
+		t.Fatalf("expected unchanged file to not be rewritten, modified at %v", info.ModTime())
+	}
+}
+
+func TestAtomicLayout(t *testing.T) {
+	hp, dir1, dir2, err := testHostPathDriver()
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := ioutil.TempDir(os.TempDir(), "ut")
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	acceptReactorFunc := func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+	}
+	sarClient := fakekubeclientset.NewSimpleClientset()
+	sarClient.PrependReactor("create", "subjectaccessreviews", acceptReactorFunc)
+	client.SetClient(sarClient)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "namespace"},
+		Data: map[string][]byte{
+			"username": []byte("user"),
+			"password": []byte("old"),
+		},
+	}
+	cache.UpsertSecret(secret)
+	defer cache.DelSecret(secret)
+
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share1",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "Secret",
+				APIVersion: "v1",
+				Name:       "creds",
+				Namespace:  "namespace",
+				Items: []corev1.KeyToPath{
+					{Key: "username", Path: "username"},
+					{Key: "password", Path: "auth/password"},
+				},
+			},
+		},
+	}
+	client.SetSharesLister(&fakeShareLister{share: share})
+	cache.AddShare(share)
+	defer cache.DelShare(share)
+
+	hpv, err := hp.createHostpathVolume("volID", targetPath, seedVolumeContext(), share, 0, mountAccess)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if err = hp.mapVolumeToPod(hpv); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	defer cache.UnregisterObjectCallbacks(hpv.VolID)
+
+	dataDir, err := os.Readlink(filepath.Join(targetPath, "..data"))
+	if err != nil {
+		t.Fatalf("expected ..data to be a symlink: %s", err.Error())
+	}
+	if !strings.HasPrefix(dataDir, "..") {
+		t.Fatalf("expected ..data to point to a timestamped directory got %s", dataDir)
+	}
+	for _, path := range []string{"username", "auth"} {
+		link, err := os.Readlink(filepath.Join(targetPath, path))
+		if err != nil {
+			t.Fatalf("expected %s to be a symlink: %s", path, err.Error())
+		}
+		if link != filepath.Join("..data", path) {
+			t.Fatalf("expected %s to point through ..data got %s", path, link)
+		}
+	}
+
+	// an unchanged payload is not written again
+	cache.UpsertSecret(secret.DeepCopy())
+	if current, _ := os.Readlink(filepath.Join(targetPath, "..data")); current != dataDir {
+		t.Fatalf("expected ..data to still point to %s got %s", dataDir, current)
+	}
+
+	updated := secret.DeepCopy()
+	updated.Data = map[string][]byte{"username": []byte("user")}
+	cache.UpsertSecret(updated)
+	current, err := os.Readlink(filepath.Join(targetPath, "..data"))
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if current == dataDir {
+		t.Fatalf("expected ..data to be swapped to a new directory")
+	}
+	entries, err := ioutil.ReadDir(targetPath)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if !reflect.DeepEqual(names, []string{current, "..data", "username"}) {
+		t.Fatalf("expected the previous data directory and removed paths to be gone got %v", names)
+	}
+}
+
+func TestFileModesAndOwnership(t *testing.T) {
+	hp, dir1, dir2, err := testHostPathDriver()
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := ioutil.TempDir(os.TempDir(), "ut")
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	acceptReactorFunc := func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+	}
+	runAsUser := int64(1000)
+	fsGroup := int64(2000)
+	sarClient := fakekubeclientset.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "podName", Namespace: "podNamespace"},
+		Spec: corev1.PodSpec{
+			SecurityContext: &corev1.PodSecurityContext{RunAsUser: &runAsUser, FSGroup: &fsGroup},
+		},
+	})
+	sarClient.PrependReactor("create", "subjectaccessreviews", acceptReactorFunc)
+	client.SetClient(sarClient)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "tls", Namespace: "namespace"},
+		Data:       map[string][]byte{"tls.key": []byte("key")},
+	}
+	cache.UpsertSecret(secret)
+	defer cache.DelSecret(secret)
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "namespace"},
+		Data:       map[string]string{"settings.yaml": "settings"},
+	}
+	cache.UpsertConfigMap(cm)
+	defer cache.DelConfigMap(cm)
+
+	defaultMode := int32(0600)
+	secretMode := int32(0400)
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share1",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResources: []sharev1alpha1.BackingResource{
+				{Kind: "Secret", APIVersion: "v1", Name: "tls", Namespace: "namespace"},
+				{Kind: "ConfigMap", APIVersion: "v1", Name: "settings", Namespace: "namespace"},
+			},
+			DefaultMode:       &defaultMode,
+			SecretDefaultMode: &secretMode,
+		},
+	}
+	client.SetSharesLister(&fakeShareLister{share: share})
+	cache.AddShare(share)
+	defer cache.DelShare(share)
+
+	volCtx := seedVolumeContext()
+	volCtx[ProjectedResourceConfigMapDefaultModeKey] = "0604"
+	hpv, err := hp.createHostpathVolume("volID", targetPath, volCtx, share, 0, mountAccess)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if err = hp.mapVolumeToPod(hpv); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	defer cache.UnregisterObjectCallbacks(hpv.VolID)
+
+	for path, mode := range map[string]os.FileMode{
+		// the fsGroup of the pod can read every file
+		filepath.Join("secrets", "namespace:tls", "tls.key"):               0440,
+		filepath.Join("configmaps", "namespace:settings", "settings.yaml"): 0644,
+	} {
+		info, err := os.Stat(filepath.Join(targetPath, path))
+		if err != nil {
+			t.Fatalf("expected %s to exist: %s", path, err.Error())
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("expected mode %o for %s got %o", mode, path, info.Mode().Perm())
+		}
+		if os.Geteuid() != 0 {
+			continue
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			continue
+		}
+		if int64(stat.Uid) != runAsUser || int64(stat.Gid) != fsGroup {
+			t.Errorf("expected %s to be owned by %d:%d got %d:%d", path, runAsUser, fsGroup, stat.Uid, stat.Gid)
+		}
+	}
+}
+
+func TestVolumeSizeLimitExceeded(t *testing.T) {
+	hp, dir1, dir2, err := testHostPathDriver()
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := ioutil.TempDir(os.TempDir(), "ut")
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	acceptReactorFunc := func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+	}
+	sarClient := fakekubeclientset.NewSimpleClientset()
+	sarClient.PrependReactor("create", "subjectaccessreviews", acceptReactorFunc)
+	client.SetClient(sarClient)
+	recorder := record.NewFakeRecorder(10)
+	client.SetRecorder(recorder)
+	defer client.SetRecorder(nil)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "namespace"},
+		Data:       map[string]string{"settings": "small"},
+	}
+	cache.UpsertConfigMap(cm)
+	defer cache.DelConfigMap(cm)
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share1",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "ConfigMap",
+				APIVersion: "v1",
+				Name:       "settings",
+				Namespace:  "namespace",
+			},
+		},
+	}
+	client.SetSharesLister(&fakeShareLister{share: share})
+	cache.AddShare(share)
+	defer cache.DelShare(share)
+
+	hpv, err := hp.createHostpathVolume("volID", targetPath, seedVolumeContext(), share, 16, mountAccess)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if err = hp.mapVolumeToPod(hpv); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	defer cache.UnregisterObjectCallbacks(hpv.VolID)
+
+	oversized := cm.DeepCopy()
+	oversized.Data["settings"] = strings.Repeat("x", 32)
+	cache.UpsertConfigMap(oversized)
+
+	content, err := ioutil.ReadFile(filepath.Join(targetPath, "configmaps", "namespace:settings", "settings"))
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if string(content) != "small" {
+		t.Fatalf("expected the volume to keep its content got %s", string(content))
+	}
+	// the volumes left by other tests for the same share have events of their own
+	for {
+		select {
+		case event := <-recorder.Events:
+			if strings.Contains(event, "VolumeSizeLimitExceeded") {
+				return
+			}
+		default:
+			t.Fatalf("expected an event for the oversized configmap")
+		}
+	}
+}
+
+func TestBackingResourceSelector(t *testing.T) {
+	hp, dir1, dir2, err := testHostPathDriver()
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := ioutil.TempDir(os.TempDir(), "ut")
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	acceptReactorFunc := func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+	}
+	sarClient := fakekubeclientset.NewSimpleClientset()
+	sarClient.PrependReactor("create", "subjectaccessreviews", acceptReactorFunc)
+	client.SetClient(sarClient)
+
+	bundleLabels := map[string]string{"trust.example.com/bundle": "true"}
+	bundle1 := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "bundle1", Namespace: "selected", Labels: bundleLabels},
+		Data:       map[string]string{"ca.crt": "ca1"},
+	}
+	bundle2 := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "bundle2", Namespace: "selected"},
+		Data:       map[string]string{"ca.crt": "ca2"},
+	}
+	cache.UpsertConfigMap(bundle1)
+	cache.UpsertConfigMap(bundle2)
+
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share1",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "ConfigMap",
+				APIVersion: "v1",
+				Namespace:  "selected",
+				Selector:   &metav1.LabelSelector{MatchLabels: bundleLabels},
+			},
+		},
+	}
+	client.SetSharesLister(&fakeShareLister{share: share})
+	cache.AddShare(share)
+
+	hpv, err := hp.createHostpathVolume("volID", targetPath, seedVolumeContext(), share, 0, mountAccess)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if err = hp.mapVolumeToPod(hpv); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+
+	bundle1Path := filepath.Join(targetPath, "configmaps", "selected:bundle1", "ca.crt")
+	bundle2Path := filepath.Join(targetPath, "configmaps", "selected:bundle2", "ca.crt")
+	if _, err := os.Stat(bundle1Path); err != nil {
+		t.Fatalf("expected %s to exist: %s", bundle1Path, err.Error())
+	}
+	if _, err := os.Stat(bundle2Path); !os.IsNotExist(err) {
+		t.Fatalf("expected %s to not exist: %v", bundle2Path, err)
+	}
+
+	// bundle2 starts matching, bundle1 stops matching
+	bundle2 = bundle2.DeepCopy()
+	bundle2.Labels = bundleLabels
+	cache.UpsertConfigMap(bundle2)
+	bundle1 = bundle1.DeepCopy()
+	bundle1.Labels = nil
+	cache.UpsertConfigMap(bundle1)
+	if _, err := os.Stat(bundle2Path); err != nil {
+		t.Fatalf("expected %s to exist: %s", bundle2Path, err.Error())
+	}
+	if _, err := os.Stat(filepath.Join(targetPath, "configmaps", "selected:bundle1")); !os.IsNotExist(err) {
+		t.Fatalf("expected bundle1 directory to be removed: %v", err)
+	}
+
+	cache.DelConfigMap(bundle2)
+	if _, err := os.Stat(filepath.Join(targetPath, "configmaps")); !os.IsNotExist(err) {
+		t.Fatalf("expected configmaps directory to be removed: %v", err)
+	}
+	cache.DelConfigMap(bundle1)
+	hp.deleteHostpathVolume("volID")
+}
+
+func TestBackingResourceFields(t *testing.T) {
+	hp, dir1, dir2, err := testHostPathDriver()
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := ioutil.TempDir(os.TempDir(), "ut")
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	acceptReactorFunc := func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+	}
+	sarClient := fakekubeclientset.NewSimpleClientset()
+	sarClient.PrependReactor("create", "subjectaccessreviews", acceptReactorFunc)
+	client.SetClient(sarClient)
+
+	endpoint := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Endpoint",
+		"metadata":   map[string]interface{}{"name": "endpoint1", "namespace": "namespace"},
+		"spec": map[string]interface{}{
+			"url":   "https://example.com",
+			"ports": []interface{}{int64(443), int64(8443)},
+		},
+	}}
+	kindKey := cache.KindKey("example.com/v1", "Endpoint")
+	cache.UpsertObject(kindKey, endpoint)
+
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share1",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "Endpoint",
+				APIVersion: "example.com/v1",
+				Name:       "endpoint1",
+				Namespace:  "namespace",
+				Fields: []sharev1alpha1.FieldToPath{
+					{JSONPath: ".spec.url", Path: "url"},
+					{JSONPath: "{.spec.ports[*]}", Path: "config/ports"},
+					{JSONPath: ".spec.missing", Path: "missing"},
+				},
+			},
+		},
+	}
+	client.SetSharesLister(&fakeShareLister{share: share})
+	cache.AddShare(share)
+
+	hpv, err := hp.createHostpathVolume("volID", targetPath, seedVolumeContext(), share, 0, mountAccess)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if err = hp.mapVolumeToPod(hpv); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+
+	expected := map[string]string{
+		"url":          "https://example.com",
+		"config/ports": "443\n8443",
+	}
+	for path, content := range expected {
+		data, err := ioutil.ReadFile(filepath.Join(targetPath, path))
+		if err != nil {
+			t.Fatalf("expected %s to exist: %s", path, err.Error())
+		}
+		if string(data) != content {
+			t.Fatalf("expected %s to contain %q got %q", path, content, string(data))
+		}
+	}
+	if _, err := os.Stat(filepath.Join(targetPath, "missing")); !os.IsNotExist(err) {
+		t.Fatalf("expected missing to not exist: %v", err)
+	}
+
+	endpoint = endpoint.DeepCopy()
+	unstructured.SetNestedField(endpoint.Object, "https://example.org", "spec", "url")
+	cache.UpsertObject(kindKey, endpoint)
+	data, err := ioutil.ReadFile(filepath.Join(targetPath, "url"))
+	if err != nil || string(data) != "https://example.org" {
+		t.Fatalf("expected url to be updated got %q: %v", string(data), err)
+	}
+
+	cache.DelObject(kindKey, endpoint)
+	for path := range expected {
+		if _, err := os.Stat(filepath.Join(targetPath, path)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be removed: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(targetPath, "config")); !os.IsNotExist(err) {
+		t.Fatalf("expected config directory to be removed: %v", err)
+	}
+	hp.deleteHostpathVolume("volID")
+}
+
+func TestNamespacedShareNamespaceDisallowed(t *testing.T) {
+	hp, dir1, dir2, err := testHostPathDriver()
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := ioutil.TempDir(os.TempDir(), "ut")
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	acceptReactorFunc := func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+	}
+	sarClient := fakekubeclientset.NewSimpleClientset()
+	sarClient.PrependReactor("create", "subjectaccessreviews", acceptReactorFunc)
+	client.SetClient(sarClient)
+
+	share := &sharev1alpha1.NamespacedShare{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "share1",
+			Namespace: "podNamespace",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "Secret",
+				APIVersion: "v1",
+				Name:       "secret1",
+				Namespace:  "namespace",
+			},
+		},
+	}
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "namespace",
+			Annotations: map[string]string{sharev1alpha1.AllowedShareNamespacesAnnotation: "podNamespace"},
+		},
+	}
+	setNamespacedShareListers(share, namespace)
+	cache.UpsertSecret(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret1",
+			Namespace: "namespace",
+		},
+	})
+	cache.AddNamespacedShare(share)
+
+	hpv, err := hp.createHostpathVolume("volID", targetPath, seedVolumeContext(), share, 0, mountAccess)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if hpv.SharedDataId != "podNamespace:share1" {
+		t.Fatalf("unexpected shared data id %s", hpv.SharedDataId)
+	}
+	if err = hp.mapVolumeToPod(hpv); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	foundSecret, _ := findSharedItems(targetPath, t)
+	if !foundSecret {
+		t.Fatalf("secret not found")
+	}
+
+	setNamespacedShareListers(share, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "namespace"}})
+	cache.UpdateNamespacedShare(share)
+	foundSecret, _ = findSharedItems(targetPath, t)
+	if foundSecret {
+		t.Fatalf("secret should have been removed")
+	}
+}
+
 func primeSecretVolume(hp *hostPath, targetPath string, share *sharev1alpha1.Share, t *testing.T) *corev1.Secret {
 	volCtx := seedVolumeContext()
 	if share == nil {
@@ -18,6 +18,7 @@ package hostpath
 
 import (
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/protobuf/ptypes/wrappers"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -28,12 +29,15 @@ import (
 type identityServer struct {
 	name    string
 	version string
+	// ready returns why the driver is not ready to serve volumes, or nil once it is
+	ready func() error
 }
 
-func NewIdentityServer(name, version string) *identityServer {
+func NewIdentityServer(name, version string, ready func() error) *identityServer {
 	return &identityServer{
 		name:    name,
 		version: version,
+		ready:   ready,
 	}
 }
 
@@ -55,13 +59,28 @@ func (ids *identityServer) GetPluginInfo(ctx context.Context, req *csi.GetPlugin
 }
 
 func (ids *identityServer) Probe(ctx context.Context, req *csi.ProbeRequest) (*csi.ProbeResponse, error) {
-	return &csi.ProbeResponse{}, nil
+	ready := true
+	if ids.ready != nil {
+		if err := ids.ready(); err != nil {
+			klog.V(4).Infof("Probe: not ready: %s", err.Error())
+			ready = false
+		}
+	}
+	return &csi.ProbeResponse{Ready: &wrappers.BoolValue{Value: ready}}, nil
 }
 
 func (ids *identityServer) GetPluginCapabilities(ctx context.Context, req *csi.GetPluginCapabilitiesRequest) (*csi.GetPluginCapabilitiesResponse, error) {
 	klog.V(5).Infof("Using default capabilities")
 	return &csi.GetPluginCapabilitiesResponse{
 		Capabilities: []*csi.PluginCapability{
+			// the controller service provisions the persistent volumes of the PVCs of a StorageClass of the driver
+			{
+				Type: &csi.PluginCapability_Service_{
+					Service: &csi.PluginCapability_Service{
+						Type: csi.PluginCapability_Service_CONTROLLER_SERVICE,
+					},
+				},
+			},
 			// Even with the use of a DaemonSet so that this plugin runs on every node, this plugin does not
 			// guarantee that the *same* volume is present on all nodes; now, equivalent data could be present
 			// on different nodes via different volumes, as a function of different pods residing on different
@@ -19,27 +19,44 @@ package hostpath
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	sharev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+	objcache "github.com/openshift/csi-driver-projected-resource/pkg/cache"
 	"github.com/openshift/csi-driver-projected-resource/pkg/client"
+	"github.com/openshift/csi-driver-projected-resource/pkg/metrics"
+	"github.com/openshift/csi-driver-projected-resource/pkg/validation"
 	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/mount"
 )
 
 const (
-	TopologyKeyNode           = "topology.hostpath.csi/node"
-	CSIPodName                = "csi.storage.k8s.io/pod.name"
-	CSIPodNamespace           = "csi.storage.k8s.io/pod.namespace"
-	CSIPodUID                 = "csi.storage.k8s.io/pod.uid"
-	CSIPodSA                  = "csi.storage.k8s.io/serviceAccount.name"
-	CSIEphemeral              = "csi.storage.k8s.io/ephemeral"
-	ProjectedResourceShareKey = "share"
+	TopologyKeyNode                     = "topology.hostpath.csi/node"
+	CSIPodName                          = "csi.storage.k8s.io/pod.name"
+	CSIPodNamespace                     = "csi.storage.k8s.io/pod.namespace"
+	CSIPodUID                           = "csi.storage.k8s.io/pod.uid"
+	CSIPodSA                            = "csi.storage.k8s.io/serviceAccount.name"
+	CSIEphemeral                        = "csi.storage.k8s.io/ephemeral"
+	ProjectedResourceShareKey           = "share"
+	ProjectedResourceNamespacedShareKey = "namespacedShare"
+	ProjectedResourceUpdatePolicyKey    = "updatePolicy"
+
+	ProjectedResourceDefaultModeKey          = "defaultMode"
+	ProjectedResourceSecretDefaultModeKey    = "secretDefaultMode"
+	ProjectedResourceConfigMapDefaultModeKey = "configMapDefaultMode"
+	ProjectedResourceSizeLimitKey            = "sizeLimit"
 )
 
 var (
@@ -49,14 +66,19 @@ var (
 type nodeServer struct {
 	nodeID            string
 	maxVolumesPerNode int64
+	maxBytesPerNode   int64
 	hp                HostPathDriver
 	mounter           mount.Interface
+	// capacityLock serializes the checks of the volume count and memory budget of the node
+	// with the creation of the volumes they account for
+	capacityLock sync.Mutex
 }
 
 func NewNodeServer(hp *hostPath) *nodeServer {
 	return &nodeServer{
 		nodeID:            hp.nodeID,
 		maxVolumesPerNode: hp.maxVolumesPerNode,
+		maxBytesPerNode:   hp.maxBytesPerNode,
 		hp:                hp,
 		mounter:           mount.New(""),
 	}
@@ -71,45 +93,114 @@ func getPodDetails(volumeContext map[string]string) (string, string, string, str
 
 }
 
-func (ns *nodeServer) validateShare(req *csi.NodePublishVolumeRequest) (*sharev1alpha1.Share, error) {
-	shareName, sok := req.GetVolumeContext()[ProjectedResourceShareKey]
-	if !sok || len(strings.TrimSpace(shareName)) == 0 {
+func (ns *nodeServer) validateShare(req *csi.NodePublishVolumeRequest) (sharev1alpha1.ShareObject, error) {
+	shareName := strings.TrimSpace(req.GetVolumeContext()[ProjectedResourceShareKey])
+	namespacedShareName := strings.TrimSpace(req.GetVolumeContext()[ProjectedResourceNamespacedShareKey])
+	podNamespace, podName, _, podSA := getPodDetails(req.GetVolumeContext())
+
+	var share sharev1alpha1.ShareObject
+	switch {
+	case len(shareName) > 0 && len(namespacedShareName) > 0:
 		return nil, status.Errorf(codes.InvalidArgument,
-			"the csi driver reference is missing the volumeAttribute 'share'")
+			"the csi driver volumeAttributes 'share' and 'namespacedShare' cannot both be set")
+	case len(shareName) > 0:
+		clusterShare, err := client.GetListers().Shares.Get(shareName)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"the csi driver volumeAttribute 'share' reference had an error: %s", err.Error())
+		}
+		share = clusterShare
+	case len(namespacedShareName) > 0:
+		// a namespaced share can only be consumed by pods in its namespace
+		namespacedShare, err := client.GetListers().NamespacedShares.NamespacedShares(podNamespace).Get(namespacedShareName)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"the csi driver volumeAttribute 'namespacedShare' reference had an error: %s", err.Error())
+		}
+		shareName = namespacedShareName
+		share = namespacedShare
+	default:
+		return nil, status.Errorf(codes.InvalidArgument,
+			"the csi driver reference is missing the volumeAttribute 'share' or 'namespacedShare'")
 	}
 
-	share, err := client.GetListers().Shares.Get(shareName)
-	if err != nil {
+	brs := share.GetSpec().GetBackingResources()
+	if len(brs) == 0 {
 		return nil, status.Errorf(codes.InvalidArgument,
-			"the csi driver volumeAttribute 'share' reference had an error: %s", err.Error())
+			"the share %s does not specify any backing resources", shareName)
 	}
-
-	switch strings.TrimSpace(share.Spec.BackingResource.Kind) {
-	case "Secret":
-	case "ConfigMap":
-	default:
+	if err := validation.ValidateUpdatePolicy(share.GetSpec().UpdatePolicy); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument,
-			"the share %s has an invalid backing resource kind %s", shareName, share.Spec.BackingResource.Kind)
+			"the share %s has an invalid update policy: %s", shareName, err.Error())
 	}
-
-	if len(strings.TrimSpace(share.Spec.BackingResource.Namespace)) == 0 {
+	if err := validation.ValidateDefaultModes(share.GetSpec()); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument,
-			"the share %s backing resource namespace needs to be set", shareName)
+			"the share %s has an invalid default mode: %s", shareName, err.Error())
 	}
-	if len(strings.TrimSpace(share.Spec.BackingResource.Name)) == 0 {
+	if err := validation.ValidateProjectedPaths(brs); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument,
-			"the share %s backing resource name needs to be set", shareName)
+			"the share %s has conflicting item paths: %s", shareName, err.Error())
 	}
+	for _, br := range brs {
+		if err := validation.ValidateBackingResourceKind(br); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"the share %s has an invalid backing resource kind %s: %s", shareName, br.Kind, err.Error())
+		}
 
-	podNamespace, podName, _, podSA := getPodDetails(req.GetVolumeContext())
+		if len(strings.TrimSpace(br.Namespace)) == 0 {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"the share %s backing resource namespace needs to be set", shareName)
+		}
+		if len(strings.TrimSpace(br.Name)) == 0 && br.Selector == nil {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"the share %s backing resource name or selector needs to be set", shareName)
+		}
+		if err := validation.ValidateSelector(br); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"the share %s backing resource in namespace %s has an invalid selector: %s", shareName, br.Namespace, err.Error())
+		}
+		for _, item := range br.Items {
+			if err := validation.ValidateKeyToPath(item); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument,
+					"the share %s backing resource %s has an invalid item: %s", shareName, br.Name, err.Error())
+			}
+		}
+	}
 
-	allowed, err := client.ExecuteSAR(shareName, podNamespace, podName, podSA)
+	if len(share.GetNamespace()) == 0 {
+		allowed, err := client.ExecuteSAR(shareName, podNamespace, podName, podSA)
+		if allowed {
+			return share, nil
+		}
+		recordAccessDenied(req, share, err)
+		return nil, err
+	}
+
+	for _, br := range brs {
+		if err := validation.ValidateNamespacedBackingResource(share.GetNamespace(), br, client.GetListers().Namespaces); err != nil {
+			return nil, status.Errorf(codes.PermissionDenied,
+				"the namespaced share %s cannot be used: %s", shareName, err.Error())
+		}
+	}
+	allowed, err := client.ExecuteNamespacedShareSAR(shareName, podNamespace, podName, podSA)
 	if allowed {
 		return share, nil
 	}
+	recordAccessDenied(req, share, err)
 	return nil, err
 }
 
+// recordAccessDenied records a ShareAccessDenied event against the pod of a publish request and the share it
+// requested when the subject access review of its service account denied it the share
+func recordAccessDenied(req *csi.NodePublishVolumeRequest, share sharev1alpha1.ShareObject, err error) {
+	if status.Code(err) != codes.PermissionDenied {
+		return
+	}
+	podNamespace, podName, podUID, _ := getPodDetails(req.GetVolumeContext())
+	recordEvent(podReference(podNamespace, podName, podUID), shareReference(objcache.GetShareKey(share)),
+		corev1.EventTypeWarning, ShareAccessDeniedReason, "%s", status.Convert(err).Message())
+}
+
 // validateVolumeContext return values:
 func (ns *nodeServer) validateVolumeContext(req *csi.NodePublishVolumeRequest) error {
 
@@ -125,20 +216,138 @@ func (ns *nodeServer) validateVolumeContext(req *csi.NodePublishVolumeRequest) e
 			fmt.Sprintf("Volume attributes missing required set for pod: namespace: %s name: %s uid: %s, sa: %s",
 				podNamespace, podName, podUID, podSA))
 	}
-	ephemeralVolume := req.GetVolumeContext()[CSIEphemeral] == "true" ||
-		req.GetVolumeContext()[CSIEphemeral] == "" // Kubernetes 1.15 doesn't have csi.storage.k8s.io/ephemeral.
-
-	if !ephemeralVolume {
-		return status.Error(codes.InvalidArgument, "Non-ephemeral request made")
-	}
-
 	if req.GetVolumeCapability().GetMount() == nil {
 		return status.Error(codes.InvalidArgument, "only support mount access type")
 	}
+
+	updatePolicy := sharev1alpha1.UpdatePolicy(strings.TrimSpace(req.GetVolumeContext()[ProjectedResourceUpdatePolicyKey]))
+	if err := validation.ValidateUpdatePolicy(updatePolicy); err != nil {
+		return status.Errorf(codes.InvalidArgument,
+			"the csi driver volumeAttribute 'updatePolicy' is invalid: %s", err.Error())
+	}
+	for _, key := range []string{ProjectedResourceDefaultModeKey, ProjectedResourceSecretDefaultModeKey, ProjectedResourceConfigMapDefaultModeKey} {
+		value, ok := req.GetVolumeContext()[key]
+		if !ok {
+			continue
+		}
+		if _, err := validation.ParseFileMode(value); err != nil {
+			return status.Errorf(codes.InvalidArgument,
+				"the csi driver volumeAttribute '%s' is invalid: %s", key, err.Error())
+		}
+	}
+	if value, ok := req.GetVolumeContext()[ProjectedResourceSizeLimitKey]; ok {
+		if size, err := resource.ParseQuantity(value); err != nil || size.Sign() <= 0 {
+			return status.Errorf(codes.InvalidArgument,
+				"the csi driver volumeAttribute '%s' is invalid: %s is not a positive quantity", ProjectedResourceSizeLimitKey, value)
+		}
+	}
 	return nil
 }
 
+// volumeKey returns the key in hostPathVolumes of the volume a publish request creates. An ephemeral volume is only
+// published for its pod, and is keyed by its volume ID; a persistent volume is published for each of the pods using
+// its PVC, and is keyed by its volume ID and the UID of the pod
+func volumeKey(volID string, volCtx map[string]string) string {
+	// Kubernetes 1.15 doesn't have csi.storage.k8s.io/ephemeral.
+	if volCtx[CSIEphemeral] != "false" {
+		return volID
+	}
+	_, _, podUID, _ := getPodDetails(volCtx)
+	return volID + "-" + podUID
+}
+
+// publishedVolumeKey returns the key in hostPathVolumes of the volume published at targetPath for a volume ID
+func publishedVolumeKey(volID, targetPath string) string {
+	for key, hpv := range hostPathVolumes.list() {
+		if hpv.TargetPath == targetPath && (key == volID || strings.HasPrefix(key, volID+"-")) {
+			return key
+		}
+	}
+	return volID
+}
+
+// tmpfsSize returns the size of the tmpfs of a volume; twice its size limit, as while its data is updated the
+// volume holds both its previous and new files. It is what a volume takes out of the memory budget of the node,
+// so that the tmpfs of the volumes never add up to more than that budget.
+func tmpfsSize(sizeLimit int64) int64 {
+	return 2 * sizeLimit
+}
+
+// reserveVolume creates a volume once it is known to fit within the volume count and memory budget of the
+// node, with a size limit of the sizeLimit volume attribute or, by default, twice the size of its data, leaving
+// it room to grow
+func (ns *nodeServer) reserveVolume(volID string, req *csi.NodePublishVolumeRequest, share sharev1alpha1.ShareObject) (*hostPathVolume, error) {
+	ns.capacityLock.Lock()
+	defer ns.capacityLock.Unlock()
+
+	// a volume published again once its mount is gone keeps the size limit it was published with, already
+	// accounted for, and is mounted and populated again
+	if stored, ok := hostPathVolumes.get(volID); ok {
+		if stored.TargetPath != req.GetTargetPath() {
+			return nil, status.Errorf(codes.AlreadyExists, "volume %s is already published at %s", volID, stored.TargetPath)
+		}
+		return stored, nil
+	}
+
+	volumes := int64(0)
+	usedBytes := int64(0)
+	for _, hpv := range hostPathVolumes.list() {
+		volumes++
+		// volumes published before size limits were enforced are not accounted for
+		if hpv.VolSize < maxStorageCapacity {
+			usedBytes += tmpfsSize(hpv.VolSize)
+		}
+	}
+	if ns.maxVolumesPerNode > 0 && volumes >= ns.maxVolumesPerNode {
+		return nil, status.Errorf(codes.ResourceExhausted,
+			"the node already has its maximum of %d projected resource volumes", ns.maxVolumesPerNode)
+	}
+
+	vol, err := ns.hp.createHostpathVolume(volID, req.GetTargetPath(), req.GetVolumeContext(), share, maxStorageCapacity, mountAccess)
+	if err != nil {
+		klog.Error("failed to create volume: ", err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	files, err := volumeFiles(vol)
+	if err != nil {
+		ns.hp.deleteHostpathVolume(volID)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	dataSize := payloadSize(files)
+	sizeLimit := 2 * dataSize
+	if sizeLimit < minVolumeSize {
+		sizeLimit = minVolumeSize
+	}
+	if value, ok := req.GetVolumeContext()[ProjectedResourceSizeLimitKey]; ok {
+		// the volume attributes are checked before the volume is created
+		size := resource.MustParse(value)
+		sizeLimit = size.Value()
+	}
+	if dataSize > sizeLimit {
+		ns.hp.deleteHostpathVolume(volID)
+		return nil, status.Errorf(codes.ResourceExhausted,
+			"the data of the share %s is %d bytes, over the volume size limit of %d bytes", share.GetName(), dataSize, sizeLimit)
+	}
+	if ns.maxBytesPerNode > 0 && usedBytes+tmpfsSize(sizeLimit) > ns.maxBytesPerNode {
+		ns.hp.deleteHostpathVolume(volID)
+		return nil, status.Errorf(codes.ResourceExhausted,
+			"a volume with a tmpfs of %d bytes does not fit in the projected resource memory budget of the node, %d of %d bytes are in use",
+			tmpfsSize(sizeLimit), usedBytes, ns.maxBytesPerNode)
+	}
+	vol.VolSize = sizeLimit
+	// saved before the capacity lock is released, for the next volume to account for its size limit
+	hostPathVolumes.save(vol)
+	return vol, nil
+}
+
 func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
+	start := time.Now()
+	resp, err := ns.publishVolume(req)
+	metrics.ObserveNodeOperation("NodePublishVolume", status.Code(err).String(), start)
+	return resp, err
+}
+
+func (ns *nodeServer) publishVolume(req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
 	var targetPath string
 
 	// Check arguments
@@ -166,10 +375,38 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 	}
 
 	targetPath = req.GetTargetPath()
-	vol, err := ns.hp.createHostpathVolume(req.GetVolumeId(), targetPath, req.GetVolumeContext(), share, maxStorageCapacity, mountAccess)
-	if err != nil && !os.IsExist(err) {
-		klog.Error("ephemeral mode failed to create volume: ", err)
-		return nil, status.Error(codes.Internal, err.Error())
+	volID := volumeKey(req.GetVolumeId(), req.GetVolumeContext())
+	// concurrent or repeated publishes of a volume are serialized; once it is mounted at its target path,
+	// publishing it again succeeds without recreating it
+	unlock := hostPathVolumes.lockVolume(volID)
+	defer unlock()
+	if vol, ok := hostPathVolumes.get(volID); ok && vol.TargetPath == targetPath {
+		if notMnt, err := mount.IsNotMountPoint(ns.mounter, targetPath); err == nil && !notMnt {
+			klog.V(4).Infof("NodePublishVolume volume %s is already published at %s", volID, targetPath)
+			return &csi.NodePublishVolumeResponse{}, nil
+		}
+	}
+	vol, err := ns.reserveVolume(volID, req, share)
+	if err != nil {
+		return nil, err
+	}
+	// a publish failing from here on deletes the volume, releasing its share of the volume count and memory
+	// budget of the node, and cleans up the paths it mounted
+	published := false
+	mountedPaths := []string{}
+	defer func() {
+		if published {
+			return
+		}
+		for i := len(mountedPaths) - 1; i >= 0; i-- {
+			if err := mount.CleanupMountPoint(mountedPaths[i], ns.mounter, true); err != nil {
+				klog.Warningf("error cleaning up %s of failed publish of volume %s: %s", mountedPaths[i], volID, err.Error())
+			}
+		}
+		ns.hp.deleteHostpathVolume(volID)
+	}()
+	if req.GetReadonly() {
+		vol.StagingPath = ns.hp.getStagingPath(volID)
 	}
 	klog.V(4).Infof("NodePublishVolume created volume: %s", vol.VolPath)
 
@@ -186,9 +423,13 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 		}
 	}
 
-	// this means the mount.Mounter call has already happened
+	// a volume still mounted at its target path was published above, so this mount is left from a publish that
+	// was never recorded, and the volume published now replaces it
 	if !notMnt {
-		return &csi.NodePublishVolumeResponse{}, nil
+		klog.V(2).Infof("NodePublishVolume unmounting %s, which no published volume accounts for", targetPath)
+		if err := ns.mounter.Unmount(targetPath); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
 	}
 
 	fsType := req.GetVolumeCapability().GetMount().GetFsType()
@@ -198,14 +439,14 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 		deviceId = req.GetPublishContext()[deviceID]
 	}
 
-	volumeId := req.GetVolumeId()
+	volumeId := volID
 	attrib := req.GetVolumeContext()
 	mountFlags := req.GetVolumeCapability().GetMount().GetMountFlags()
 
 	klog.V(4).Infof("NodePublishVolume %v\nfstype %v\ndevice %v\nvolumeId %v\nattributes %v\nmountflags %v\n",
 		targetPath, fsType, deviceId, volumeId, attrib, mountFlags)
 
-	options := []string{}
+	options := []string{fmt.Sprintf("size=%d", tmpfsSize(vol.VolSize))}
 	path := vol.VolPath
 
 	// NOTE: so our intent here is to have a separate tmpfs per pod; through experimentation
@@ -214,7 +455,8 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 	// - otherwise, if pods share the same host dir, all sorts of warnings from the SMEs
 	// - and the obvious isolation between pods that implies
 	// We cannot do read-only on the mount since we have to copy the data after the mount, otherwise we get errors
-	// that the filesystem is readonly
+	// that the filesystem is readonly; so for read-only volumes, the tmpfs is mounted at a staging path only the
+	// driver writes to, and that is bind mounted read-only onto the target path, so the pod never gets write access
 	// The various bits that work in concert to achieve this
 	// - the use of emptyDir with a medium of Memory in this drivers Deployment is all that is needed to get tmpfs
 	// - do not use the "bind" option, that reuses existing dirs/filesystems vs. creating new tmpfs
@@ -224,17 +466,37 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 	//   being xfs and not tmpfs
 	// - with the lack of a bind option, and each pod getting its own tmpfs we have to copy the data from our emptydir
 	//   based location to the targetPath here ... that is handled in hostpath.go
-	if err := ns.mounter.Mount(path, targetPath, "tmpfs", options); err != nil {
-		var errList strings.Builder
-		errList.WriteString(err.Error())
-		if rmErr := os.RemoveAll(path); rmErr != nil && !os.IsNotExist(rmErr) {
-			errList.WriteString(fmt.Sprintf(" :%s", rmErr.Error()))
+	mountPath := vol.dataPath()
+	if err := os.MkdirAll(mountPath, 0750); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if mountPath != targetPath {
+		// the staging tmpfs of a volume published again may have outlived the bind mount of its target path
+		if notMnt, err := mount.IsNotMountPoint(ns.mounter, mountPath); err == nil && !notMnt {
+			if err := ns.mounter.Unmount(mountPath); err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
 		}
-
+		// the staging path is the driver's own, removed along with the volume
+		mountedPaths = append(mountedPaths, mountPath)
+	}
+	if err := ns.mounter.Mount(path, mountPath, "tmpfs", options); err != nil {
 		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to mount device: %s at %s: %s",
 			path,
-			targetPath,
-			errList.String()))
+			mountPath,
+			err.Error()))
+	}
+	if mountPath == targetPath {
+		mountedPaths = append(mountedPaths, targetPath)
+	} else {
+		// the mounter remounts the bind mount read-only after creating it
+		if err := ns.mounter.Mount(mountPath, targetPath, "", []string{"bind", "ro"}); err != nil {
+			return nil, status.Error(codes.Internal, fmt.Sprintf("failed to bind mount: %s at %s read-only: %s",
+				mountPath,
+				targetPath,
+				err.Error()))
+		}
+		mountedPaths = append(mountedPaths, targetPath)
 	}
 	// here is what initiates that necessary copy now with *NOT* using bind on the mount so each pod gets its own tmpfs
 	if err := ns.hp.mapVolumeToPod(vol); err != nil {
@@ -244,14 +506,22 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 			err.Error()))
 	}
 
-	if err := storeVolMapToDisk(); err != nil {
+	if err := storeVolume(vol); err != nil {
 		klog.Errorf("failed to persist driver volume metadata to disk: %s", err.Error())
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	published = true
 	return &csi.NodePublishVolumeResponse{}, nil
 }
 
 func (ns *nodeServer) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
+	start := time.Now()
+	resp, err := ns.unpublishVolume(req)
+	metrics.ObserveNodeOperation("NodeUnpublishVolume", status.Code(err).String(), start)
+	return resp, err
+}
+
+func (ns *nodeServer) unpublishVolume(req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
 
 	// Check arguments
 	if len(req.GetVolumeId()) == 0 {
@@ -261,12 +531,19 @@ func (ns *nodeServer) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpu
 		return nil, status.Error(codes.InvalidArgument, "Target path missing in request")
 	}
 	targetPath := req.GetTargetPath()
-	volumeID := req.GetVolumeId()
+	volumeID := publishedVolumeKey(req.GetVolumeId(), targetPath)
+	unlock := hostPathVolumes.lockVolume(volumeID)
+	defer unlock()
 
 	err := mount.CleanupMountPoint(targetPath, ns.mounter, true)
 	if err != nil {
 		klog.Errorf("error cleaning and unmounting target path %s, err: %v for vol: %s", targetPath, err, volumeID)
 	}
+	if vol, ok := hostPathVolumes.get(volumeID); ok && len(vol.StagingPath) > 0 {
+		if err := mount.CleanupMountPoint(vol.StagingPath, ns.mounter, true); err != nil {
+			klog.Errorf("error cleaning and unmounting staging path %s, err: %v for vol: %s", vol.StagingPath, err, volumeID)
+		}
+	}
 
 	klog.V(4).Infof("hostpath: volume %s has been unpublished.", targetPath)
 
@@ -278,12 +555,49 @@ func (ns *nodeServer) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpu
 	return &csi.NodeUnpublishVolumeResponse{}, nil
 }
 
+// NodeStageVolume stages a persistent volume on the node. The data of a persistent volume is written for each of
+// its pods when it is published, once their permission to use its share is checked, into a tmpfs of their own;
+// so staging only checks the volume references a share, and creates the staging path.
 func (ns *nodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "")
+	if len(req.GetVolumeId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume ID missing in request")
+	}
+	if len(req.GetStagingTargetPath()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Staging target path missing in request")
+	}
+	if req.GetVolumeCapability() == nil {
+		return nil, status.Error(codes.InvalidArgument, "Volume capability missing in request")
+	}
+	if req.GetVolumeCapability().GetMount() == nil {
+		return nil, status.Error(codes.InvalidArgument, "only support mount access type")
+	}
+	shareName := strings.TrimSpace(req.GetVolumeContext()[ProjectedResourceShareKey])
+	namespacedShareName := strings.TrimSpace(req.GetVolumeContext()[ProjectedResourceNamespacedShareKey])
+	if len(shareName) == 0 && len(namespacedShareName) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"the csi driver reference is missing the volumeAttribute 'share' or 'namespacedShare'")
+	}
+
+	if err := os.MkdirAll(req.GetStagingTargetPath(), 0750); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	klog.V(4).Infof("NodeStageVolume staged volume %s at %s", req.GetVolumeId(), req.GetStagingTargetPath())
+	return &csi.NodeStageVolumeResponse{}, nil
 }
 
 func (ns *nodeServer) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "")
+	if len(req.GetVolumeId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume ID missing in request")
+	}
+	if len(req.GetStagingTargetPath()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Staging target path missing in request")
+	}
+
+	if err := mount.CleanupMountPoint(req.GetStagingTargetPath(), ns.mounter, true); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to clean up staging path %s: %s", req.GetStagingTargetPath(), err.Error())
+	}
+	klog.V(4).Infof("NodeUnstageVolume unstaged volume %s from %s", req.GetVolumeId(), req.GetStagingTargetPath())
+	return &csi.NodeUnstageVolumeResponse{}, nil
 }
 
 func (ns *nodeServer) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
@@ -301,13 +615,116 @@ func (ns *nodeServer) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoReque
 
 func (ns *nodeServer) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
 
+	capabilities := []*csi.NodeServiceCapability{}
+	for _, rpcType := range []csi.NodeServiceCapability_RPC_Type{
+		csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME,
+		csi.NodeServiceCapability_RPC_GET_VOLUME_STATS,
+		csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
+	} {
+		capabilities = append(capabilities, &csi.NodeServiceCapability{
+			Type: &csi.NodeServiceCapability_Rpc{
+				Rpc: &csi.NodeServiceCapability_RPC{Type: rpcType},
+			},
+		})
+	}
 	return &csi.NodeGetCapabilitiesResponse{
-		Capabilities: []*csi.NodeServiceCapability{},
+		Capabilities: capabilities,
 	}, nil
 }
 
 func (ns *nodeServer) NodeGetVolumeStats(ctx context.Context, in *csi.NodeGetVolumeStatsRequest) (*csi.NodeGetVolumeStatsResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "")
+	if len(in.GetVolumeId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume ID missing in request")
+	}
+	if len(in.GetVolumePath()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume path missing in request")
+	}
+	volID := publishedVolumeKey(in.GetVolumeId(), in.GetVolumePath())
+	unlock := hostPathVolumes.lockVolume(volID)
+	defer unlock()
+	hpv, ok := hostPathVolumes.get(volID)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "volume %s not found", in.GetVolumeId())
+	}
+
+	usage, err := volumeUsage(hpv)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get the usage of volume %s: %s", in.GetVolumeId(), err.Error())
+	}
+	return &csi.NodeGetVolumeStatsResponse{
+		Usage:           usage,
+		VolumeCondition: volumeCondition(hpv),
+	}, nil
+}
+
+// volumeUsage returns the bytes and inodes used by the data of a volume; the bytes out of its size limit,
+// and the inodes out of those of its tmpfs
+func volumeUsage(hpv *hostPathVolume) ([]*csi.VolumeUsage, error) {
+	usedBytes := int64(0)
+	usedInodes := int64(0)
+	err := filepath.Walk(hpv.dataPath(), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		usedInodes++
+		if info.Mode().IsRegular() {
+			usedBytes += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var statfs unix.Statfs_t
+	if err := unix.Statfs(hpv.dataPath(), &statfs); err != nil {
+		return nil, err
+	}
+	availableBytes := hpv.VolSize - usedBytes
+	if availableBytes < 0 {
+		availableBytes = 0
+	}
+	return []*csi.VolumeUsage{
+		{
+			Unit:      csi.VolumeUsage_BYTES,
+			Total:     hpv.VolSize,
+			Used:      usedBytes,
+			Available: availableBytes,
+		},
+		{
+			Unit:      csi.VolumeUsage_INODES,
+			Total:     int64(statfs.Files),
+			Used:      usedInodes,
+			Available: int64(statfs.Ffree),
+		},
+	}, nil
+}
+
+// volumeCondition reports a volume as abnormal when the share it projects was deleted, its pod lost
+// permission to use that share, or an object the share names is missing
+func volumeCondition(hpv *hostPathVolume) *csi.VolumeCondition {
+	shareNamespace, shareName := "", hpv.SharedDataId
+	if parts := strings.SplitN(hpv.SharedDataId, ":", 2); len(parts) == 2 {
+		shareNamespace, shareName = parts[0], parts[1]
+	}
+	var err error
+	if len(shareNamespace) == 0 {
+		_, err = client.GetListers().Shares.Get(shareName)
+	} else {
+		_, err = client.GetListers().NamespacedShares.NamespacedShares(shareNamespace).Get(shareName)
+	}
+	switch {
+	case kerrors.IsNotFound(err):
+		return &csi.VolumeCondition{Abnormal: true, Message: fmt.Sprintf("the share %s was deleted", hpv.SharedDataId)}
+	case err != nil:
+		return &csi.VolumeCondition{Abnormal: true, Message: fmt.Sprintf("the share %s could not be retrieved: %s", hpv.SharedDataId, err.Error())}
+	case !hpv.Allowed:
+		return &csi.VolumeCondition{Abnormal: true, Message: fmt.Sprintf("the pod no longer has permission to use the share %s", hpv.SharedDataId)}
+	}
+	if missing := missingItems(hpv); len(missing) > 0 {
+		item := missing[0]
+		return &csi.VolumeCondition{Abnormal: true, Message: fmt.Sprintf("the %s %s of the share %s is missing", item.Kind, item.Key, hpv.SharedDataId)}
+	}
+	return &csi.VolumeCondition{Abnormal: false, Message: fmt.Sprintf("the share %s is projected", hpv.SharedDataId)}
 }
 
 // NodeExpandVolume is only implemented so the driver can be used for e2e testing.
@@ -1,24 +1,35 @@
 package hostpath
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	sharev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+	objcache "github.com/openshift/csi-driver-projected-resource/pkg/cache"
 	"github.com/openshift/csi-driver-projected-resource/pkg/client"
+	sharelisters "github.com/openshift/csi-driver-projected-resource/pkg/generated/listers/projectedresource/v1alpha1"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	fakekubetesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/utils/mount"
 )
 
@@ -40,6 +51,19 @@ func (f *fakeShareLister) Get(name string) (*sharev1alpha1.Share, error) {
 	return f.share, nil
 }
 
+func setNamespacedShareListers(namespacedShare *sharev1alpha1.NamespacedShare, namespaces ...*corev1.Namespace) {
+	shareIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	if namespacedShare != nil {
+		shareIndexer.Add(namespacedShare)
+	}
+	nsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, ns := range namespaces {
+		nsIndexer.Add(ns)
+	}
+	client.SetNamespacedSharesLister(sharelisters.NewNamespacedShareLister(shareIndexer))
+	client.SetNamespacesLister(corelisters.NewNamespaceLister(nsIndexer))
+}
+
 func testNodeServer() (*nodeServer, string, string, error) {
 	hp, tmpDir, volPathTmpDir, err := testHostPathDriver()
 	if err != nil {
@@ -85,12 +109,41 @@ func TestNodePublishVolume(t *testing.T) {
 		},
 		Status: sharev1alpha1.ShareStatus{},
 	}
+	validNamespacedShare := &sharev1alpha1.NamespacedShare{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "share1",
+			Namespace: "namespace1",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "Secret",
+				APIVersion: "v1",
+				Name:       "cool-secret",
+				Namespace:  "cool-secret-namespace",
+			},
+		},
+	}
+	namespacedShareVolumeContext := map[string]string{
+		CSIEphemeral:                        "true",
+		CSIPodName:                          "name1",
+		CSIPodNamespace:                     "namespace1",
+		CSIPodUID:                           "uid1",
+		CSIPodSA:                            "sa1",
+		ProjectedResourceNamespacedShareKey: "share1",
+	}
+	mountCapability := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{
+			Mount: &csi.VolumeCapability_MountVolume{},
+		},
+	}
 
 	tests := []struct {
 		name              string
 		nodePublishVolReq csi.NodePublishVolumeRequest
 		expectedMsg       string
 		share             *sharev1alpha1.Share
+		namespacedShare   *sharev1alpha1.NamespacedShare
+		namespaces        []*corev1.Namespace
 		reactor           fakekubetesting.ReactionFunc
 	}{
 		{
@@ -135,7 +188,7 @@ func TestNodePublishVolume(t *testing.T) {
 			expectedMsg: "Volume attributes missing required set for pod",
 		},
 		{
-			name: "volume context is non-ephemeral",
+			name: "persistent volume capabilities access is not mount type",
 			nodePublishVolReq: csi.NodePublishVolumeRequest{
 				VolumeCapability: &csi.VolumeCapability{},
 				VolumeId:         "testvolid1",
@@ -148,7 +201,7 @@ func TestNodePublishVolume(t *testing.T) {
 					CSIPodSA:        "sa1",
 				},
 			},
-			expectedMsg: "Non-ephemeral request made",
+			expectedMsg: "only support mount access type",
 		},
 		{
 			name: "volume capabilities access is not mount type",
@@ -186,6 +239,28 @@ func TestNodePublishVolume(t *testing.T) {
 			},
 			expectedMsg: "the csi driver reference is missing the volumeAttribute 'share'",
 		},
+		{
+			name: "invalid default mode",
+			nodePublishVolReq: csi.NodePublishVolumeRequest{
+				VolumeId:   "testvolid1",
+				TargetPath: getTestTargetPath(t),
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{},
+					},
+				},
+				VolumeContext: map[string]string{
+					CSIEphemeral:                    "true",
+					CSIPodName:                      "name1",
+					CSIPodNamespace:                 "namespace1",
+					CSIPodUID:                       "uid1",
+					CSIPodSA:                        "sa1",
+					ProjectedResourceShareKey:       "share1",
+					ProjectedResourceDefaultModeKey: "01000",
+				},
+			},
+			expectedMsg: "the csi driver volumeAttribute 'defaultMode' is invalid",
+		},
 		{
 			name: "missing share",
 			nodePublishVolReq: csi.NodePublishVolumeRequest{
@@ -207,6 +282,113 @@ func TestNodePublishVolume(t *testing.T) {
 			},
 			expectedMsg: "the csi driver volumeAttribute 'share' reference had an error",
 		},
+		{
+			name: "no backing resources",
+			share: &sharev1alpha1.Share{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "share1",
+				},
+				Spec: sharev1alpha1.ShareSpec{
+					Description: "",
+				},
+				Status: sharev1alpha1.ShareStatus{},
+			},
+			nodePublishVolReq: csi.NodePublishVolumeRequest{
+				VolumeId:   "testvolid1",
+				TargetPath: getTestTargetPath(t),
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{},
+					},
+				},
+				VolumeContext: map[string]string{
+					CSIEphemeral:              "true",
+					CSIPodName:                "name1",
+					CSIPodNamespace:           "namespace1",
+					CSIPodUID:                 "uid1",
+					CSIPodSA:                  "sa1",
+					ProjectedResourceShareKey: "share1",
+				},
+			},
+			expectedMsg: "does not specify any backing resources",
+		},
+		{
+			name: "bad entry in backing resources",
+			share: &sharev1alpha1.Share{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "share1",
+				},
+				Spec: sharev1alpha1.ShareSpec{
+					BackingResource: validShare.Spec.BackingResource,
+					BackingResources: []sharev1alpha1.BackingResource{
+						{
+							Kind: "ConfigMap",
+							Name: "configmap1",
+						},
+					},
+				},
+				Status: sharev1alpha1.ShareStatus{},
+			},
+			nodePublishVolReq: csi.NodePublishVolumeRequest{
+				VolumeId:   "testvolid1",
+				TargetPath: getTestTargetPath(t),
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{},
+					},
+				},
+				VolumeContext: map[string]string{
+					CSIEphemeral:              "true",
+					CSIPodName:                "name1",
+					CSIPodNamespace:           "namespace1",
+					CSIPodUID:                 "uid1",
+					CSIPodSA:                  "sa1",
+					ProjectedResourceShareKey: "share1",
+				},
+			},
+			expectedMsg: "backing resource namespace needs to be set",
+		},
+		{
+			name: "bad backing resource item",
+			share: &sharev1alpha1.Share{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "share1",
+				},
+				Spec: sharev1alpha1.ShareSpec{
+					BackingResource: sharev1alpha1.BackingResource{
+						Kind:      "ConfigMap",
+						Name:      "configmap1",
+						Namespace: "namespace1",
+						Items: []corev1.KeyToPath{
+							{
+								Key:  "ca.crt",
+								Path: "../ca.crt",
+							},
+						},
+					},
+					Description: "",
+				},
+				Status: sharev1alpha1.ShareStatus{},
+			},
+			nodePublishVolReq: csi.NodePublishVolumeRequest{
+				VolumeId:   "testvolid1",
+				TargetPath: getTestTargetPath(t),
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{},
+					},
+				},
+				VolumeContext: map[string]string{
+					CSIEphemeral:              "true",
+					CSIPodName:                "name1",
+					CSIPodNamespace:           "namespace1",
+					CSIPodUID:                 "uid1",
+					CSIPodSA:                  "sa1",
+					ProjectedResourceShareKey: "share1",
+				},
+			},
+			expectedMsg: "must not contain '..'",
+		},
 		{
 			name: "bad backing resource kind",
 			share: &sharev1alpha1.Share{
@@ -306,7 +488,41 @@ func TestNodePublishVolume(t *testing.T) {
 					ProjectedResourceShareKey: "share1",
 				},
 			},
-			expectedMsg: "backing resource name needs to be set",
+			expectedMsg: "backing resource name or selector needs to be set",
+		},
+		{
+			name: "backing resource name and selector",
+			share: &sharev1alpha1.Share{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "share1",
+				},
+				Spec: sharev1alpha1.ShareSpec{
+					BackingResource: sharev1alpha1.BackingResource{
+						Kind:      "ConfigMap",
+						Namespace: "namespace1",
+						Name:      "configmap1",
+						Selector:  &metav1.LabelSelector{MatchLabels: map[string]string{"trust.example.com/bundle": "true"}},
+					},
+				},
+			},
+			nodePublishVolReq: csi.NodePublishVolumeRequest{
+				VolumeId:   "testvolid1",
+				TargetPath: getTestTargetPath(t),
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{},
+					},
+				},
+				VolumeContext: map[string]string{
+					CSIEphemeral:              "true",
+					CSIPodName:                "name1",
+					CSIPodNamespace:           "namespace1",
+					CSIPodUID:                 "uid1",
+					CSIPodSA:                  "sa1",
+					ProjectedResourceShareKey: "share1",
+				},
+			},
+			expectedMsg: "has an invalid selector",
 		},
 		{
 			name:    "sar fails",
@@ -331,6 +547,93 @@ func TestNodePublishVolume(t *testing.T) {
 			},
 			expectedMsg: "PermissionDenied",
 		},
+		{
+			name: "share and namespaced share both referenced",
+			nodePublishVolReq: csi.NodePublishVolumeRequest{
+				VolumeId:         "testvolid1",
+				TargetPath:       getTestTargetPath(t),
+				VolumeCapability: mountCapability,
+				VolumeContext: map[string]string{
+					CSIEphemeral:                        "true",
+					CSIPodName:                          "name1",
+					CSIPodNamespace:                     "namespace1",
+					CSIPodUID:                           "uid1",
+					CSIPodSA:                            "sa1",
+					ProjectedResourceShareKey:           "share1",
+					ProjectedResourceNamespacedShareKey: "share1",
+				},
+			},
+			expectedMsg: "cannot both be set",
+		},
+		{
+			name: "missing namespaced share",
+			nodePublishVolReq: csi.NodePublishVolumeRequest{
+				VolumeId:         "testvolid1",
+				TargetPath:       getTestTargetPath(t),
+				VolumeCapability: mountCapability,
+				VolumeContext:    namespacedShareVolumeContext,
+			},
+			expectedMsg: "the csi driver volumeAttribute 'namespacedShare' reference had an error",
+		},
+		{
+			name:            "namespaced share backing resource namespace not allowed",
+			namespacedShare: validNamespacedShare,
+			namespaces: []*corev1.Namespace{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "cool-secret-namespace",
+						Annotations: map[string]string{sharev1alpha1.AllowedShareNamespacesAnnotation: "namespace2"},
+					},
+				},
+			},
+			reactor: acceptReactorFunc,
+			nodePublishVolReq: csi.NodePublishVolumeRequest{
+				VolumeId:         "testvolid1",
+				TargetPath:       getTestTargetPath(t),
+				VolumeCapability: mountCapability,
+				VolumeContext:    namespacedShareVolumeContext,
+			},
+			expectedMsg: "does not allow shares from namespace namespace1",
+		},
+		{
+			name:            "namespaced share sar fails",
+			namespacedShare: validNamespacedShare,
+			namespaces: []*corev1.Namespace{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "cool-secret-namespace",
+						Annotations: map[string]string{sharev1alpha1.AllowedShareNamespacesAnnotation: "*"},
+					},
+				},
+			},
+			reactor: denyReactorFunc,
+			nodePublishVolReq: csi.NodePublishVolumeRequest{
+				VolumeId:         "testvolid1",
+				TargetPath:       getTestTargetPath(t),
+				VolumeCapability: mountCapability,
+				VolumeContext:    namespacedShareVolumeContext,
+			},
+			expectedMsg: "PermissionDenied",
+		},
+		{
+			name:            "namespaced share inputs are OK",
+			namespacedShare: validNamespacedShare,
+			namespaces: []*corev1.Namespace{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "cool-secret-namespace",
+						Annotations: map[string]string{sharev1alpha1.AllowedShareNamespacesAnnotation: "namespace1"},
+					},
+				},
+			},
+			reactor: acceptReactorFunc,
+			nodePublishVolReq: csi.NodePublishVolumeRequest{
+				VolumeId:         "testvolid1",
+				TargetPath:       getTestTargetPath(t),
+				VolumeCapability: mountCapability,
+				VolumeContext:    namespacedShareVolumeContext,
+			},
+		},
 		{
 			name:    "inputs are OK",
 			share:   validShare,
@@ -353,6 +656,24 @@ func TestNodePublishVolume(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "persistent inputs are OK",
+			share:   validShare,
+			reactor: acceptReactorFunc,
+			nodePublishVolReq: csi.NodePublishVolumeRequest{
+				VolumeId:         "testvolid1",
+				TargetPath:       getTestTargetPath(t),
+				VolumeCapability: mountCapability,
+				VolumeContext: map[string]string{
+					CSIEphemeral:              "false",
+					CSIPodName:                "name1",
+					CSIPodNamespace:           "namespace1",
+					CSIPodUID:                 "uid1",
+					CSIPodSA:                  "sa1",
+					ProjectedResourceShareKey: "share1",
+				},
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -365,11 +686,14 @@ func TestNodePublishVolume(t *testing.T) {
 			}
 			defer os.RemoveAll(tmpDir)
 			defer os.RemoveAll(volPath)
+			hostPathVolumes = newVolumeStore()
+			defer func() { hostPathVolumes = newVolumeStore() }()
 
 			shareLister := &fakeShareLister{
 				share: test.share,
 			}
 			client.SetSharesLister(shareLister)
+			setNamespacedShareListers(test.namespacedShare, test.namespaces...)
 
 			if test.reactor != nil {
 				sarClient := fakekubeclientset.NewSimpleClientset()
@@ -398,3 +722,859 @@ func TestNodePublishVolume(t *testing.T) {
 		})
 	}
 }
+
+func TestNodePublishVolumeReadOnly(t *testing.T) {
+	ns, tmpDir, volPath, err := testNodeServer()
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	defer os.RemoveAll(tmpDir)
+	defer os.RemoveAll(volPath)
+	targetPath := getTestTargetPath(t)
+	defer os.RemoveAll(targetPath)
+	hostPathVolumes = newVolumeStore()
+	defer func() { hostPathVolumes = newVolumeStore() }()
+
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share1",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "Secret",
+				APIVersion: "v1",
+				Name:       "cool-secret",
+				Namespace:  "cool-secret-namespace",
+			},
+		},
+	}
+	client.SetSharesLister(&fakeShareLister{share: share})
+	sarClient := fakekubeclientset.NewSimpleClientset()
+	sarClient.PrependReactor("create", "subjectaccessreviews", func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+	})
+	client.SetClient(sarClient)
+
+	req := &csi.NodePublishVolumeRequest{
+		VolumeId:   "testvolid1",
+		TargetPath: targetPath,
+		Readonly:   true,
+		VolumeCapability: &csi.VolumeCapability{
+			AccessType: &csi.VolumeCapability_Mount{
+				Mount: &csi.VolumeCapability_MountVolume{},
+			},
+		},
+		VolumeContext: map[string]string{
+			CSIEphemeral:              "true",
+			CSIPodName:                "name1",
+			CSIPodNamespace:           "namespace1",
+			CSIPodUID:                 "uid1",
+			CSIPodSA:                  "sa1",
+			ProjectedResourceShareKey: "share1",
+		},
+	}
+	if _, err := ns.NodePublishVolume(context.TODO(), req); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+
+	stagingPath := ns.hp.getStagingPath("testvolid1")
+	mnts, err := ns.mounter.List()
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	mounted := map[string]mount.MountPoint{}
+	for _, mnt := range mnts {
+		mounted[mnt.Path] = mnt
+	}
+	if mnt, ok := mounted[stagingPath]; !ok || mnt.Type != "tmpfs" {
+		t.Fatalf("expected a tmpfs mounted at the staging path %s got %#v", stagingPath, mnts)
+	}
+	mnt, ok := mounted[targetPath]
+	if !ok {
+		t.Fatalf("expected a mount at the target path %s got %#v", targetPath, mnts)
+	}
+	if !reflect.DeepEqual(mnt.Opts, []string{"bind", "ro"}) {
+		t.Fatalf("expected the staging path bind mounted read-only got %#v", mnt)
+	}
+	if _, err := os.Stat(filepath.Join(stagingPath, "..data")); err != nil {
+		t.Fatalf("expected the data of the volume in the staging path: %s", err.Error())
+	}
+
+	if _, err := ns.NodeUnpublishVolume(context.TODO(), &csi.NodeUnpublishVolumeRequest{VolumeId: "testvolid1", TargetPath: targetPath}); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if mnts, _ = ns.mounter.List(); len(mnts) != 0 {
+		t.Fatalf("expected the target and staging paths to be unmounted got %#v", mnts)
+	}
+}
+
+func TestNodePublishPersistentVolume(t *testing.T) {
+	ns, tmpDir, volPath, err := testNodeServer()
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	defer os.RemoveAll(tmpDir)
+	defer os.RemoveAll(volPath)
+
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share1",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "Secret",
+				APIVersion: "v1",
+				Name:       "cool-secret",
+				Namespace:  "cool-secret-namespace",
+			},
+		},
+	}
+	client.SetSharesLister(&fakeShareLister{share: share})
+	sarClient := fakekubeclientset.NewSimpleClientset()
+	sarClient.PrependReactor("create", "subjectaccessreviews", func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+	})
+	client.SetClient(sarClient)
+
+	mountCapability := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{
+			Mount: &csi.VolumeCapability_MountVolume{},
+		},
+	}
+	stagingPath := getTestTargetPath(t)
+	defer os.RemoveAll(stagingPath)
+	volumeContext := map[string]string{ProjectedResourceShareKey: "share1"}
+	if _, err := ns.NodeStageVolume(context.TODO(), &csi.NodeStageVolumeRequest{
+		VolumeId:          "pvc-1",
+		StagingTargetPath: stagingPath,
+		VolumeCapability:  mountCapability,
+		VolumeContext:     volumeContext,
+	}); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+
+	// the volume is published for two pods using the PVC, each getting a volume of its own
+	targetPaths := map[string]string{}
+	for _, podUID := range []string{"uid1", "uid2"} {
+		targetPath := getTestTargetPath(t)
+		defer os.RemoveAll(targetPath)
+		targetPaths[podUID] = targetPath
+		req := &csi.NodePublishVolumeRequest{
+			VolumeId:          "pvc-1",
+			StagingTargetPath: stagingPath,
+			TargetPath:        targetPath,
+			VolumeCapability:  mountCapability,
+			VolumeContext: map[string]string{
+				CSIEphemeral:              "false",
+				CSIPodName:                "name-" + podUID,
+				CSIPodNamespace:           "namespace1",
+				CSIPodUID:                 podUID,
+				CSIPodSA:                  "sa1",
+				ProjectedResourceShareKey: "share1",
+			},
+		}
+		if _, err := ns.NodePublishVolume(context.TODO(), req); err != nil {
+			t.Fatalf("unexpected err %s", err.Error())
+		}
+	}
+	for podUID, targetPath := range targetPaths {
+		hpv, ok := hostPathVolumes.get("pvc-1-" + podUID)
+		if !ok || hpv.TargetPath != targetPath {
+			t.Fatalf("expected a volume for pod %s at %s got %#v", podUID, targetPath, hpv)
+		}
+	}
+
+	if _, err := ns.NodeUnpublishVolume(context.TODO(), &csi.NodeUnpublishVolumeRequest{VolumeId: "pvc-1", TargetPath: targetPaths["uid1"]}); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if _, ok := hostPathVolumes.get("pvc-1-uid1"); ok {
+		t.Fatalf("expected the volume of pod uid1 to be deleted")
+	}
+	if _, ok := hostPathVolumes.get("pvc-1-uid2"); !ok {
+		t.Fatalf("expected the volume of pod uid2 to be kept")
+	}
+	if _, err := ns.NodeUnpublishVolume(context.TODO(), &csi.NodeUnpublishVolumeRequest{VolumeId: "pvc-1", TargetPath: targetPaths["uid2"]}); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if mnts, _ := ns.mounter.List(); len(mnts) != 0 {
+		t.Fatalf("expected the target paths to be unmounted got %#v", mnts)
+	}
+
+	if _, err := ns.NodeUnstageVolume(context.TODO(), &csi.NodeUnstageVolumeRequest{VolumeId: "pvc-1", StagingTargetPath: stagingPath}); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if _, err := os.Stat(stagingPath); !os.IsNotExist(err) {
+		t.Fatalf("expected the staging path to be removed: %v", err)
+	}
+	_, err = ns.NodeStageVolume(context.TODO(), &csi.NodeStageVolumeRequest{
+		VolumeId:          "pvc-1",
+		StagingTargetPath: stagingPath,
+		VolumeCapability:  mountCapability,
+	})
+	if err == nil || !strings.Contains(err.Error(), "missing the volumeAttribute 'share'") {
+		t.Fatalf("expected staging a volume without a share to fail got %v", err)
+	}
+}
+
+func TestNodePublishVolumeConcurrency(t *testing.T) {
+	ns, tmpDir, volPath, err := testNodeServer()
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	defer os.RemoveAll(tmpDir)
+	defer os.RemoveAll(volPath)
+	hostPathVolumes = newVolumeStore()
+	defer func() { hostPathVolumes = newVolumeStore() }()
+
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share-concurrency",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "Secret",
+				APIVersion: "v1",
+				Name:       "cool-secret",
+				Namespace:  "cool-secret-namespace",
+			},
+		},
+	}
+	secret := func(i int) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "cool-secret", Namespace: "cool-secret-namespace"},
+			Data:       map[string][]byte{"key": []byte(fmt.Sprintf("value%d", i))},
+		}
+	}
+	client.SetSharesLister(&fakeShareLister{share: share})
+	sarClient := fakekubeclientset.NewSimpleClientset()
+	sarClient.PrependReactor("create", "subjectaccessreviews", func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+	})
+	client.SetClient(sarClient)
+	objcache.UpsertSecret(secret(0))
+	objcache.AddShare(share)
+	defer objcache.DelSecret(secret(0))
+	defer objcache.DelShare(share)
+
+	const volumes = 10
+	const duplicates = 3
+	requests := []*csi.NodePublishVolumeRequest{}
+	for i := 0; i < volumes; i++ {
+		targetPath := getTestTargetPath(t)
+		defer os.RemoveAll(targetPath)
+		requests = append(requests, &csi.NodePublishVolumeRequest{
+			VolumeId:   fmt.Sprintf("volid-concurrency-%d", i),
+			TargetPath: targetPath,
+			VolumeCapability: &csi.VolumeCapability{
+				AccessType: &csi.VolumeCapability_Mount{
+					Mount: &csi.VolumeCapability_MountVolume{},
+				},
+			},
+			VolumeContext: map[string]string{
+				CSIEphemeral:              "true",
+				CSIPodName:                fmt.Sprintf("name%d", i),
+				CSIPodNamespace:           "namespace1",
+				CSIPodUID:                 fmt.Sprintf("uid%d", i),
+				CSIPodSA:                  "sa1",
+				ProjectedResourceShareKey: share.Name,
+			},
+		})
+	}
+
+	// the events of the share and its secret, and the stats of the volumes, race with the publishes and unpublishes
+	churn := func(wg *sync.WaitGroup) {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= 20; i++ {
+				objcache.UpsertSecret(secret(i))
+				objcache.UpdateShare(share)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for _, req := range requests {
+				ns.NodeGetVolumeStats(context.TODO(), &csi.NodeGetVolumeStatsRequest{VolumeId: req.VolumeId, VolumePath: req.TargetPath})
+			}
+		}()
+	}
+	errs := make(chan error, volumes*duplicates)
+
+	wg := &sync.WaitGroup{}
+	churn(wg)
+	for _, req := range requests {
+		for i := 0; i < duplicates; i++ {
+			wg.Add(1)
+			go func(req *csi.NodePublishVolumeRequest) {
+				defer wg.Done()
+				if _, err := ns.NodePublishVolume(context.TODO(), req); err != nil {
+					errs <- err
+				}
+			}(req)
+		}
+	}
+	wg.Wait()
+	select {
+	case err := <-errs:
+		t.Fatalf("unexpected publish err %s", err.Error())
+	default:
+	}
+	if published := hostPathVolumes.list(); len(published) != volumes {
+		t.Fatalf("expected %d volumes got %d", volumes, len(published))
+	}
+	// each volume is mounted once, however many times it was published
+	if mnts, _ := ns.mounter.List(); len(mnts) != volumes {
+		t.Fatalf("expected %d mount points got %d", volumes, len(mnts))
+	}
+
+	churn(wg)
+	for _, req := range requests {
+		for i := 0; i < duplicates; i++ {
+			wg.Add(1)
+			go func(req *csi.NodePublishVolumeRequest) {
+				defer wg.Done()
+				if _, err := ns.NodeUnpublishVolume(context.TODO(), &csi.NodeUnpublishVolumeRequest{VolumeId: req.VolumeId, TargetPath: req.TargetPath}); err != nil {
+					errs <- err
+				}
+			}(req)
+		}
+	}
+	wg.Wait()
+	select {
+	case err := <-errs:
+		t.Fatalf("unexpected unpublish err %s", err.Error())
+	default:
+	}
+	if published := hostPathVolumes.list(); len(published) != 0 {
+		t.Fatalf("expected no volume left got %d", len(published))
+	}
+	if mnts, _ := ns.mounter.List(); len(mnts) != 0 {
+		t.Fatalf("expected no mount point left got %d", len(mnts))
+	}
+}
+
+func TestNodePublishVolumeRepeated(t *testing.T) {
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share-repeated",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "Secret",
+				APIVersion: "v1",
+				Name:       "cool-secret",
+				Namespace:  "cool-secret-namespace",
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "cool-secret", Namespace: "cool-secret-namespace"},
+		Data:       map[string][]byte{"key": []byte("value")},
+	}
+	objcache.UpsertSecret(secret)
+	objcache.AddShare(share)
+	defer objcache.DelSecret(secret)
+	defer objcache.DelShare(share)
+
+	for _, test := range []struct {
+		name     string
+		readOnly bool
+		mounts   int
+	}{
+		{
+			name:   "read-write volume",
+			mounts: 1,
+		},
+		{
+			// the tmpfs of the staging path and its bind mount onto the target path
+			name:     "read-only volume",
+			readOnly: true,
+			mounts:   2,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ns, tmpDir, volPath, err := testNodeServer()
+			if err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			defer os.RemoveAll(tmpDir)
+			defer os.RemoveAll(volPath)
+			hostPathVolumes = newVolumeStore()
+			defer func() { hostPathVolumes = newVolumeStore() }()
+
+			client.SetSharesLister(&fakeShareLister{share: share})
+			sarClient := fakekubeclientset.NewSimpleClientset()
+			sarClient.PrependReactor("create", "subjectaccessreviews", func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+				return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+			})
+			client.SetClient(sarClient)
+
+			targetPath := getTestTargetPath(t)
+			defer os.RemoveAll(targetPath)
+			req := &csi.NodePublishVolumeRequest{
+				VolumeId:   "volid-repeated",
+				TargetPath: targetPath,
+				Readonly:   test.readOnly,
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{},
+					},
+				},
+				VolumeContext: map[string]string{
+					CSIEphemeral:              "true",
+					CSIPodName:                "name1",
+					CSIPodNamespace:           "namespace1",
+					CSIPodUID:                 "uid1",
+					CSIPodSA:                  "sa1",
+					ProjectedResourceShareKey: share.Name,
+				},
+			}
+			volID := volumeKey(req.VolumeId, req.VolumeContext)
+			expectPublished := func(step string) *hostPathVolume {
+				if _, err := ns.NodePublishVolume(context.TODO(), req); err != nil {
+					t.Fatalf("%s: unexpected publish err %s", step, err.Error())
+				}
+				if published := hostPathVolumes.list(); len(published) != 1 {
+					t.Fatalf("%s: expected one volume got %d", step, len(published))
+				}
+				if mnts, _ := ns.mounter.List(); len(mnts) != test.mounts {
+					t.Fatalf("%s: expected %d mount points got %#v", step, test.mounts, mnts)
+				}
+				vol, _ := hostPathVolumes.get(volID)
+				if _, err := os.Stat(filepath.Join(vol.dataPath(), "..data")); err != nil {
+					t.Fatalf("%s: expected the data of the volume: %s", step, err.Error())
+				}
+				return vol
+			}
+
+			first := expectPublished("first publish")
+			size := first.VolSize
+
+			// the volume is mounted, publishing it again leaves it as it is
+			if again := expectPublished("publish of the mounted volume"); again != first {
+				t.Fatalf("expected the published volume to be kept")
+			}
+
+			// the mount of the target path is gone, publishing the volume again mounts and populates the volume
+			// recorded, with the size limit it was published with, even if its volume attributes ask for another
+			if err := ns.mounter.Unmount(targetPath); err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			req.VolumeContext[ProjectedResourceSizeLimitKey] = "4Mi"
+			again := expectPublished("publish of the unmounted volume")
+			if again != first || again.VolSize != size {
+				t.Fatalf("expected the volume recorded to be published again with size limit %d got %#v", size, again)
+			}
+			delete(req.VolumeContext, ProjectedResourceSizeLimitKey)
+
+			// the volume ID of a volume published at a target path is not published at another one
+			otherTargetPath := getTestTargetPath(t)
+			defer os.RemoveAll(otherTargetPath)
+			other := *req
+			other.TargetPath = otherTargetPath
+			if _, err := ns.NodePublishVolume(context.TODO(), &other); status.Code(err) != codes.AlreadyExists {
+				t.Fatalf("expected an AlreadyExists err got %v", err)
+			}
+			if stored, _ := hostPathVolumes.get(volID); stored != first {
+				t.Fatalf("expected the published volume to be kept")
+			}
+
+			if _, err := ns.NodeUnpublishVolume(context.TODO(), &csi.NodeUnpublishVolumeRequest{VolumeId: req.VolumeId, TargetPath: targetPath}); err != nil {
+				t.Fatalf("unexpected unpublish err %s", err.Error())
+			}
+			if published := hostPathVolumes.list(); len(published) != 0 {
+				t.Fatalf("expected no volume left got %d", len(published))
+			}
+			if mnts, _ := ns.mounter.List(); len(mnts) != 0 {
+				t.Fatalf("expected no mount point left got %#v", mnts)
+			}
+		})
+	}
+}
+
+func TestNodePublishVolumeLimits(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "cool-secret", Namespace: "cool-secret-namespace"},
+		Data:       map[string][]byte{"big": make([]byte, 2048)},
+	}
+	objcache.UpsertSecret(secret)
+	defer objcache.DelSecret(secret)
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share1",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "Secret",
+				APIVersion: "v1",
+				Name:       "cool-secret",
+				Namespace:  "cool-secret-namespace",
+			},
+		},
+	}
+	objcache.AddShare(share)
+	defer objcache.DelShare(share)
+
+	tests := []struct {
+		name              string
+		maxVolumesPerNode int64
+		maxBytesPerNode   int64
+		sizeLimit         string
+		otherVolumeSize   int64
+		expectedMsg       string
+		expectedSize      int64
+	}{
+		{
+			name:         "default size limit",
+			expectedSize: minVolumeSize,
+		},
+		{
+			name:         "size limit attribute",
+			sizeLimit:    "4Ki",
+			expectedSize: 4096,
+		},
+		{
+			name:        "data over size limit",
+			sizeLimit:   "1Ki",
+			expectedMsg: "the data of the share share1 is 2048 bytes, over the volume size limit of 1024 bytes",
+		},
+		{
+			name:              "too many volumes",
+			maxVolumesPerNode: 1,
+			otherVolumeSize:   minVolumeSize,
+			expectedMsg:       "the node already has its maximum of 1 projected resource volumes",
+		},
+		{
+			name:            "memory budget exceeded",
+			maxBytesPerNode: 2*minVolumeSize + 2*4096,
+			otherVolumeSize: minVolumeSize,
+			sizeLimit:       "8Ki",
+			expectedMsg:     "a volume with a tmpfs of 16384 bytes does not fit in the projected resource memory budget of the node",
+		},
+		{
+			// the tmpfs of the volumes, twice their size limits, are what is charged against the budget
+			name:            "memory budget exceeded by one byte",
+			maxBytesPerNode: 2*minVolumeSize + 2*4096 - 1,
+			otherVolumeSize: minVolumeSize,
+			sizeLimit:       "4Ki",
+			expectedMsg:     "a volume with a tmpfs of 8192 bytes does not fit in the projected resource memory budget of the node",
+		},
+		{
+			name:            "memory budget exactly full",
+			maxBytesPerNode: 2*minVolumeSize + 2*4096,
+			otherVolumeSize: minVolumeSize,
+			sizeLimit:       "4Ki",
+			expectedSize:    4096,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ns, tmpDir, volPath, err := testNodeServer()
+			if err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			defer os.RemoveAll(tmpDir)
+			defer os.RemoveAll(volPath)
+			targetPath := getTestTargetPath(t)
+			defer os.RemoveAll(targetPath)
+			ns.maxVolumesPerNode = test.maxVolumesPerNode
+			ns.maxBytesPerNode = test.maxBytesPerNode
+
+			client.SetSharesLister(&fakeShareLister{share: share})
+			sarClient := fakekubeclientset.NewSimpleClientset()
+			sarClient.PrependReactor("create", "subjectaccessreviews", func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+				return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+			})
+			client.SetClient(sarClient)
+
+			hostPathVolumes = newVolumeStore()
+			defer func() { hostPathVolumes = newVolumeStore() }()
+			if test.otherVolumeSize > 0 {
+				hostPathVolumes.put(&hostPathVolume{VolID: "othervolid", VolSize: test.otherVolumeSize})
+			}
+
+			volCtx := map[string]string{
+				CSIEphemeral:              "true",
+				CSIPodName:                "name1",
+				CSIPodNamespace:           "namespace1",
+				CSIPodUID:                 "uid1",
+				CSIPodSA:                  "sa1",
+				ProjectedResourceShareKey: "share1",
+			}
+			if len(test.sizeLimit) > 0 {
+				volCtx[ProjectedResourceSizeLimitKey] = test.sizeLimit
+			}
+			_, err = ns.NodePublishVolume(context.TODO(), &csi.NodePublishVolumeRequest{
+				VolumeId:   "testvolid1",
+				TargetPath: targetPath,
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{},
+					},
+				},
+				VolumeContext: volCtx,
+			})
+			defer objcache.UnregisterObjectCallbacks("testvolid1")
+			if len(test.expectedMsg) > 0 {
+				if err == nil || status.Code(err) != codes.ResourceExhausted || !strings.Contains(err.Error(), test.expectedMsg) {
+					t.Fatalf("expected ResourceExhausted err containing %s got %v", test.expectedMsg, err)
+				}
+				if _, ok := hostPathVolumes.get("testvolid1"); ok {
+					t.Fatalf("expected the volume to not be kept")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			if hpv, _ := hostPathVolumes.get("testvolid1"); hpv.VolSize != test.expectedSize {
+				t.Fatalf("expected size limit %d got %d", test.expectedSize, hpv.VolSize)
+			}
+			mnts, _ := ns.mounter.List()
+			if len(mnts) != 1 || !reflect.DeepEqual(mnts[0].Opts, []string{fmt.Sprintf("size=%d", 2*test.expectedSize)}) {
+				t.Fatalf("expected a tmpfs of twice the size limit got %#v", mnts)
+			}
+		})
+	}
+}
+
+// failingMounter fails the mounts onto a target path
+type failingMounter struct {
+	*mount.FakeMounter
+	target string
+}
+
+func (m *failingMounter) Mount(source string, target string, fstype string, options []string) error {
+	if target == m.target {
+		return fmt.Errorf("mount of %s failed", target)
+	}
+	return m.FakeMounter.Mount(source, target, fstype, options)
+}
+
+func TestNodePublishVolumeMountFailure(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "cool-secret", Namespace: "cool-secret-namespace"},
+		Data:       map[string][]byte{"key": []byte("value")},
+	}
+	objcache.UpsertSecret(secret)
+	defer objcache.DelSecret(secret)
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share1",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "Secret",
+				APIVersion: "v1",
+				Name:       "cool-secret",
+				Namespace:  "cool-secret-namespace",
+			},
+		},
+	}
+	objcache.AddShare(share)
+	defer objcache.DelShare(share)
+
+	for _, test := range []struct {
+		name     string
+		readOnly bool
+	}{
+		{
+			name: "tmpfs mount fails",
+		},
+		{
+			name:     "read-only bind mount fails",
+			readOnly: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ns, tmpDir, volPath, err := testNodeServer()
+			if err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			defer os.RemoveAll(tmpDir)
+			defer os.RemoveAll(volPath)
+			targetPath := getTestTargetPath(t)
+			defer os.RemoveAll(targetPath)
+			mounter := &failingMounter{FakeMounter: mount.NewFakeMounter([]mount.MountPoint{}), target: targetPath}
+			ns.mounter = mounter
+			// the node has room for a single volume
+			ns.maxVolumesPerNode = 1
+			ns.maxBytesPerNode = tmpfsSize(minVolumeSize)
+
+			client.SetSharesLister(&fakeShareLister{share: share})
+			sarClient := fakekubeclientset.NewSimpleClientset()
+			sarClient.PrependReactor("create", "subjectaccessreviews", func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+				return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+			})
+			client.SetClient(sarClient)
+			hostPathVolumes = newVolumeStore()
+			defer func() { hostPathVolumes = newVolumeStore() }()
+
+			publish := func(volID, targetPath string) error {
+				_, err := ns.NodePublishVolume(context.TODO(), &csi.NodePublishVolumeRequest{
+					VolumeId:   volID,
+					TargetPath: targetPath,
+					Readonly:   test.readOnly,
+					VolumeCapability: &csi.VolumeCapability{
+						AccessType: &csi.VolumeCapability_Mount{
+							Mount: &csi.VolumeCapability_MountVolume{},
+						},
+					},
+					VolumeContext: map[string]string{
+						CSIEphemeral:              "true",
+						CSIPodName:                "name1",
+						CSIPodNamespace:           "namespace1",
+						CSIPodUID:                 "uid1",
+						CSIPodSA:                  "sa1",
+						ProjectedResourceShareKey: "share1",
+					},
+				})
+				return err
+			}
+			if err := publish("testvolid1", targetPath); err == nil || status.Code(err) != codes.Internal {
+				t.Fatalf("expected an Internal err got %v", err)
+			}
+			if published := hostPathVolumes.list(); len(published) != 0 {
+				t.Fatalf("expected the volume of the failed publish to be deleted got %#v", published)
+			}
+			if mnts, _ := mounter.List(); len(mnts) != 0 {
+				t.Fatalf("expected the mounts of the failed publish to be cleaned up got %#v", mnts)
+			}
+			if _, err := os.Stat(ns.hp.getStagingPath("testvolid1")); !os.IsNotExist(err) {
+				t.Fatalf("expected the staging path of the failed publish to be removed got %v", err)
+			}
+
+			// the volume count and memory budget of the failed publish are released for another volume
+			otherTargetPath := getTestTargetPath(t)
+			defer os.RemoveAll(otherTargetPath)
+			if err := publish("testvolid2", otherTargetPath); err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			defer objcache.UnregisterObjectCallbacks("testvolid2")
+			if published := hostPathVolumes.list(); len(published) != 1 {
+				t.Fatalf("expected one volume got %#v", published)
+			}
+		})
+	}
+}
+
+func TestNodeGetVolumeStats(t *testing.T) {
+	share := &sharev1alpha1.Share{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share1",
+		},
+		Spec: sharev1alpha1.ShareSpec{
+			BackingResource: sharev1alpha1.BackingResource{
+				Kind:       "Secret",
+				APIVersion: "v1",
+				Name:       "cool-secret",
+				Namespace:  "cool-secret-namespace",
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "cool-secret", Namespace: "cool-secret-namespace"},
+	}
+	tests := []struct {
+		name            string
+		share           *sharev1alpha1.Share
+		secret          *corev1.Secret
+		notAllowed      bool
+		expectedMsg     string
+		expectAbnormal  bool
+		expectCondition string
+	}{
+		{
+			name:            "projected",
+			share:           share,
+			secret:          secret,
+			expectCondition: "the share share1 is projected",
+		},
+		{
+			name:            "share deleted",
+			secret:          secret,
+			notAllowed:      true,
+			expectAbnormal:  true,
+			expectCondition: "the share share1 was deleted",
+		},
+		{
+			name:            "permission lost",
+			share:           share,
+			secret:          secret,
+			notAllowed:      true,
+			expectAbnormal:  true,
+			expectCondition: "the pod no longer has permission to use the share share1",
+		},
+		{
+			name:            "backing object missing",
+			share:           share,
+			expectAbnormal:  true,
+			expectCondition: "the Secret cool-secret-namespace:cool-secret of the share share1 is missing",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ns, tmpDir, volPath, err := testNodeServer()
+			if err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			defer os.RemoveAll(tmpDir)
+			defer os.RemoveAll(volPath)
+			targetPath := getTestTargetPath(t)
+			defer os.RemoveAll(targetPath)
+			if err := ioutil.WriteFile(filepath.Join(targetPath, "data"), []byte("12345"), 0644); err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+
+			client.SetSharesLister(&fakeShareLister{share: test.share})
+			if test.secret != nil {
+				objcache.UpsertSecret(test.secret)
+				objcache.AddShare(share)
+				defer objcache.DelShare(share)
+				defer objcache.DelSecret(test.secret)
+			}
+			hostPathVolumes.put(&hostPathVolume{
+				VolID:        "testvolid1",
+				VolSize:      1024,
+				TargetPath:   targetPath,
+				SharedData:   sharedDataFromShare(share),
+				SharedDataId: "share1",
+				Allowed:      !test.notAllowed,
+			})
+			defer hostPathVolumes.remove("testvolid1")
+
+			resp, err := ns.NodeGetVolumeStats(context.TODO(), &csi.NodeGetVolumeStatsRequest{VolumeId: "testvolid1", VolumePath: targetPath})
+			if err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			condition := resp.GetVolumeCondition()
+			if condition.GetAbnormal() != test.expectAbnormal || condition.GetMessage() != test.expectCondition {
+				t.Fatalf("expected condition abnormal %v %q got %#v", test.expectAbnormal, test.expectCondition, condition)
+			}
+			for _, usage := range resp.GetUsage() {
+				switch usage.GetUnit() {
+				case csi.VolumeUsage_BYTES:
+					if usage.GetUsed() != 5 || usage.GetTotal() != 1024 || usage.GetAvailable() != 1019 {
+						t.Fatalf("unexpected bytes usage %#v", usage)
+					}
+				case csi.VolumeUsage_INODES:
+					// the target path and the data file
+					if usage.GetUsed() != 2 {
+						t.Fatalf("unexpected inodes usage %#v", usage)
+					}
+				}
+			}
+		})
+	}
+
+	ns, tmpDir, volPath, err := testNodeServer()
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	defer os.RemoveAll(tmpDir)
+	defer os.RemoveAll(volPath)
+	_, err = ns.NodeGetVolumeStats(context.TODO(), &csi.NodeGetVolumeStatsRequest{VolumeId: "unknown", VolumePath: "/unknown"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound for an unknown volume got %v", err)
+	}
+}
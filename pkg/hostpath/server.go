@@ -22,6 +22,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
@@ -40,17 +41,24 @@ func NewNonBlockingGRPCServer() *nonBlockingGRPCServer {
 type nonBlockingGRPCServer struct {
 	wg     sync.WaitGroup
 	server *grpc.Server
+	// listening is set once the server listens on its endpoint
+	listening int32
 }
 
-func (s *nonBlockingGRPCServer) Start(endpoint string, ids csi.IdentityServer, ns csi.NodeServer) {
+func (s *nonBlockingGRPCServer) Start(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) {
 
 	s.wg.Add(1)
 
-	go s.serve(endpoint, ids, ns)
+	go s.serve(endpoint, ids, cs, ns)
 
 	return
 }
 
+// Listening returns whether the server listens on its endpoint
+func (s *nonBlockingGRPCServer) Listening() bool {
+	return atomic.LoadInt32(&s.listening) == 1
+}
+
 func (s *nonBlockingGRPCServer) Wait() {
 	s.wg.Wait()
 }
@@ -63,7 +71,7 @@ func (s *nonBlockingGRPCServer) ForceStop() {
 	s.server.Stop()
 }
 
-func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, ns csi.NodeServer) {
+func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) {
 
 	proto, addr, err := parseEndpoint(endpoint)
 	if err != nil {
@@ -91,13 +99,18 @@ func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, n
 	if ids != nil {
 		csi.RegisterIdentityServer(server, ids)
 	}
+	if cs != nil {
+		csi.RegisterControllerServer(server, cs)
+	}
 	if ns != nil {
 		csi.RegisterNodeServer(server, ns)
 	}
 
 	klog.Infof("Listening for connections on address: %#v", listener.Addr())
 
+	atomic.StoreInt32(&s.listening, 1)
 	server.Serve(listener)
+	atomic.StoreInt32(&s.listening, 0)
 
 }
 
@@ -0,0 +1,103 @@
+package hostpath
+
+import (
+	"sync"
+)
+
+// volumeStore holds the volumes published on the node. The operations on a volume, from the gRPC handlers and
+// from the callbacks of the cache, are serialized by the lock of its volume ID; the fields of a volume are only
+// read or written with that lock held. The state of each volume as last saved is what is persisted and listed,
+// so that the volumes can be accounted for without taking the lock of each of them.
+type volumeStore struct {
+	lock    sync.RWMutex
+	volumes map[string]*hostPathVolume
+	saved   map[string]hostPathVolume
+	locks   map[string]*volumeLock
+}
+
+// volumeLock is the lock of a volume ID, dropped once no operation holds or waits for it
+type volumeLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newVolumeStore() *volumeStore {
+	return &volumeStore{
+		volumes: map[string]*hostPathVolume{},
+		saved:   map[string]hostPathVolume{},
+		locks:   map[string]*volumeLock{},
+	}
+}
+
+// lockVolume locks a volume ID, and returns the function unlocking it
+func (s *volumeStore) lockVolume(volID string) func() {
+	s.lock.Lock()
+	l, ok := s.locks[volID]
+	if !ok {
+		l = &volumeLock{}
+		s.locks[volID] = l
+	}
+	l.refs++
+	s.lock.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		l.refs--
+		if l.refs == 0 {
+			delete(s.locks, volID)
+		}
+	}
+}
+
+// get returns the volume of a volume ID
+func (s *volumeStore) get(volID string) (*hostPathVolume, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	hpv, ok := s.volumes[volID]
+	return hpv, ok
+}
+
+// current returns true if the volume is the one stored for its volume ID, that is, it was neither deleted
+// nor replaced by a new publish
+func (s *volumeStore) current(hpv *hostPathVolume) bool {
+	stored, ok := s.get(hpv.VolID)
+	return ok && stored == hpv
+}
+
+// put stores a volume, replacing any previous volume of its volume ID
+func (s *volumeStore) put(hpv *hostPathVolume) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.volumes[hpv.VolID] = hpv
+	s.saved[hpv.VolID] = *hpv
+}
+
+// save records the state of a volume to persist and list, if it is still stored
+func (s *volumeStore) save(hpv *hostPathVolume) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.volumes[hpv.VolID] == hpv {
+		s.saved[hpv.VolID] = *hpv
+	}
+}
+
+func (s *volumeStore) remove(volID string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.volumes, volID)
+	delete(s.saved, volID)
+}
+
+// list returns the state of the volumes as last saved, by volume ID
+func (s *volumeStore) list() map[string]hostPathVolume {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	volumes := make(map[string]hostPathVolume, len(s.saved))
+	for volID, hpv := range s.saved {
+		volumes[volID] = hpv
+	}
+	return volumes
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	namespace = "openshift_csi_projected_resource"
+
+	// the results of subject access reviews
+	SARAllowed = "allowed"
+	SARDenied  = "denied"
+	SARError   = "error"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	nodeOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "node_operations_total",
+		Help:      "Number of NodePublishVolume and NodeUnpublishVolume calls, by operation and gRPC status code.",
+	}, []string{"operation", "code"})
+	nodeOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "node_operation_duration_seconds",
+		Help:      "Latency of NodePublishVolume and NodeUnpublishVolume calls, by operation and gRPC status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "code"})
+	sarDecisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "subject_access_reviews_total",
+		Help:      "Number of subject access reviews of the permission of pods to use shares, by resource and result.",
+	}, []string{"resource", "result"})
+	volumes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "volumes",
+		Help:      "Number of volumes published on the node, by share.",
+	}, []string{"share"})
+	updateLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "update_latency_seconds",
+		Help:      "Time from an informer event for a backing resource to its data being written to the volumes it is projected into, by kind.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"kind"})
+	fileSystemErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "filesystem_errors_total",
+		Help:      "Number of errors writing the data of volumes, by the reason of the event raised for them.",
+	}, []string{"reason"})
+	workqueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "workqueue_depth",
+		Help:      "Number of events waiting in a workqueue of the controller, by workqueue.",
+	}, []string{"name"})
+)
+
+func init() {
+	registry.MustRegister(nodeOperations, nodeOperationDuration, sarDecisions, volumes, updateLatency, fileSystemErrors,
+		workqueueDepth)
+	// the provider has to be set before the workqueues of the controller are created
+	workqueue.SetProvider(workqueueMetricsProvider{})
+}
+
+// Handler serves the metrics of the driver
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveNodeOperation records the outcome and latency of a NodePublishVolume or NodeUnpublishVolume call
+func ObserveNodeOperation(operation, code string, start time.Time) {
+	nodeOperations.WithLabelValues(operation, code).Inc()
+	nodeOperationDuration.WithLabelValues(operation, code).Observe(time.Since(start).Seconds())
+}
+
+// RecordSAR records the result of the subject access review of the permission of a pod to use a share
+func RecordSAR(resource, result string) {
+	sarDecisions.WithLabelValues(resource, result).Inc()
+}
+
+// SetVolumes sets the number of volumes published on the node for each share
+func SetVolumes(volumesByShare map[string]int) {
+	volumes.Reset()
+	for share, count := range volumesByShare {
+		volumes.WithLabelValues(share).Set(float64(count))
+	}
+}
+
+// ObserveUpdate records the time from an informer event for a backing resource to its data being written
+func ObserveUpdate(kind string, eventTime time.Time) {
+	updateLatency.WithLabelValues(kind).Observe(time.Since(eventTime).Seconds())
+}
+
+// RecordFileSystemError records an error writing the data of a volume
+func RecordFileSystemError(reason string) {
+	fileSystemErrors.WithLabelValues(reason).Inc()
+}
+
+// workqueueMetricsProvider provides the depth of the workqueues of the controller; their other metrics are not
+// collected
+type workqueueMetricsProvider struct{}
+
+func (workqueueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return workqueueDepth.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return noopMetric{}
+}
+
+func (workqueueMetricsProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return noopMetric{}
+}
+
+func (workqueueMetricsProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	return noopMetric{}
+}
+
+func (workqueueMetricsProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return noopMetric{}
+}
+
+func (workqueueMetricsProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return noopMetric{}
+}
+
+func (workqueueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return noopMetric{}
+}
+
+type noopMetric struct{}
+
+func (noopMetric) Inc()            {}
+func (noopMetric) Dec()            {}
+func (noopMetric) Set(float64)     {}
+func (noopMetric) Observe(float64) {}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestMetrics(t *testing.T) {
+	ObserveNodeOperation("NodePublishVolume", "OK", time.Now())
+	ObserveNodeOperation("NodePublishVolume", "PermissionDenied", time.Now())
+	if count := testutil.ToFloat64(nodeOperations.WithLabelValues("NodePublishVolume", "OK")); count != 1 {
+		t.Fatalf("expected 1 successful publish got %v", count)
+	}
+
+	RecordSAR("share", SARAllowed)
+	RecordSAR("share", SARDenied)
+	RecordSAR("share", SARDenied)
+	if count := testutil.ToFloat64(sarDecisions.WithLabelValues("share", SARDenied)); count != 2 {
+		t.Fatalf("expected 2 denied subject access reviews got %v", count)
+	}
+
+	SetVolumes(map[string]int{"share1": 2, "share2": 1})
+	SetVolumes(map[string]int{"share1": 1})
+	if count := testutil.CollectAndCount(volumes); count != 1 {
+		t.Fatalf("expected the volumes of share2 to be dropped got %d series", count)
+	}
+	if count := testutil.ToFloat64(volumes.WithLabelValues("share1")); count != 1 {
+		t.Fatalf("expected 1 volume for share1 got %v", count)
+	}
+
+	RecordFileSystemError("VolumeSizeLimitExceeded")
+	if count := testutil.ToFloat64(fileSystemErrors.WithLabelValues("VolumeSizeLimitExceeded")); count != 1 {
+		t.Fatalf("expected 1 filesystem error got %v", count)
+	}
+
+	queue := workqueue.NewNamed("projected-resource-test-changes")
+	defer queue.ShutDown()
+	queue.Add("a")
+	queue.Add("b")
+	if depth := testutil.ToFloat64(workqueueDepth.WithLabelValues("projected-resource-test-changes")); depth != 2 {
+		t.Fatalf("expected a workqueue depth of 2 got %v", depth)
+	}
+
+	ObserveUpdate("Secret", time.Now().Add(-time.Second))
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := ioutil.ReadAll(w.Body)
+	for _, name := range []string{
+		"openshift_csi_projected_resource_node_operation_duration_seconds",
+		"openshift_csi_projected_resource_subject_access_reviews_total",
+		"openshift_csi_projected_resource_update_latency_seconds_count{kind=\"Secret\"} 1",
+		"openshift_csi_projected_resource_workqueue_depth",
+	} {
+		if !strings.Contains(string(body), name) {
+			t.Fatalf("expected %s in the metrics got %s", name, string(body))
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package validation
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	utilvalidation "k8s.io/apimachinery/pkg/util/validation"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/util/jsonpath"
+
+	sharev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+)
+
+// namespacedShareKinds holds the sets.String of the group version kinds other than ConfigMap and Secret that the
+// backing resources of NamespacedShares may be of
+var namespacedShareKinds atomic.Value
+
+func init() {
+	namespacedShareKinds.Store(sets.NewString())
+}
+
+// SetNamespacedShareKinds sets the kinds other than ConfigMap and Secret that the backing resources of
+// NamespacedShares may be of. The driver reads those objects with its own permissions, which the namespace admins
+// creating NamespacedShares do not necessarily have, so no other kind is allowed unless a cluster admin lists it
+func SetNamespacedShareKinds(kinds []metav1.TypeMeta) {
+	allowed := sets.NewString()
+	for _, kind := range kinds {
+		allowed.Insert(schema.FromAPIVersionAndKind(strings.TrimSpace(kind.APIVersion), strings.TrimSpace(kind.Kind)).String())
+	}
+	namespacedShareKinds.Store(allowed)
+}
+
+// KindDirectory returns the directory under a volume's target path where backing resources
+// of the given kind are stored, when they are projected without items
+func KindDirectory(kind string) string {
+	switch kind {
+	case "ConfigMap":
+		return "configmaps"
+	case "Secret":
+		return "secrets"
+	}
+	return ""
+}
+
+// ValidateKeyToPath checks that an item mapping a key of a backing resource to a file
+// results in a file within the volume it is projected into
+func ValidateKeyToPath(item corev1.KeyToPath) error {
+	if len(strings.TrimSpace(item.Key)) == 0 {
+		return fmt.Errorf("item key needs to be set")
+	}
+	if len(strings.TrimSpace(item.Path)) == 0 {
+		return fmt.Errorf("item path for key %s needs to be set", item.Key)
+	}
+	if filepath.IsAbs(item.Path) {
+		return fmt.Errorf("item path %s for key %s must be relative", item.Path, item.Key)
+	}
+	for _, element := range strings.Split(item.Path, "/") {
+		if element == ".." {
+			return fmt.Errorf("item path %s for key %s must not contain '..'", item.Path, item.Key)
+		}
+	}
+	// the paths starting with '..' are reserved for the data directories of the volume
+	if strings.HasPrefix(item.Path, "..") {
+		return fmt.Errorf("item path %s for key %s must not start with '..'", item.Path, item.Key)
+	}
+	if item.Mode != nil && (*item.Mode < 0 || *item.Mode > 0777) {
+		return fmt.Errorf("item mode %o for key %s must be between 0 and 0777", *item.Mode, item.Key)
+	}
+	return nil
+}
+
+// ValidateBackingResourceKind checks that a backing resource is either a Secret or ConfigMap, or is
+// of another kind identified by its apiVersion, whose fields to project are listed
+func ValidateBackingResourceKind(br sharev1alpha1.BackingResource) error {
+	kind := strings.TrimSpace(br.Kind)
+	switch {
+	case kind == "ConfigMap" || kind == "Secret":
+		if len(br.Fields) > 0 {
+			return fmt.Errorf("fields cannot be set for kind %s, items select its keys instead", kind)
+		}
+		return nil
+	case len(kind) == 0:
+		return fmt.Errorf("kind needs to be set")
+	case len(strings.TrimSpace(br.APIVersion)) == 0:
+		return fmt.Errorf("apiVersion needs to be set for kind %s", kind)
+	case len(br.Fields) == 0:
+		return fmt.Errorf("fields need to be set for kind %s", kind)
+	case len(br.Items) > 0:
+		return fmt.Errorf("items cannot be set for kind %s, fields select what is projected instead", kind)
+	case br.Selector != nil:
+		return fmt.Errorf("selector can only be set for kinds ConfigMap and Secret")
+	}
+	if _, err := schema.ParseGroupVersion(strings.TrimSpace(br.APIVersion)); err != nil {
+		return fmt.Errorf("apiVersion %s is invalid: %s", br.APIVersion, err.Error())
+	}
+	for _, field := range br.Fields {
+		if _, err := ParseJSONPath(field.JSONPath); err != nil {
+			return fmt.Errorf("field jsonPath %s is invalid: %s", field.JSONPath, err.Error())
+		}
+		if err := ValidateKeyToPath(corev1.KeyToPath{Key: field.JSONPath, Path: field.Path, Mode: field.Mode}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateBackingResource checks everything about a backing resource that can be determined from the backing
+// resource alone: its kind and apiVersion, that its namespace and name are valid object names, and its selector
+// and items
+func ValidateBackingResource(br sharev1alpha1.BackingResource) error {
+	if err := ValidateBackingResourceKind(br); err != nil {
+		return err
+	}
+	kind := strings.TrimSpace(br.Kind)
+	apiVersion := strings.TrimSpace(br.APIVersion)
+	if (kind == "ConfigMap" || kind == "Secret") && len(apiVersion) > 0 && apiVersion != "v1" {
+		return fmt.Errorf("apiVersion %s does not match kind %s, which is v1", br.APIVersion, kind)
+	}
+	if len(strings.TrimSpace(br.Namespace)) == 0 {
+		return fmt.Errorf("namespace needs to be set")
+	}
+	if msgs := utilvalidation.IsDNS1123Label(br.Namespace); len(msgs) > 0 {
+		return fmt.Errorf("namespace %s is invalid: %s", br.Namespace, strings.Join(msgs, ", "))
+	}
+	if len(strings.TrimSpace(br.Name)) == 0 && br.Selector == nil {
+		return fmt.Errorf("name or selector needs to be set")
+	}
+	if len(br.Name) > 0 {
+		if msgs := utilvalidation.IsDNS1123Subdomain(br.Name); len(msgs) > 0 {
+			return fmt.Errorf("name %s is invalid: %s", br.Name, strings.Join(msgs, ", "))
+		}
+	}
+	if err := ValidateSelector(br); err != nil {
+		return err
+	}
+	for _, item := range br.Items {
+		if err := ValidateKeyToPath(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateProjectedPaths checks that no two items or fields of the backing resources of a share are projected
+// to the same path, nor one to a path within the path of another, as one file would then replace the other. The
+// directories of the kinds of the backing resources projected without items, or by selector, are reserved too.
+func ValidateProjectedPaths(brs []sharev1alpha1.BackingResource) error {
+	paths := map[string]string{}
+	kindDirs := map[string]string{}
+	for _, br := range brs {
+		owner := fmt.Sprintf("%s %s:%s", br.Kind, br.Namespace, br.Name)
+		// the objects selected are projected without items, like those of a backing resource listing none
+		if dir := KindDirectory(strings.TrimSpace(br.Kind)); len(dir) > 0 && (br.Selector != nil || len(br.Items) == 0) {
+			if _, ok := kindDirs[dir]; !ok {
+				kindDirs[dir] = owner
+			}
+			continue
+		}
+		brPaths := []string{}
+		for _, item := range br.Items {
+			brPaths = append(brPaths, item.Path)
+		}
+		for _, field := range br.Fields {
+			brPaths = append(brPaths, field.Path)
+		}
+		for _, path := range brPaths {
+			path = filepath.Clean(path)
+			if other, ok := paths[path]; ok {
+				return fmt.Errorf("item path %s is projected by both backing resource %s and backing resource %s", path, other, owner)
+			}
+			paths[path] = owner
+		}
+	}
+	for path, owner := range paths {
+		for dir := path; dir != "." && dir != "/"; dir = filepath.Dir(dir) {
+			if other, ok := kindDirs[dir]; ok {
+				return fmt.Errorf("item path %s of backing resource %s conflicts with directory %s, where backing resource %s is projected",
+					path, owner, dir, other)
+			}
+			if other, ok := paths[dir]; ok && dir != path {
+				return fmt.Errorf("item path %s of backing resource %s is within item path %s of backing resource %s",
+					path, owner, dir, other)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateUpdatePolicy checks that an update policy, of a share or from the volume attributes of a pod, is
+// either empty or one of the supported ones
+func ValidateUpdatePolicy(policy sharev1alpha1.UpdatePolicy) error {
+	switch policy {
+	case "", sharev1alpha1.UpdatePolicyImmediate, sharev1alpha1.UpdatePolicyOnPodRestart:
+		return nil
+	}
+	return fmt.Errorf("update policy %s is not one of %s or %s", policy,
+		sharev1alpha1.UpdatePolicyImmediate, sharev1alpha1.UpdatePolicyOnPodRestart)
+}
+
+// ValidateDefaultModes checks that the default file modes of a share are between 0 and 0777
+func ValidateDefaultModes(spec sharev1alpha1.ShareSpec) error {
+	for name, mode := range map[string]*int32{
+		"defaultMode":          spec.DefaultMode,
+		"secretDefaultMode":    spec.SecretDefaultMode,
+		"configMapDefaultMode": spec.ConfigMapDefaultMode,
+	} {
+		if mode != nil && (*mode < 0 || *mode > 0777) {
+			return fmt.Errorf("%s %o must be between 0 and 0777", name, *mode)
+		}
+	}
+	return nil
+}
+
+// ParseFileMode parses a file mode from the volume attributes of a pod; an octal value with a leading 0,
+// as in 0640, or a decimal one
+func ParseFileMode(value string) (int32, error) {
+	mode, err := strconv.ParseInt(strings.TrimSpace(value), 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("file mode %s is not a number", value)
+	}
+	if mode < 0 || mode > 0777 {
+		return 0, fmt.Errorf("file mode %s must be between 0 and 0777", value)
+	}
+	return int32(mode), nil
+}
+
+// ParseJSONPath parses the expression of a field of a backing resource, whose enclosing braces are optional
+func ParseJSONPath(expression string) (*jsonpath.JSONPath, error) {
+	expression = strings.TrimSpace(expression)
+	if len(expression) == 0 {
+		return nil, fmt.Errorf("expression needs to be set")
+	}
+	if !strings.HasPrefix(expression, "{") {
+		expression = "{" + expression + "}"
+	}
+	j := jsonpath.New("field")
+	if err := j.Parse(expression); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// ValidateSelector checks that a backing resource selecting objects by label does not also name one,
+// or list items, whose paths every selected object would be written to
+func ValidateSelector(br sharev1alpha1.BackingResource) error {
+	if br.Selector == nil {
+		return nil
+	}
+	if len(strings.TrimSpace(br.Name)) > 0 {
+		return fmt.Errorf("name %s and selector cannot both be set", br.Name)
+	}
+	if len(br.Items) > 0 {
+		return fmt.Errorf("items cannot be set along with a selector")
+	}
+	if _, err := metav1.LabelSelectorAsSelector(br.Selector); err != nil {
+		return fmt.Errorf("selector is invalid: %s", err.Error())
+	}
+	return nil
+}
+
+// ValidateNamespacedBackingResourceKind checks that the backing resource of a NamespacedShare is a ConfigMap or a
+// Secret, or of one of the kinds set with SetNamespacedShareKinds
+func ValidateNamespacedBackingResourceKind(br sharev1alpha1.BackingResource) error {
+	kind := strings.TrimSpace(br.Kind)
+	if kind == "ConfigMap" || kind == "Secret" {
+		return nil
+	}
+	gvk := schema.FromAPIVersionAndKind(strings.TrimSpace(br.APIVersion), kind)
+	if namespacedShareKinds.Load().(sets.String).Has(gvk.String()) {
+		return nil
+	}
+	return fmt.Errorf("kind %s of backing resource %s is not one of the kinds namespaced shares may use", gvk.String(), br.Name)
+}
+
+// ValidateNamespacedBackingResource checks that a NamespacedShare in shareNamespace may use the given backing
+// resource, which is the case when it is of a kind NamespacedShares may use, and in the same namespace, or when its namespace lists shareNamespace
+// in its AllowedShareNamespacesAnnotation
+func ValidateNamespacedBackingResource(shareNamespace string, br sharev1alpha1.BackingResource, namespaces corelisters.NamespaceLister) error {
+	if err := ValidateNamespacedBackingResourceKind(br); err != nil {
+		return err
+	}
+	if br.Namespace == shareNamespace {
+		return nil
+	}
+	ns, err := namespaces.Get(br.Namespace)
+	if err != nil {
+		return fmt.Errorf("namespace %s of backing resource %s could not be retrieved: %s", br.Namespace, br.Name, err.Error())
+	}
+	for _, allowed := range strings.Split(ns.Annotations[sharev1alpha1.AllowedShareNamespacesAnnotation], ",") {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "*" || allowed == shareNamespace {
+			return nil
+		}
+	}
+	return fmt.Errorf("namespace %s of backing resource %s does not allow shares from namespace %s", br.Namespace, br.Name, shareNamespace)
+}
@@ -0,0 +1,444 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	sharev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+)
+
+func TestValidateKeyToPath(t *testing.T) {
+	mode := int32(0600)
+	badMode := int32(01000)
+	tests := []struct {
+		name        string
+		item        corev1.KeyToPath
+		expectedMsg string
+	}{
+		{
+			name: "nested path with mode",
+			item: corev1.KeyToPath{Key: "ca.crt", Path: "ssl/certs/ca.crt", Mode: &mode},
+		},
+		{
+			name:        "missing key",
+			item:        corev1.KeyToPath{Path: "ca.crt"},
+			expectedMsg: "key needs to be set",
+		},
+		{
+			name:        "missing path",
+			item:        corev1.KeyToPath{Key: "ca.crt"},
+			expectedMsg: "path for key ca.crt needs to be set",
+		},
+		{
+			name:        "absolute path",
+			item:        corev1.KeyToPath{Key: "ca.crt", Path: "/etc/ca.crt"},
+			expectedMsg: "must be relative",
+		},
+		{
+			name:        "parent path element",
+			item:        corev1.KeyToPath{Key: "ca.crt", Path: "ssl/../../ca.crt"},
+			expectedMsg: "must not contain '..'",
+		},
+		{
+			name:        "reserved path",
+			item:        corev1.KeyToPath{Key: "ca.crt", Path: "..data/ca.crt"},
+			expectedMsg: "must not start with '..'",
+		},
+		{
+			name:        "bad mode",
+			item:        corev1.KeyToPath{Key: "ca.crt", Path: "ca.crt", Mode: &badMode},
+			expectedMsg: "must be between 0 and 0777",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateKeyToPath(test.item)
+			if len(test.expectedMsg) == 0 && err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			if len(test.expectedMsg) > 0 && (err == nil || !strings.Contains(err.Error(), test.expectedMsg)) {
+				t.Fatalf("expected err msg containing %s got %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateSelector(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"trust.example.com/bundle": "true"}}
+	tests := []struct {
+		name        string
+		br          sharev1alpha1.BackingResource
+		expectedMsg string
+	}{
+		{
+			name: "name only",
+			br:   sharev1alpha1.BackingResource{Kind: "ConfigMap", Namespace: "namespace", Name: "configmap1"},
+		},
+		{
+			name: "selector only",
+			br:   sharev1alpha1.BackingResource{Kind: "ConfigMap", Namespace: "namespace", Selector: selector},
+		},
+		{
+			name:        "name and selector",
+			br:          sharev1alpha1.BackingResource{Kind: "ConfigMap", Namespace: "namespace", Name: "configmap1", Selector: selector},
+			expectedMsg: "cannot both be set",
+		},
+		{
+			name: "items and selector",
+			br: sharev1alpha1.BackingResource{Kind: "ConfigMap", Namespace: "namespace", Selector: selector,
+				Items: []corev1.KeyToPath{{Key: "ca.crt", Path: "ca.crt"}}},
+			expectedMsg: "items cannot be set",
+		},
+		{
+			name: "bad operator",
+			br: sharev1alpha1.BackingResource{Kind: "ConfigMap", Namespace: "namespace", Selector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "bundle", Operator: "Matches"}}}},
+			expectedMsg: "selector is invalid",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateSelector(test.br)
+			if len(test.expectedMsg) == 0 && err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			if len(test.expectedMsg) > 0 && (err == nil || !strings.Contains(err.Error(), test.expectedMsg)) {
+				t.Fatalf("expected err msg containing %s got %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateBackingResourceKind(t *testing.T) {
+	fields := []sharev1alpha1.FieldToPath{{JSONPath: ".spec.endpoint", Path: "endpoint"}}
+	tests := []struct {
+		name        string
+		br          sharev1alpha1.BackingResource
+		expectedMsg string
+	}{
+		{
+			name: "configmap",
+			br:   sharev1alpha1.BackingResource{Kind: "ConfigMap", APIVersion: "v1", Namespace: "namespace", Name: "configmap1"},
+		},
+		{
+			name: "custom kind with fields",
+			br: sharev1alpha1.BackingResource{Kind: "Endpoint", APIVersion: "example.com/v1", Namespace: "namespace", Name: "endpoint1",
+				Fields: fields},
+		},
+		{
+			name: "custom kind with braced expression",
+			br: sharev1alpha1.BackingResource{Kind: "Endpoint", APIVersion: "example.com/v1", Namespace: "namespace", Name: "endpoint1",
+				Fields: []sharev1alpha1.FieldToPath{{JSONPath: "{.spec.ports[*].port}", Path: "ports"}}},
+		},
+		{
+			name:        "secret with fields",
+			br:          sharev1alpha1.BackingResource{Kind: "Secret", APIVersion: "v1", Namespace: "namespace", Name: "secret1", Fields: fields},
+			expectedMsg: "fields cannot be set",
+		},
+		{
+			name:        "missing apiVersion",
+			br:          sharev1alpha1.BackingResource{Kind: "Endpoint", Namespace: "namespace", Name: "endpoint1", Fields: fields},
+			expectedMsg: "apiVersion needs to be set",
+		},
+		{
+			name:        "missing fields",
+			br:          sharev1alpha1.BackingResource{Kind: "Endpoint", APIVersion: "example.com/v1", Namespace: "namespace", Name: "endpoint1"},
+			expectedMsg: "fields need to be set",
+		},
+		{
+			name: "custom kind with items",
+			br: sharev1alpha1.BackingResource{Kind: "Endpoint", APIVersion: "example.com/v1", Namespace: "namespace", Name: "endpoint1",
+				Fields: fields, Items: []corev1.KeyToPath{{Key: "endpoint", Path: "endpoint"}}},
+			expectedMsg: "items cannot be set",
+		},
+		{
+			name: "bad apiVersion",
+			br: sharev1alpha1.BackingResource{Kind: "Endpoint", APIVersion: "example.com/v1/v2", Namespace: "namespace", Name: "endpoint1",
+				Fields: fields},
+			expectedMsg: "apiVersion example.com/v1/v2 is invalid",
+		},
+		{
+			name: "bad expression",
+			br: sharev1alpha1.BackingResource{Kind: "Endpoint", APIVersion: "example.com/v1", Namespace: "namespace", Name: "endpoint1",
+				Fields: []sharev1alpha1.FieldToPath{{JSONPath: ".spec.ports[", Path: "ports"}}},
+			expectedMsg: "field jsonPath .spec.ports[ is invalid",
+		},
+		{
+			name: "absolute path",
+			br: sharev1alpha1.BackingResource{Kind: "Endpoint", APIVersion: "example.com/v1", Namespace: "namespace", Name: "endpoint1",
+				Fields: []sharev1alpha1.FieldToPath{{JSONPath: ".spec.endpoint", Path: "/etc/endpoint"}}},
+			expectedMsg: "must be relative",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateBackingResourceKind(test.br)
+			if len(test.expectedMsg) == 0 && err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			if len(test.expectedMsg) > 0 && (err == nil || !strings.Contains(err.Error(), test.expectedMsg)) {
+				t.Fatalf("expected err msg containing %s got %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateBackingResource(t *testing.T) {
+	tests := []struct {
+		name        string
+		br          sharev1alpha1.BackingResource
+		expectedMsg string
+	}{
+		{
+			name: "configmap",
+			br:   sharev1alpha1.BackingResource{Kind: "ConfigMap", APIVersion: "v1", Namespace: "namespace", Name: "configmap1"},
+		},
+		{
+			name: "secret without apiVersion",
+			br:   sharev1alpha1.BackingResource{Kind: "Secret", Namespace: "namespace", Name: "secret1"},
+		},
+		{
+			name:        "unsupported kind",
+			br:          sharev1alpha1.BackingResource{Kind: "Pod", APIVersion: "v1", Namespace: "namespace", Name: "pod1"},
+			expectedMsg: "fields need to be set",
+		},
+		{
+			name:        "apiVersion not matching kind",
+			br:          sharev1alpha1.BackingResource{Kind: "ConfigMap", APIVersion: "apps/v1", Namespace: "namespace", Name: "configmap1"},
+			expectedMsg: "does not match kind ConfigMap",
+		},
+		{
+			name:        "missing namespace",
+			br:          sharev1alpha1.BackingResource{Kind: "ConfigMap", APIVersion: "v1", Name: "configmap1"},
+			expectedMsg: "namespace needs to be set",
+		},
+		{
+			name:        "invalid namespace",
+			br:          sharev1alpha1.BackingResource{Kind: "ConfigMap", APIVersion: "v1", Namespace: "Name_Space", Name: "configmap1"},
+			expectedMsg: "namespace Name_Space is invalid",
+		},
+		{
+			name:        "missing name",
+			br:          sharev1alpha1.BackingResource{Kind: "ConfigMap", APIVersion: "v1", Namespace: "namespace"},
+			expectedMsg: "name or selector needs to be set",
+		},
+		{
+			name:        "invalid name",
+			br:          sharev1alpha1.BackingResource{Kind: "ConfigMap", APIVersion: "v1", Namespace: "namespace", Name: "config map"},
+			expectedMsg: "name config map is invalid",
+		},
+		{
+			name: "invalid item",
+			br: sharev1alpha1.BackingResource{Kind: "ConfigMap", APIVersion: "v1", Namespace: "namespace", Name: "configmap1",
+				Items: []corev1.KeyToPath{{Key: "ca.crt", Path: "../ca.crt"}}},
+			expectedMsg: "must not contain '..'",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateBackingResource(test.br)
+			if len(test.expectedMsg) == 0 && err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			if len(test.expectedMsg) > 0 && (err == nil || !strings.Contains(err.Error(), test.expectedMsg)) {
+				t.Fatalf("expected err msg containing %s got %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateProjectedPaths(t *testing.T) {
+	configMap := func(name string, paths ...string) sharev1alpha1.BackingResource {
+		br := sharev1alpha1.BackingResource{Kind: "ConfigMap", APIVersion: "v1", Namespace: "namespace", Name: name}
+		for _, path := range paths {
+			br.Items = append(br.Items, corev1.KeyToPath{Key: "key", Path: path})
+		}
+		return br
+	}
+	endpoint := sharev1alpha1.BackingResource{Kind: "Endpoint", APIVersion: "example.com/v1", Namespace: "namespace", Name: "endpoint1",
+		Fields: []sharev1alpha1.FieldToPath{{JSONPath: ".spec.url", Path: "config/url"}}}
+	tests := []struct {
+		name        string
+		brs         []sharev1alpha1.BackingResource
+		expectedMsg string
+	}{
+		{
+			name: "distinct paths",
+			brs:  []sharev1alpha1.BackingResource{configMap("configmap1", "a", "dir/b"), configMap("configmap2", "dir/c"), endpoint},
+		},
+		{
+			name: "backing resources without items",
+			brs:  []sharev1alpha1.BackingResource{configMap("configmap1"), configMap("configmap2")},
+		},
+		{
+			name:        "same path in two backing resources",
+			brs:         []sharev1alpha1.BackingResource{configMap("configmap1", "a"), configMap("configmap2", "./a")},
+			expectedMsg: "item path a is projected by both backing resource ConfigMap namespace:configmap1 and backing resource ConfigMap namespace:configmap2",
+		},
+		{
+			name:        "same path twice in one backing resource",
+			brs:         []sharev1alpha1.BackingResource{configMap("configmap1", "a", "a")},
+			expectedMsg: "item path a is projected by both",
+		},
+		{
+			name:        "field path of an item path",
+			brs:         []sharev1alpha1.BackingResource{configMap("configmap1", "config/url"), endpoint},
+			expectedMsg: "item path config/url is projected by both",
+		},
+		{
+			name: "item path in the directory of another kind",
+			brs:  []sharev1alpha1.BackingResource{configMap("configmap1"), configMap("configmap2", "secrets/a")},
+		},
+		{
+			name:        "item path of the directory of a backing resource without items",
+			brs:         []sharev1alpha1.BackingResource{configMap("configmap1"), configMap("configmap2", "configmaps")},
+			expectedMsg: "item path configmaps of backing resource ConfigMap namespace:configmap2 conflicts with directory configmaps, where backing resource ConfigMap namespace:configmap1 is projected",
+		},
+		{
+			name: "item path within the directory of a selector",
+			brs: []sharev1alpha1.BackingResource{
+				{Kind: "Secret", APIVersion: "v1", Namespace: "namespace", Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}}},
+				endpoint,
+				configMap("configmap1", "secrets/foo"),
+			},
+			expectedMsg: "item path secrets/foo of backing resource ConfigMap namespace:configmap1 conflicts with directory secrets",
+		},
+		{
+			name:        "path within another path",
+			brs:         []sharev1alpha1.BackingResource{configMap("configmap1", "config"), endpoint},
+			expectedMsg: "item path config/url of backing resource Endpoint namespace:endpoint1 is within item path config",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateProjectedPaths(test.brs)
+			if len(test.expectedMsg) == 0 && err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			if len(test.expectedMsg) > 0 && (err == nil || !strings.Contains(err.Error(), test.expectedMsg)) {
+				t.Fatalf("expected err msg containing %s got %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateUpdatePolicy(t *testing.T) {
+	for _, policy := range []sharev1alpha1.UpdatePolicy{"", sharev1alpha1.UpdatePolicyImmediate, sharev1alpha1.UpdatePolicyOnPodRestart} {
+		if err := ValidateUpdatePolicy(policy); err != nil {
+			t.Errorf("unexpected err for %q: %s", policy, err.Error())
+		}
+	}
+	if err := ValidateUpdatePolicy("Never"); err == nil || !strings.Contains(err.Error(), "update policy Never is not one of") {
+		t.Errorf("expected err for Never got %v", err)
+	}
+}
+
+func TestValidateDefaultModes(t *testing.T) {
+	mode := int32(0600)
+	badMode := int32(01000)
+	if err := ValidateDefaultModes(sharev1alpha1.ShareSpec{DefaultMode: &mode, SecretDefaultMode: &mode}); err != nil {
+		t.Errorf("unexpected err %s", err.Error())
+	}
+	err := ValidateDefaultModes(sharev1alpha1.ShareSpec{DefaultMode: &mode, ConfigMapDefaultMode: &badMode})
+	if err == nil || !strings.Contains(err.Error(), "configMapDefaultMode 1000 must be between 0 and 0777") {
+		t.Errorf("expected err for configMapDefaultMode got %v", err)
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	for _, test := range []struct {
+		value       string
+		expected    int32
+		expectedMsg string
+	}{
+		{value: "0640", expected: 0640},
+		{value: "420", expected: 0644},
+		{value: "0o600", expected: 0600},
+		{value: "rw-r--r--", expectedMsg: "is not a number"},
+		{value: "01000", expectedMsg: "must be between 0 and 0777"},
+		{value: "-1", expectedMsg: "must be between 0 and 0777"},
+	} {
+		mode, err := ParseFileMode(test.value)
+		switch {
+		case len(test.expectedMsg) == 0 && err != nil:
+			t.Errorf("unexpected err for %s: %s", test.value, err.Error())
+		case len(test.expectedMsg) == 0 && mode != test.expected:
+			t.Errorf("expected %o for %s got %o", test.expected, test.value, mode)
+		case len(test.expectedMsg) > 0 && (err == nil || !strings.Contains(err.Error(), test.expectedMsg)):
+			t.Errorf("expected err containing %s for %s got %v", test.expectedMsg, test.value, err)
+		}
+	}
+}
+
+func TestValidateNamespacedBackingResource(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	indexer.Add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "closed"}})
+	indexer.Add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "listed",
+		Annotations: map[string]string{sharev1alpha1.AllowedShareNamespacesAnnotation: "team-a, team-b"},
+	}})
+	indexer.Add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "open",
+		Annotations: map[string]string{sharev1alpha1.AllowedShareNamespacesAnnotation: "*"},
+	}})
+	namespaces := corelisters.NewNamespaceLister(indexer)
+
+	tests := []struct {
+		name             string
+		backingNamespace string
+		allowed          bool
+	}{
+		{name: "same namespace", backingNamespace: "team-b", allowed: true},
+		{name: "no annotation", backingNamespace: "closed"},
+		{name: "listed", backingNamespace: "listed", allowed: true},
+		{name: "wildcard", backingNamespace: "open", allowed: true},
+		{name: "namespace not found", backingNamespace: "missing"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			br := sharev1alpha1.BackingResource{Kind: "ConfigMap", Namespace: test.backingNamespace, Name: "configmap1"}
+			err := ValidateNamespacedBackingResource("team-b", br, namespaces)
+			if test.allowed && err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			if !test.allowed && err == nil {
+				t.Fatalf("expected an error")
+			}
+		})
+	}
+}
+
+func TestValidateNamespacedBackingResourceKind(t *testing.T) {
+	defer SetNamespacedShareKinds(nil)
+	SetNamespacedShareKinds([]metav1.TypeMeta{{APIVersion: "example.com/v1", Kind: "Endpoint"}})
+
+	tests := []struct {
+		name       string
+		apiVersion string
+		kind       string
+		allowed    bool
+	}{
+		{name: "configmap", apiVersion: "v1", kind: "ConfigMap", allowed: true},
+		{name: "secret", kind: "Secret", allowed: true},
+		{name: "listed kind", apiVersion: "example.com/v1", kind: "Endpoint", allowed: true},
+		{name: "other version of a listed kind", apiVersion: "example.com/v2", kind: "Endpoint"},
+		{name: "kind not listed", apiVersion: "rbac.authorization.k8s.io/v1", kind: "Role"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			br := sharev1alpha1.BackingResource{APIVersion: test.apiVersion, Kind: test.kind, Namespace: "team-b", Name: "object1"}
+			err := ValidateNamespacedBackingResourceKind(br)
+			if test.allowed && err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			if !test.allowed && err == nil {
+				t.Fatalf("expected an error")
+			}
+		})
+	}
+}
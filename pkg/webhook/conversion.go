@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+	"github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1beta1"
+)
+
+const (
+	// ConvertPath is the path the conversion webhook of the Share and NamespacedShare CRDs sends conversion reviews to
+	ConvertPath = "/convert"
+)
+
+// ConversionReview mirrors the apiextensions.k8s.io/v1 type of the same name, which is all the driver needs of
+// the apiextensions API
+type ConversionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *ConversionRequest  `json:"request,omitempty"`
+	Response        *ConversionResponse `json:"response,omitempty"`
+}
+
+type ConversionRequest struct {
+	UID               types.UID              `json:"uid"`
+	DesiredAPIVersion string                 `json:"desiredAPIVersion"`
+	Objects           []runtime.RawExtension `json:"objects"`
+}
+
+type ConversionResponse struct {
+	UID              types.UID              `json:"uid"`
+	ConvertedObjects []runtime.RawExtension `json:"convertedObjects"`
+	Result           metav1.Status          `json:"result"`
+}
+
+func serveConversion(w http.ResponseWriter, r *http.Request) {
+	review := ConversionReview{}
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		http.Error(w, fmt.Sprintf("could not decode conversion review: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "conversion review has no request", http.StatusBadRequest)
+		return
+	}
+	review.Response = convert(review.Request)
+	review.Response.UID = review.Request.UID
+	review.Request = nil
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		klog.Errorf("could not encode conversion review response: %s", err.Error())
+	}
+}
+
+func convert(req *ConversionRequest) *ConversionResponse {
+	response := &ConversionResponse{Result: metav1.Status{Status: metav1.StatusSuccess}}
+	for _, obj := range req.Objects {
+		converted, err := convertObject(obj.Raw, req.DesiredAPIVersion)
+		if err != nil {
+			// the apiserver fails the whole request when any object cannot be converted
+			response.ConvertedObjects = nil
+			response.Result = metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+			return response
+		}
+		response.ConvertedObjects = append(response.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+	return response
+}
+
+// convertObject converts a Share or NamespacedShare to the desired apiVersion
+func convertObject(raw []byte, desiredAPIVersion string) ([]byte, error) {
+	typeMeta := metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		return nil, err
+	}
+	if typeMeta.APIVersion == desiredAPIVersion {
+		return raw, nil
+	}
+	from, to := typeMeta.APIVersion, desiredAPIVersion
+	alpha, beta := v1alpha1.SchemeGroupVersion.String(), v1beta1.SchemeGroupVersion.String()
+	switch {
+	case typeMeta.Kind == "Share" && from == alpha && to == beta:
+		share := &v1alpha1.Share{}
+		if err := json.Unmarshal(raw, share); err != nil {
+			return nil, err
+		}
+		return json.Marshal(v1beta1.ConvertShareFromV1alpha1(share))
+	case typeMeta.Kind == "Share" && from == beta && to == alpha:
+		share := &v1beta1.Share{}
+		if err := json.Unmarshal(raw, share); err != nil {
+			return nil, err
+		}
+		return json.Marshal(v1beta1.ConvertShareToV1alpha1(share))
+	case typeMeta.Kind == "NamespacedShare" && from == alpha && to == beta:
+		share := &v1alpha1.NamespacedShare{}
+		if err := json.Unmarshal(raw, share); err != nil {
+			return nil, err
+		}
+		return json.Marshal(v1beta1.ConvertNamespacedShareFromV1alpha1(share))
+	case typeMeta.Kind == "NamespacedShare" && from == beta && to == alpha:
+		share := &v1beta1.NamespacedShare{}
+		if err := json.Unmarshal(raw, share); err != nil {
+			return nil, err
+		}
+		return json.Marshal(v1beta1.ConvertNamespacedShareToV1alpha1(share))
+	}
+	return nil, fmt.Errorf("unsupported conversion of %s %s to %s", typeMeta.Kind, from, to)
+}
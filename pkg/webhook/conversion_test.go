@@ -0,0 +1,123 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+	"github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1beta1"
+)
+
+func TestConversion(t *testing.T) {
+	alphaShare := &v1alpha1.Share{
+		TypeMeta:   metav1.TypeMeta{Kind: "Share", APIVersion: v1alpha1.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{Name: "share1", ResourceVersion: "5"},
+		Spec: v1alpha1.ShareSpec{
+			BackingResource: v1alpha1.BackingResource{Kind: "ConfigMap", Namespace: "namespace1", Name: "configmap1"},
+			BackingResources: []v1alpha1.BackingResource{{Kind: "Endpoint", APIVersion: "example.com/v1", Namespace: "namespace1",
+				Name: "endpoint1", Fields: []v1alpha1.FieldToPath{{JSONPath: ".spec.url", Path: "url"}}}},
+			Description: "shared config",
+		},
+		Status: v1alpha1.ShareStatus{Conditions: []metav1.Condition{{Type: v1alpha1.ShareConditionValid, Status: metav1.ConditionTrue}}},
+	}
+	alphaNamespacedShare := &v1alpha1.NamespacedShare{
+		TypeMeta:   metav1.TypeMeta{Kind: "NamespacedShare", APIVersion: v1alpha1.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{Name: "share1", Namespace: "namespace1"},
+		Spec: v1alpha1.ShareSpec{
+			BackingResources: []v1alpha1.BackingResource{{Kind: "Secret", APIVersion: "v1", Namespace: "namespace1", Name: "secret1"}},
+		},
+	}
+	raw := func(obj interface{}) runtime.RawExtension {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			t.Fatalf("unexpected err %s", err.Error())
+		}
+		return runtime.RawExtension{Raw: data}
+	}
+
+	// v1alpha1 to v1beta1
+	response := postConversionReview(t, v1beta1.SchemeGroupVersion.String(), raw(alphaShare), raw(alphaNamespacedShare))
+	if response.Result.Status != metav1.StatusSuccess || len(response.ConvertedObjects) != 2 {
+		t.Fatalf("unexpected response %#v", response)
+	}
+	betaShare := &v1beta1.Share{}
+	if err := json.Unmarshal(response.ConvertedObjects[0].Raw, betaShare); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if betaShare.APIVersion != v1beta1.SchemeGroupVersion.String() || betaShare.ResourceVersion != "5" {
+		t.Fatalf("unexpected type or object meta %#v %#v", betaShare.TypeMeta, betaShare.ObjectMeta)
+	}
+	if betaShare.Spec.BackingResource == nil || betaShare.Spec.BackingResource.APIVersion != "v1" {
+		t.Fatalf("expected backing resource with apiVersion v1 got %#v", betaShare.Spec.BackingResource)
+	}
+	if len(betaShare.Spec.BackingResources) != 1 || len(betaShare.Spec.BackingResources[0].Fields) != 1 ||
+		betaShare.Spec.Description != "shared config" || len(betaShare.Status.Conditions) != 1 {
+		t.Fatalf("unexpected share %#v", betaShare)
+	}
+	betaNamespacedShare := &v1beta1.NamespacedShare{}
+	if err := json.Unmarshal(response.ConvertedObjects[1].Raw, betaNamespacedShare); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if betaNamespacedShare.Kind != "NamespacedShare" || betaNamespacedShare.Spec.BackingResource != nil ||
+		len(betaNamespacedShare.Spec.BackingResources) != 1 {
+		t.Fatalf("unexpected namespaced share %#v", betaNamespacedShare)
+	}
+
+	// and back again, where the only difference is the apiVersion set on the ConfigMap
+	response = postConversionReview(t, v1alpha1.SchemeGroupVersion.String(), raw(betaShare))
+	if response.Result.Status != metav1.StatusSuccess || len(response.ConvertedObjects) != 1 {
+		t.Fatalf("unexpected response %#v", response)
+	}
+	roundTripped := &v1alpha1.Share{}
+	if err := json.Unmarshal(response.ConvertedObjects[0].Raw, roundTripped); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	expected := alphaShare.DeepCopy()
+	expected.Spec.BackingResource.APIVersion = "v1"
+	if !equality.Semantic.DeepEqual(expected, roundTripped) {
+		t.Fatalf("expected %#v got %#v", expected, roundTripped)
+	}
+
+	// objects already at the desired version are returned as is
+	response = postConversionReview(t, v1alpha1.SchemeGroupVersion.String(), raw(alphaShare))
+	if len(response.ConvertedObjects) != 1 || string(response.ConvertedObjects[0].Raw) != string(raw(alphaShare).Raw) {
+		t.Fatalf("unexpected response %#v", response)
+	}
+
+	response = postConversionReview(t, "projectedresource.storage.openshift.io/v2", raw(alphaShare))
+	if response.Result.Status != metav1.StatusFailure || !strings.Contains(response.Result.Message, "unsupported conversion") {
+		t.Fatalf("expected unsupported conversion failure got %#v", response.Result)
+	}
+}
+
+func postConversionReview(t *testing.T, desiredAPIVersion string, objects ...runtime.RawExtension) *ConversionResponse {
+	review := ConversionReview{
+		TypeMeta: metav1.TypeMeta{Kind: "ConversionReview", APIVersion: "apiextensions.k8s.io/v1"},
+		Request:  &ConversionRequest{UID: "uid1", DesiredAPIVersion: desiredAPIVersion, Objects: objects},
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	recorder := httptest.NewRecorder()
+	serveConversion(recorder, httptest.NewRequest(http.MethodPost, ConvertPath, bytes.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("unexpected status code %d: %s", recorder.Code, recorder.Body.String())
+	}
+	response := ConversionReview{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("unexpected err %s", err.Error())
+	}
+	if response.Response == nil || response.Response.UID != "uid1" {
+		t.Fatalf("expected response for uid1 got %#v", response.Response)
+	}
+	return response.Response
+}
@@ -0,0 +1,227 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/klog/v2"
+
+	sharev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+	sharev1beta1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1beta1"
+	"github.com/openshift/csi-driver-projected-resource/pkg/client"
+	"github.com/openshift/csi-driver-projected-resource/pkg/controller"
+	"github.com/openshift/csi-driver-projected-resource/pkg/validation"
+)
+
+const (
+	// ValidatePath is the path the ValidatingWebhookConfiguration of the driver sends admission reviews to
+	ValidatePath = "/validate"
+)
+
+// Server validates Share and NamespacedShare create and update requests, so that a share whose backing
+// resources cannot be projected is rejected when it is written, rather than when a pod fails to mount it.
+// It also converts them between the versions of the API.
+type Server struct {
+	addr     string
+	certFile string
+	keyFile  string
+
+	// restMapper provides the kinds served by the apiserver, against which the apiVersion of the backing
+	// resources of kinds other than ConfigMap and Secret are checked; when nil the check is skipped
+	restMapper func() (meta.RESTMapper, error)
+}
+
+func NewServer(addr, certFile, keyFile string) (*Server, error) {
+	kubeRestConfig, err := client.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(kubeRestConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		addr:     addr,
+		certFile: certFile,
+		keyFile:  keyFile,
+		restMapper: func() (meta.RESTMapper, error) {
+			groupResources, err := restmapper.GetAPIGroupResources(discoveryClient)
+			if err != nil {
+				return nil, err
+			}
+			return restmapper.NewDiscoveryRESTMapper(groupResources), nil
+		},
+	}, nil
+}
+
+// Run serves admission reviews over TLS until stopCh is closed
+func (s *Server) Run(stopCh <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle(ValidatePath, s)
+	mux.HandleFunc(ConvertPath, serveConversion)
+	srv := &http.Server{Addr: s.addr, Handler: mux}
+	go func() {
+		<-stopCh
+		srv.Shutdown(context.TODO())
+	}()
+	klog.V(2).Infof("webhook listening on %s", s.addr)
+	if err := srv.ListenAndServeTLS(s.certFile, s.keyFile); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	review := admissionv1.AdmissionReview{}
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		http.Error(w, fmt.Sprintf("could not decode admission review: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "admission review has no request", http.StatusBadRequest)
+		return
+	}
+	review.Response = s.review(review.Request)
+	review.Response.UID = review.Request.UID
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		klog.Errorf("could not encode admission review response: %s", err.Error())
+	}
+}
+
+func (s *Server) review(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+	share, err := decodeShare(req)
+	if err != nil {
+		return denied(metav1.StatusReasonBadRequest, http.StatusBadRequest,
+			fmt.Sprintf("could not decode %s: %s", req.Kind.Kind, err.Error()))
+	}
+	if share == nil {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+	problems := s.validateShare(share)
+	if len(problems) == 0 {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+	klog.V(4).Infof("denying %s %s %s: %s", req.Operation, req.Kind.Kind, req.Name, strings.Join(problems, "; "))
+	return denied(metav1.StatusReasonInvalid, http.StatusUnprocessableEntity, strings.Join(problems, "; "))
+}
+
+// decodeShare returns the Share or NamespacedShare of an admission request, converted to v1alpha1,
+// or nil for any other kind
+func decodeShare(req *admissionv1.AdmissionRequest) (sharev1alpha1.ShareObject, error) {
+	switch {
+	case req.Kind.Kind == "Share" && req.Kind.Version == sharev1beta1.Version:
+		share := &sharev1beta1.Share{}
+		if err := json.Unmarshal(req.Object.Raw, share); err != nil {
+			return nil, err
+		}
+		return sharev1beta1.ConvertShareToV1alpha1(share), nil
+	case req.Kind.Kind == "NamespacedShare" && req.Kind.Version == sharev1beta1.Version:
+		share := &sharev1beta1.NamespacedShare{}
+		if err := json.Unmarshal(req.Object.Raw, share); err != nil {
+			return nil, err
+		}
+		return sharev1beta1.ConvertNamespacedShareToV1alpha1(share), nil
+	case req.Kind.Kind == "Share":
+		share := &sharev1alpha1.Share{}
+		return share, json.Unmarshal(req.Object.Raw, share)
+	case req.Kind.Kind == "NamespacedShare":
+		share := &sharev1alpha1.NamespacedShare{}
+		return share, json.Unmarshal(req.Object.Raw, share)
+	}
+	return nil, nil
+}
+
+func denied(reason metav1.StatusReason, code int32, message string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  reason,
+			Code:    code,
+			Message: message,
+		},
+	}
+}
+
+// validateShare returns why the backing resources of a share cannot be projected, if at all
+func (s *Server) validateShare(share sharev1alpha1.ShareObject) []string {
+	brs := share.GetSpec().GetBackingResources()
+	if len(brs) == 0 {
+		return []string{"neither backingResource nor backingResources are set"}
+	}
+	problems := []string{}
+	if err := validation.ValidateUpdatePolicy(share.GetSpec().UpdatePolicy); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := validation.ValidateDefaultModes(share.GetSpec()); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := validation.ValidateProjectedPaths(brs); err != nil {
+		problems = append(problems, err.Error())
+	}
+	var mapper meta.RESTMapper
+	discover := s.restMapper
+	for _, br := range brs {
+		if err := validation.ValidateBackingResource(br); err != nil {
+			problems = append(problems, fmt.Sprintf("backing resource %s %s:%s: %s", br.Kind, br.Namespace, br.Name, err.Error()))
+			continue
+		}
+		if len(share.GetNamespace()) > 0 {
+			if err := validation.ValidateNamespacedBackingResourceKind(br); err != nil {
+				problems = append(problems, fmt.Sprintf("backing resource %s %s:%s: %s", br.Kind, br.Namespace, br.Name, err.Error()))
+				continue
+			}
+		}
+		if controller.IsNamespaceExcluded(br.Namespace) {
+			problems = append(problems, fmt.Sprintf("backing resource %s %s:%s: namespace %s is excluded by the controller",
+				br.Kind, br.Namespace, br.Name, br.Namespace))
+			continue
+		}
+		kind := strings.TrimSpace(br.Kind)
+		if kind == "ConfigMap" || kind == "Secret" || discover == nil {
+			continue
+		}
+		if mapper == nil {
+			var err error
+			if mapper, err = discover(); err != nil {
+				// the controller reports backing resources of unknown kinds in the status of the share
+				klog.Warningf("unable to discover the kinds served by the apiserver: %s", err.Error())
+				discover = nil
+				continue
+			}
+		}
+		if err := checkKindServed(mapper, br); err != nil {
+			problems = append(problems, fmt.Sprintf("backing resource %s %s:%s: %s", br.Kind, br.Namespace, br.Name, err.Error()))
+		}
+	}
+	return problems
+}
+
+// checkKindServed checks that the apiVersion of a backing resource serves its kind, and that the kind is namespaced
+func checkKindServed(mapper meta.RESTMapper, br sharev1alpha1.BackingResource) error {
+	gvk := schema.FromAPIVersionAndKind(strings.TrimSpace(br.APIVersion), strings.TrimSpace(br.Kind))
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		return fmt.Errorf("apiVersion %s does not serve kind %s", br.APIVersion, br.Kind)
+	}
+	if err != nil {
+		return err
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return fmt.Errorf("kind %s is not namespaced", br.Kind)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	sharev1alpha1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1alpha1"
+	sharev1beta1 "github.com/openshift/csi-driver-projected-resource/pkg/api/projectedresource/v1beta1"
+)
+
+func testRESTMapper() (meta.RESTMapper, error) {
+	gv := schema.GroupVersion{Group: "example.com", Version: "v1"}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gv})
+	mapper.Add(gv.WithKind("Endpoint"), meta.RESTScopeNamespace)
+	mapper.Add(gv.WithKind("Cluster"), meta.RESTScopeRoot)
+	return mapper, nil
+}
+
+func testShare(br sharev1alpha1.BackingResource) *sharev1alpha1.Share {
+	return &sharev1alpha1.Share{
+		TypeMeta:   metav1.TypeMeta{Kind: "Share", APIVersion: "projectedresource.storage.openshift.io/v1alpha1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "share1"},
+		Spec:       sharev1alpha1.ShareSpec{BackingResource: br},
+	}
+}
+
+func TestReview(t *testing.T) {
+	endpointFields := []sharev1alpha1.FieldToPath{{JSONPath: ".spec.url", Path: "url"}}
+	tests := []struct {
+		name        string
+		kind        string
+		version     string
+		operation   admissionv1.Operation
+		share       interface{}
+		expectedMsg string
+	}{
+		{
+			name:      "valid share",
+			kind:      "Share",
+			operation: admissionv1.Create,
+			share:     testShare(sharev1alpha1.BackingResource{Kind: "ConfigMap", APIVersion: "v1", Namespace: "namespace1", Name: "configmap1"}),
+		},
+		{
+			name:      "valid namespaced share",
+			kind:      "NamespacedShare",
+			operation: admissionv1.Update,
+			share: &sharev1alpha1.NamespacedShare{
+				ObjectMeta: metav1.ObjectMeta{Name: "share1", Namespace: "namespace1"},
+				Spec: sharev1alpha1.ShareSpec{BackingResource: sharev1alpha1.BackingResource{
+					Kind: "Secret", APIVersion: "v1", Namespace: "namespace1", Name: "secret1"}},
+			},
+		},
+		{
+			name:        "unsupported kind",
+			kind:        "Share",
+			operation:   admissionv1.Create,
+			share:       testShare(sharev1alpha1.BackingResource{Kind: "Pod", APIVersion: "v1", Namespace: "namespace1", Name: "pod1"}),
+			expectedMsg: "fields need to be set for kind Pod",
+		},
+		{
+			name:        "invalid name",
+			kind:        "Share",
+			operation:   admissionv1.Update,
+			share:       testShare(sharev1alpha1.BackingResource{Kind: "ConfigMap", APIVersion: "v1", Namespace: "namespace1", Name: "ConfigMap_1"}),
+			expectedMsg: "name ConfigMap_1 is invalid",
+		},
+		{
+			name:        "excluded namespace",
+			kind:        "Share",
+			operation:   admissionv1.Create,
+			share:       testShare(sharev1alpha1.BackingResource{Kind: "ConfigMap", APIVersion: "v1", Namespace: "kube-system", Name: "configmap1"}),
+			expectedMsg: "namespace kube-system is excluded",
+		},
+		{
+			name:        "apiVersion not matching kind",
+			kind:        "Share",
+			operation:   admissionv1.Create,
+			share:       testShare(sharev1alpha1.BackingResource{Kind: "Secret", APIVersion: "apps/v1", Namespace: "namespace1", Name: "secret1"}),
+			expectedMsg: "does not match kind Secret",
+		},
+		{
+			name:      "served custom kind",
+			kind:      "Share",
+			operation: admissionv1.Create,
+			share: testShare(sharev1alpha1.BackingResource{Kind: "Endpoint", APIVersion: "example.com/v1", Namespace: "namespace1",
+				Name: "endpoint1", Fields: endpointFields}),
+		},
+		{
+			name:      "custom kind not served by apiVersion",
+			kind:      "Share",
+			operation: admissionv1.Create,
+			share: testShare(sharev1alpha1.BackingResource{Kind: "Endpoint", APIVersion: "example.com/v2", Namespace: "namespace1",
+				Name: "endpoint1", Fields: endpointFields}),
+			expectedMsg: "apiVersion example.com/v2 does not serve kind Endpoint",
+		},
+		{
+			name:      "cluster scoped custom kind",
+			kind:      "Share",
+			operation: admissionv1.Create,
+			share: testShare(sharev1alpha1.BackingResource{Kind: "Cluster", APIVersion: "example.com/v1", Namespace: "namespace1",
+				Name: "cluster1", Fields: endpointFields}),
+			expectedMsg: "kind Cluster is not namespaced",
+		},
+		{
+			name:      "namespaced share of a kind namespaced shares may not use",
+			kind:      "NamespacedShare",
+			operation: admissionv1.Create,
+			share: &sharev1alpha1.NamespacedShare{
+				ObjectMeta: metav1.ObjectMeta{Name: "share1", Namespace: "namespace1"},
+				Spec: sharev1alpha1.ShareSpec{BackingResource: sharev1alpha1.BackingResource{
+					Kind: "Endpoint", APIVersion: "example.com/v1", Namespace: "namespace1", Name: "endpoint1", Fields: endpointFields}},
+			},
+			expectedMsg: "is not one of the kinds namespaced shares may use",
+		},
+		{
+			name:      "v1beta1 share",
+			kind:      "Share",
+			version:   sharev1beta1.Version,
+			operation: admissionv1.Create,
+			share: &sharev1beta1.Share{
+				ObjectMeta: metav1.ObjectMeta{Name: "share1"},
+				Spec: sharev1beta1.ShareSpec{BackingResources: []sharev1beta1.BackingResource{{
+					Kind: "ConfigMap", APIVersion: "v1", Namespace: "kube-system", Name: "configmap1"}}},
+			},
+			expectedMsg: "namespace kube-system is excluded",
+		},
+		{
+			name:      "item path projected by two backing resources",
+			kind:      "Share",
+			operation: admissionv1.Create,
+			share: &sharev1alpha1.Share{
+				ObjectMeta: metav1.ObjectMeta{Name: "share1"},
+				Spec: sharev1alpha1.ShareSpec{
+					BackingResource: sharev1alpha1.BackingResource{Kind: "ConfigMap", APIVersion: "v1", Namespace: "namespace1",
+						Name: "configmap1", Items: []corev1.KeyToPath{{Key: "ca.crt", Path: "ca.crt"}}},
+					BackingResources: []sharev1alpha1.BackingResource{{Kind: "Secret", APIVersion: "v1", Namespace: "namespace1",
+						Name: "secret1", Items: []corev1.KeyToPath{{Key: "tls.crt", Path: "./ca.crt"}}}},
+				},
+			},
+			expectedMsg: "item path ca.crt is projected by both",
+		},
+		{
+			name:        "no backing resources",
+			kind:        "Share",
+			operation:   admissionv1.Create,
+			share:       &sharev1alpha1.Share{ObjectMeta: metav1.ObjectMeta{Name: "share1"}},
+			expectedMsg: "neither backingResource nor backingResources are set",
+		},
+		{
+			name:      "delete is not validated",
+			kind:      "Share",
+			operation: admissionv1.Delete,
+			share:     &sharev1alpha1.Share{ObjectMeta: metav1.ObjectMeta{Name: "share1"}},
+		},
+	}
+	server := &Server{restMapper: testRESTMapper}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			raw, err := json.Marshal(test.share)
+			if err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			version := test.version
+			if len(version) == 0 {
+				version = sharev1alpha1.Version
+			}
+			review := admissionv1.AdmissionReview{
+				TypeMeta: metav1.TypeMeta{Kind: "AdmissionReview", APIVersion: "admission.k8s.io/v1"},
+				Request: &admissionv1.AdmissionRequest{
+					UID:       types.UID("uid1"),
+					Kind:      metav1.GroupVersionKind{Group: "projectedresource.storage.openshift.io", Version: version, Kind: test.kind},
+					Operation: test.operation,
+					Object:    runtime.RawExtension{Raw: raw},
+				},
+			}
+			body, err := json.Marshal(review)
+			if err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, ValidatePath, bytes.NewReader(body)))
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("unexpected status code %d: %s", recorder.Code, recorder.Body.String())
+			}
+			response := admissionv1.AdmissionReview{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			if response.Response == nil || response.Response.UID != "uid1" {
+				t.Fatalf("expected response for uid1 got %#v", response.Response)
+			}
+			if len(test.expectedMsg) == 0 && !response.Response.Allowed {
+				t.Fatalf("expected request to be allowed got %#v", response.Response.Result)
+			}
+			if len(test.expectedMsg) > 0 {
+				if response.Response.Allowed {
+					t.Fatalf("expected request to be denied")
+				}
+				if !strings.Contains(response.Response.Result.Message, test.expectedMsg) {
+					t.Fatalf("expected message containing %s got %s", test.expectedMsg, response.Response.Result.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestServeHTTPBadRequest(t *testing.T) {
+	server := &Server{}
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, ValidatePath, strings.NewReader("{")))
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected status code %d got %d", http.StatusBadRequest, recorder.Code)
+	}
+}